@@ -0,0 +1,303 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// maxIncludeDepth limits the nesting of include statements, to catch include
+// loops.
+const maxIncludeDepth = 10
+
+// parseConf parses a configuration file in the strongSwan settings format,
+// i.e. nested sections of key/value pairs:
+//
+//	connections {
+//		home {
+//			remote_addrs = moon.strongswan.org # a comment
+//			local {
+//				auth = pubkey
+//			}
+//		}
+//	}
+//	include conf.d/*.conf
+//
+// Values may be quoted to include leading and trailing whitespace, '#' or
+// braces. Include patterns are relative to the including file, and the
+// matching files are included in lexical order. Sections given multiple
+// times are merged, and later values replace earlier ones.
+func parseConf(path string) (*vici.Message, error) {
+	m := vici.NewMessage()
+
+	if err := parseConfFile(m, path, 0); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func parseConfFile(m *vici.Message, path string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("%s: includes nested too deeply", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	p := &confParser{
+		path:  path,
+		data:  string(data),
+		line:  1,
+		depth: depth,
+	}
+
+	return p.parseSection(m, true)
+}
+
+// confParser parses a single configuration file.
+type confParser struct {
+	path  string
+	data  string
+	pos   int
+	line  int
+	depth int
+}
+
+func (p *confParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s:%d: %s", p.path, p.line, fmt.Sprintf(format, args...))
+}
+
+// skip skips whitespace, including newlines, and comments.
+func (p *confParser) skip() {
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; {
+		case c == '\n':
+			p.line++
+			p.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// skipBlank skips whitespace on the current line.
+func (p *confParser) skipBlank() {
+	for p.pos < len(p.data) && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t' || p.data[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+// name reads a section or key name.
+func (p *confParser) name() string {
+	start := p.pos
+	for p.pos < len(p.data) && !strings.ContainsRune(" \t\r\n{}=:#\"", rune(p.data[p.pos])) {
+		p.pos++
+	}
+
+	return p.data[start:p.pos]
+}
+
+// parseSection parses the contents of a section into m, up to the closing
+// brace, or the end of the file if top is true.
+func (p *confParser) parseSection(m *vici.Message, top bool) error {
+	for {
+		p.skip()
+
+		if p.pos == len(p.data) {
+			if !top {
+				return p.errorf("unexpected end of file, missing '}'")
+			}
+
+			return nil
+		}
+
+		if p.data[p.pos] == '}' {
+			if top {
+				return p.errorf("unexpected '}'")
+			}
+			p.pos++
+
+			return nil
+		}
+
+		name := p.name()
+		if name == "" {
+			return p.errorf("unexpected %q", p.data[p.pos])
+		}
+
+		p.skipBlank()
+
+		if name == "include" && p.pos < len(p.data) && !strings.ContainsRune("{=:", rune(p.data[p.pos])) {
+			if err := p.include(m); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if p.pos == len(p.data) {
+			return p.errorf("unexpected end of file after %q", name)
+		}
+
+		switch p.data[p.pos] {
+		case '=':
+			p.pos++
+
+			v, err := p.value()
+			if err != nil {
+				return err
+			}
+
+			if err := m.Set(name, v); err != nil {
+				return p.errorf("invalid value for %q: %v", name, err)
+			}
+
+		case '{':
+			p.pos++
+
+			sub, ok := m.Get(name).(*vici.Message)
+			if !ok {
+				sub = vici.NewMessage()
+				if err := m.Set(name, sub); err != nil {
+					return p.errorf("invalid section %q: %v", name, err)
+				}
+			}
+
+			if err := p.parseSection(sub, false); err != nil {
+				return err
+			}
+
+		case ':':
+			return p.errorf("section templates are not supported")
+
+		default:
+			return p.errorf("expected '=' or '{' after %q", name)
+		}
+	}
+}
+
+// value reads the value of a key, up to the end of the line, a comment or a
+// closing brace.
+func (p *confParser) value() (string, error) {
+	p.skipBlank()
+
+	if p.pos < len(p.data) && p.data[p.pos] == '"' {
+		return p.quoted()
+	}
+
+	start := p.pos
+	for p.pos < len(p.data) && !strings.ContainsRune("\n#}", rune(p.data[p.pos])) {
+		p.pos++
+	}
+
+	return strings.TrimSpace(p.data[start:p.pos]), nil
+}
+
+// quoted reads a quoted value.
+func (p *confParser) quoted() (string, error) {
+	var b strings.Builder
+
+	p.pos++ // opening quote
+
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\n':
+			p.line++
+			b.WriteByte(c)
+		case '\\':
+			if p.pos == len(p.data) {
+				return "", p.errorf("unterminated string")
+			}
+
+			e := p.data[p.pos]
+			p.pos++
+
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '\n':
+				// Line continuation.
+				p.line++
+			default:
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", p.errorf("unterminated string")
+}
+
+// include includes the files matching the pattern following an include
+// statement.
+func (p *confParser) include(m *vici.Message) error {
+	pattern, err := p.value()
+	if err != nil {
+		return err
+	}
+
+	if pattern == "" {
+		return p.errorf("include requires a file pattern")
+	}
+
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(p.path), pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return p.errorf("invalid include pattern %q: %v", pattern, err)
+	}
+	sort.Strings(matches)
+
+	for _, path := range matches {
+		if err := parseConfFile(m, path, p.depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParseConf(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"swanctl.conf": `# swanctl.conf
+connections {
+	home {
+		remote_addrs = moon.strongswan.org # the gateway
+		local {
+			auth = pubkey
+			id = "  carol #1  "
+		}
+	}
+}
+secrets {
+	eap-carol { secret = "a\"b\\c" }
+}
+include conf.d/*.conf
+`,
+		"conf.d/b.conf": "connections {\n\thome {\n\t\tversion = 2\n\t}\n}\n",
+		"conf.d/a.conf": "connections {\n\thome {\n\t\tversion = 1\n\t\tproposals = aes128-sha256-x25519\n\t}\n}\n",
+	})
+
+	m, err := parseConf(filepath.Join(dir, "swanctl.conf"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	home := section(section(m, "connections"), "home")
+	if home == nil {
+		t.Fatalf("Expected connection home, got:\n%v", m)
+	}
+
+	for key, want := range map[string]string{
+		"remote_addrs": "moon.strongswan.org",
+		"version":      "2",
+		"proposals":    "aes128-sha256-x25519",
+	} {
+		if got := str(home, key); got != want {
+			t.Errorf("Expected %s = %q, got %q", key, want, got)
+		}
+	}
+
+	if got, want := str(section(home, "local"), "id"), "  carol #1  "; got != want {
+		t.Errorf("Expected id = %q, got %q", want, got)
+	}
+
+	if got, want := str(section(section(m, "secrets"), "eap-carol"), "secret"), `a"b\c`; got != want {
+		t.Errorf("Expected secret = %q, got %q", want, got)
+	}
+}
+
+func TestParseConfErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		conf string
+		err  string
+	}{
+		{name: "missing brace", conf: "connections {\n\thome {\n\t}\n", err: "swanctl.conf:4: unexpected end of file, missing '}'"},
+		{name: "extra brace", conf: "a = b\n}\n", err: "swanctl.conf:2: unexpected '}'"},
+		{name: "template", conf: "connections {\n\thome : base {\n\t}\n}\n", err: "swanctl.conf:2: section templates are not supported"},
+		{name: "missing value", conf: "connections\n", err: "swanctl.conf:1: expected '=' or '{' after \"connections\""},
+		{name: "unterminated string", conf: "a = \"b\n", err: "swanctl.conf:2: unterminated string"},
+		{name: "include loop", conf: "include swanctl.conf\n", err: "includes nested too deeply"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"swanctl.conf": tt.conf})
+
+			_, err := parseConf(filepath.Join(dir, "swanctl.conf"))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// initiate initiates a CHILD_SA, and prints the log output of the daemon
+// until it is complete.
+func (c *cli) initiate(ctx context.Context) error {
+	if c.opts.child == "" && c.opts.ike == "" {
+		return errors.New("initiate requires --child or --ike")
+	}
+
+	in, err := c.names(vici.Build()).
+		Set("timeout", c.opts.timeout*1000).
+		Set("init-limits", true).
+		Set("loglevel", c.opts.loglevel).
+		Message()
+	if err != nil {
+		return err
+	}
+
+	return c.control(ctx, "initiate", in)
+}
+
+// terminate terminates an IKE_SA or CHILD_SA, and prints the log output of
+// the daemon until it is complete.
+func (c *cli) terminate(ctx context.Context) error {
+	if !c.selected() {
+		return errors.New("terminate requires --ike, --ike-id, --child or --child-id")
+	}
+
+	b := c.ids(c.names(vici.Build()))
+	if c.opts.force {
+		b.Set("force", true)
+	}
+
+	in, err := b.Set("timeout", c.opts.timeout*1000).
+		Set("loglevel", c.opts.loglevel).
+		Message()
+	if err != nil {
+		return err
+	}
+
+	return c.control(ctx, "terminate", in)
+}
+
+// rekey rekeys an IKE_SA or CHILD_SA.
+func (c *cli) rekey(ctx context.Context) error {
+	if !c.selected() {
+		return errors.New("rekey requires --ike, --ike-id, --child or --child-id")
+	}
+
+	b := c.ids(c.names(vici.Build()))
+	if c.opts.reauth {
+		b.Set("reauth", true)
+	}
+
+	in, err := b.Message()
+	if err != nil {
+		return err
+	}
+
+	out, err := c.s.Call(ctx, "rekey", in)
+	if err != nil {
+		return failed("rekey", out, err)
+	}
+
+	if c.dump("rekey reply", out) {
+		return nil
+	}
+
+	fmt.Fprintf(c.out, "rekey completed successfully\n")
+
+	return nil
+}
+
+// control makes a command request which streams the control-log event, and
+// prints the log messages as they are received.
+func (c *cli) control(ctx context.Context, cmd string, in *vici.Message) error {
+	for m, err := range c.s.CallStreaming(ctx, cmd, "control-log", in) {
+		if err != nil {
+			return failed(cmd, m, err)
+		}
+
+		if c.dump("control-log event", m) {
+			continue
+		}
+
+		fmt.Fprintf(c.out, "[%s] %s\n", str(m, "group"), str(m, "msg"))
+	}
+
+	fmt.Fprintf(c.out, "%s completed successfully\n", cmd)
+
+	return nil
+}
+
+// selected reports whether an IKE_SA or CHILD_SA is selected by name or
+// unique identifier.
+func (c *cli) selected() bool {
+	return c.opts.ike != "" || c.opts.child != "" || c.opts.ikeID != 0 || c.opts.childID != 0
+}
+
+// names sets the selected IKE_SA and CHILD_SA names on b, and returns b.
+func (c *cli) names(b *vici.Builder) *vici.Builder {
+	if c.opts.child != "" {
+		b.Set("child", c.opts.child)
+	}
+	if c.opts.ike != "" {
+		b.Set("ike", c.opts.ike)
+	}
+
+	return b
+}
+
+// ids sets the selected IKE_SA and CHILD_SA unique identifiers on b, and
+// returns b.
+func (c *cli) ids(b *vici.Builder) *vici.Builder {
+	if c.opts.childID != 0 {
+		b.Set("child-id", c.opts.childID)
+	}
+	if c.opts.ikeID != 0 {
+		b.Set("ike-id", c.opts.ikeID)
+	}
+
+	return b
+}
+
+// log prints the log messages of the daemon until ctx is done, or the
+// session is closed.
+func (c *cli) log(ctx context.Context) error {
+	events := make(chan vici.Event, 128)
+	c.s.NotifyEvents(events)
+	defer c.s.StopEvents(events)
+
+	if err := c.s.SubscribeContext(ctx, "log"); err != nil {
+		return fmt.Errorf("subscribing to the log failed: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-events:
+			if !ok {
+				return fmt.Errorf("connection to the daemon lost: %w", c.s.Err())
+			}
+
+			if e.Name != "log" || c.dump("log", e.Message) {
+				continue
+			}
+
+			c.printLog(e.Message)
+		}
+	}
+}
+
+func (c *cli) printLog(m *vici.Message) {
+	thread := str(m, "thread")
+	if n, err := strconv.Atoi(thread); err == nil {
+		thread = fmt.Sprintf("%02d", n)
+	}
+
+	if name := str(m, "ikesa-name"); name != "" {
+		fmt.Fprintf(c.out, "%s[%s] <%s|%s> %s\n", thread, str(m, "group"), name, str(m, "ikesa-uniqueid"), str(m, "msg"))
+
+		return
+	}
+
+	fmt.Fprintf(c.out, "%s[%s] %s\n", thread, str(m, "group"), str(m, "msg"))
+}
@@ -0,0 +1,158 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// dump prints m with the given label, e.g. "list-sa event", if --raw or
+// --pretty is given, and reports whether it did.
+func (c *cli) dump(label string, m *vici.Message) bool {
+	switch {
+	case c.opts.pretty:
+		fmt.Fprintf(c.out, "%s {\n", label)
+		dumpPretty(c.out, m, "  ")
+		fmt.Fprintf(c.out, "}\n")
+	case c.opts.raw:
+		fmt.Fprintf(c.out, "%s {", label)
+		dumpRaw(c.out, m)
+		fmt.Fprintf(c.out, "}\n")
+	default:
+		return false
+	}
+
+	return true
+}
+
+// dumpRaw prints the elements of m in the compact form of swanctl --raw, e.g.
+// "name {key=value list=[a b]}".
+func dumpRaw(w io.Writer, m *vici.Message) {
+	for i, k := range m.Keys() {
+		if i > 0 {
+			fmt.Fprint(w, " ")
+		}
+
+		switch v := m.Get(k).(type) {
+		case *vici.Message:
+			fmt.Fprintf(w, "%s {", k)
+			dumpRaw(w, v)
+			fmt.Fprint(w, "}")
+		case []string:
+			items := make([]string, len(v))
+			for j, item := range v {
+				items[j] = printable(item)
+			}
+			fmt.Fprintf(w, "%s=[%s]", k, strings.Join(items, " "))
+		case string:
+			fmt.Fprintf(w, "%s=%s", k, printable(v))
+		}
+	}
+}
+
+// dumpPretty prints the elements of m in the indented form of swanctl --pretty.
+func dumpPretty(w io.Writer, m *vici.Message, indent string) {
+	for _, k := range m.Keys() {
+		switch v := m.Get(k).(type) {
+		case *vici.Message:
+			fmt.Fprintf(w, "%s%s {\n", indent, k)
+			dumpPretty(w, v, indent+"  ")
+			fmt.Fprintf(w, "%s}\n", indent)
+		case []string:
+			fmt.Fprintf(w, "%s%s = [\n", indent, k)
+			for _, item := range v {
+				fmt.Fprintf(w, "%s  %s\n", indent, printable(item))
+			}
+			fmt.Fprintf(w, "%s]\n", indent)
+		case string:
+			fmt.Fprintf(w, "%s%s = %s\n", indent, k, printable(v))
+		}
+	}
+}
+
+// printable returns v, or its hex encoding if it is binary, e.g. DER encoded
+// certificate data.
+func printable(v string) string {
+	if !utf8.ValidString(v) {
+		return "0x" + hex.EncodeToString([]byte(v))
+	}
+
+	for _, r := range v {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return "0x" + hex.EncodeToString([]byte(v))
+		}
+	}
+
+	return v
+}
+
+// str returns the string value of key in m, or "" if it is not a string.
+func str(m *vici.Message, key string) string {
+	s, _ := m.Get(key).(string)
+
+	return s
+}
+
+// list returns the list value of key in m. A string value is returned as a
+// list with a single item, unless it is empty.
+func list(m *vici.Message, key string) []string {
+	switch v := m.Get(key).(type) {
+	case []string:
+		return v
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	}
+
+	return nil
+}
+
+// section returns the section value of key in m, or nil if it is not a section.
+func section(m *vici.Message, key string) *vici.Message {
+	s, _ := m.Get(key).(*vici.Message)
+
+	return s
+}
+
+// sections returns the names and contents of the sections in m, in order.
+func sections(m *vici.Message) ([]string, []*vici.Message) {
+	var (
+		names []string
+		secs  []*vici.Message
+	)
+
+	for _, k := range m.Keys() {
+		if s := section(m, k); s != nil {
+			names = append(names, k)
+			secs = append(secs, s)
+		}
+	}
+
+	return names, secs
+}
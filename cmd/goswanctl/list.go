@@ -0,0 +1,570 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// listSAs lists the IKE_SAs and their CHILD_SAs.
+func (c *cli) listSAs(ctx context.Context) error {
+	b := vici.Build()
+	if c.opts.ike != "" {
+		b.Set("ike", c.opts.ike)
+	}
+	if c.opts.ikeID != 0 {
+		b.Set("ike-id", c.opts.ikeID)
+	}
+
+	in, err := b.Message()
+	if err != nil {
+		return err
+	}
+
+	for m, err := range c.s.CallStreaming(ctx, "list-sas", "list-sa", in) {
+		if err != nil {
+			return failed("list-sas", m, err)
+		}
+
+		if c.dump("list-sa event", m) {
+			continue
+		}
+
+		names, sas := sections(m)
+		for i, name := range names {
+			c.printIKESA(name, sas[i])
+		}
+	}
+
+	return nil
+}
+
+func (c *cli) printIKESA(name string, sa *vici.Message) {
+	ispi, rspi := "_i", "_r"
+	if str(sa, "initiator") == "yes" {
+		ispi += "*"
+	} else {
+		rspi += "*"
+	}
+
+	fmt.Fprintf(c.out, "%s: #%s, %s, IKEv%s, %s%s %s%s\n", name,
+		str(sa, "uniqueid"), str(sa, "state"), str(sa, "version"),
+		str(sa, "initiator-spi"), ispi, str(sa, "responder-spi"), rspi)
+
+	fmt.Fprintf(c.out, "  local  '%s' @ %s[%s]", str(sa, "local-id"), str(sa, "local-host"), str(sa, "local-port"))
+	for _, vip := range list(sa, "local-vips") {
+		fmt.Fprintf(c.out, " [%s]", vip)
+	}
+	fmt.Fprintln(c.out)
+
+	fmt.Fprintf(c.out, "  remote '%s' @ %s[%s]", str(sa, "remote-id"), str(sa, "remote-host"), str(sa, "remote-port"))
+	if id := str(sa, "remote-eap-id"); id != "" {
+		fmt.Fprintf(c.out, " EAP: '%s'", id)
+	}
+	if id := str(sa, "remote-xauth-id"); id != "" {
+		fmt.Fprintf(c.out, " XAuth: '%s'", id)
+	}
+	for _, vip := range list(sa, "remote-vips") {
+		fmt.Fprintf(c.out, " [%s]", vip)
+	}
+	fmt.Fprintln(c.out)
+
+	if algs := algorithms(sa, "encr-alg", "encr-keysize", "integ-alg", "integ-keysize", "prf-alg", "dh-group"); algs != "" {
+		fmt.Fprintf(c.out, "  %s\n", algs)
+	}
+
+	if established := str(sa, "established"); established != "" {
+		fmt.Fprintf(c.out, "  established %ss ago", established)
+		if t := str(sa, "rekey-time"); t != "" && t != "0" {
+			fmt.Fprintf(c.out, ", rekeying in %ss", t)
+		}
+		if t := str(sa, "reauth-time"); t != "" && t != "0" {
+			fmt.Fprintf(c.out, ", reauth in %ss", t)
+		}
+		fmt.Fprintln(c.out)
+	}
+
+	for _, tasks := range []string{"queued", "active", "passive"} {
+		if l := list(sa, "tasks-"+tasks); len(l) > 0 {
+			fmt.Fprintf(c.out, "  %-8s %s\n", tasks+":", strings.Join(l, " "))
+		}
+	}
+
+	if children := section(sa, "child-sas"); children != nil {
+		_, sas := sections(children)
+		for _, child := range sas {
+			c.printChildSA(child)
+		}
+	}
+}
+
+func (c *cli) printChildSA(sa *vici.Message) {
+	mode := str(sa, "mode")
+	if str(sa, "encap") == "yes" {
+		mode += "-in-UDP"
+	}
+
+	fmt.Fprintf(c.out, "  %s: #%s, reqid %s, %s, %s, %s:%s\n", str(sa, "name"),
+		str(sa, "uniqueid"), str(sa, "reqid"), str(sa, "state"), mode, str(sa, "protocol"),
+		algorithms(sa, "encr-alg", "encr-keysize", "integ-alg", "integ-keysize", "prf-alg", "dh-group"))
+
+	if installed := str(sa, "install-time"); installed != "" {
+		fmt.Fprintf(c.out, "    installed %ss ago", installed)
+		if t := str(sa, "rekey-time"); t != "" && t != "0" {
+			fmt.Fprintf(c.out, ", rekeying in %ss", t)
+		}
+		if t := str(sa, "life-time"); t != "" && t != "0" {
+			fmt.Fprintf(c.out, ", expires in %ss", t)
+		}
+		fmt.Fprintln(c.out)
+	}
+
+	for _, dir := range []string{"in", "out"} {
+		fmt.Fprintf(c.out, "    %-3s %s, %6s bytes, %5s packets", dir,
+			str(sa, "spi-"+dir), str(sa, "bytes-"+dir), str(sa, "packets-"+dir))
+		if use := str(sa, "use-"+dir); use != "" {
+			fmt.Fprintf(c.out, ", %5ss ago", use)
+		}
+		fmt.Fprintln(c.out)
+	}
+
+	fmt.Fprintf(c.out, "    local  %s\n", strings.Join(list(sa, "local-ts"), " "))
+	fmt.Fprintf(c.out, "    remote %s\n", strings.Join(list(sa, "remote-ts"), " "))
+}
+
+// algorithms formats the algorithms of an SA like swanctl, e.g.
+// "AES_CBC-128/HMAC_SHA2_256_128/PRF_HMAC_SHA2_256/ECP_256". The keys are
+// given as pairs of algorithm and key size, followed by the PRF and DH group.
+func algorithms(sa *vici.Message, encr, encrSize, integ, integSize, prf, dh string) string {
+	var parts []string
+
+	for _, alg := range [][2]string{{encr, encrSize}, {integ, integSize}} {
+		if name := str(sa, alg[0]); name != "" {
+			if size := str(sa, alg[1]); size != "" {
+				name += "-" + size
+			}
+			parts = append(parts, name)
+		}
+	}
+
+	for _, key := range []string{prf, dh} {
+		if name := str(sa, key); name != "" {
+			parts = append(parts, name)
+		}
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// listConns lists the loaded connections.
+func (c *cli) listConns(ctx context.Context) error {
+	b := vici.Build()
+	if c.opts.ike != "" {
+		b.Set("ike", c.opts.ike)
+	}
+
+	in, err := b.Message()
+	if err != nil {
+		return err
+	}
+
+	for m, err := range c.s.CallStreaming(ctx, "list-conns", "list-conn", in) {
+		if err != nil {
+			return failed("list-conns", m, err)
+		}
+
+		if c.dump("list-conn event", m) {
+			continue
+		}
+
+		names, conns := sections(m)
+		for i, name := range names {
+			c.printConn(name, conns[i])
+		}
+	}
+
+	return nil
+}
+
+func (c *cli) printConn(name string, conn *vici.Message) {
+	fmt.Fprintf(c.out, "%s: %s, %s, %s\n", name, str(conn, "version"),
+		every(str(conn, "reauth_time"), "reauthentication"),
+		every(str(conn, "rekey_time"), "rekeying"))
+
+	fmt.Fprintf(c.out, "  local:  %s\n", strings.Join(list(conn, "local_addrs"), " "))
+	fmt.Fprintf(c.out, "  remote: %s\n", strings.Join(list(conn, "remote_addrs"), " "))
+
+	names, secs := sections(conn)
+	for i, name := range names {
+		var side string
+
+		switch {
+		case strings.HasPrefix(name, "local"):
+			side = "local"
+		case strings.HasPrefix(name, "remote"):
+			side = "remote"
+		default:
+			continue
+		}
+
+		auth := str(secs[i], "class")
+		if eap := str(secs[i], "eap-type"); eap != "" {
+			auth += " (" + eap + ")"
+		}
+		fmt.Fprintf(c.out, "  %s %s authentication:\n", side, auth)
+
+		for _, key := range []string{"id", "eap_id", "xauth_id", "aaa_id", "groups", "cert_policy", "certs", "cacerts"} {
+			if l := list(secs[i], key); len(l) > 0 {
+				fmt.Fprintf(c.out, "    %s: %s\n", key, strings.Join(l, ", "))
+			}
+		}
+	}
+
+	children := section(conn, "children")
+	if children == nil {
+		return
+	}
+
+	names, secs = sections(children)
+	for i, name := range names {
+		child := secs[i]
+
+		fmt.Fprintf(c.out, "  %s: %s, %s", name, str(child, "mode"), every(str(child, "rekey_time"), "rekeying"))
+		if action := str(child, "dpd_action"); action != "" && action != "clear" {
+			fmt.Fprintf(c.out, ", dpd action is %s", action)
+		}
+		fmt.Fprintln(c.out)
+
+		fmt.Fprintf(c.out, "    local:  %s\n", strings.Join(list(child, "local-ts"), " "))
+		fmt.Fprintf(c.out, "    remote: %s\n", strings.Join(list(child, "remote-ts"), " "))
+	}
+}
+
+// every formats an interval in seconds, e.g. "rekeying every 3600s", or
+// "no rekeying" if the interval is zero.
+func every(seconds, what string) string {
+	if seconds == "" || seconds == "0" {
+		return "no " + what
+	}
+
+	return what + " every " + seconds + "s"
+}
+
+// certHeadings are the headings of the certificate lists, by type and flag.
+var certHeadings = map[[2]string]string{
+	{"X509", "NONE"}:          "List of X.509 End Entity Certificates",
+	{"X509", "CA"}:            "List of X.509 CA Certificates",
+	{"X509", "AA"}:            "List of X.509 AA Certificates",
+	{"X509", "OCSP"}:          "List of X.509 OCSP Signer Certificates",
+	{"X509_AC", "NONE"}:       "List of X.509 Attribute Certificates",
+	{"X509_CRL", "NONE"}:      "List of X.509 CRLs",
+	{"OCSP_RESPONSE", "NONE"}: "List of OCSP Responses",
+	{"PUBKEY", "NONE"}:        "List of Raw Public Keys",
+}
+
+// listCerts lists the stored certificates.
+func (c *cli) listCerts(ctx context.Context) error {
+	b := vici.Build()
+	if c.opts.subject != "" {
+		b.Set("subject", c.opts.subject)
+	}
+
+	in, err := b.Message()
+	if err != nil {
+		return err
+	}
+
+	var heading string
+
+	for m, err := range c.s.CallStreaming(ctx, "list-certs", "list-cert", in) {
+		if err != nil {
+			return failed("list-certs", m, err)
+		}
+
+		if c.dump("list-cert event", m) {
+			continue
+		}
+
+		typ, flag := str(m, "type"), str(m, "flag")
+		if flag == "" {
+			flag = "NONE"
+		}
+
+		h, ok := certHeadings[[2]string{typ, flag}]
+		if !ok {
+			h = "List of " + typ + " Certificates"
+		}
+
+		if h != heading {
+			heading = h
+			fmt.Fprintf(c.out, "\n%s\n", heading)
+		}
+		fmt.Fprintln(c.out)
+
+		c.printCert(m)
+	}
+
+	return nil
+}
+
+func (c *cli) printCert(m *vici.Message) {
+	data := []byte(str(m, "data"))
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+
+	var cert *x509.Certificate
+	if str(m, "type") == "X509" {
+		cert, _ = x509.ParseCertificate(data)
+	}
+
+	if cert == nil {
+		fmt.Fprintf(c.out, "  %d bytes of %s data\n", len(data), str(m, "type"))
+
+		return
+	}
+
+	now := time.Now()
+
+	fmt.Fprintf(c.out, "  subject:  \"%s\"\n", distinguishedName(cert.RawSubject))
+	fmt.Fprintf(c.out, "  issuer:   \"%s\"\n", distinguishedName(cert.RawIssuer))
+	fmt.Fprintf(c.out, "  validity:  not before %s, %s\n", cert.NotBefore.Local().Format(time.Stamp+" 2006"),
+		validity(now.After(cert.NotBefore), "not valid yet (valid in %s)", cert.NotBefore.Sub(now)))
+	fmt.Fprintf(c.out, "             not after  %s, %s\n", cert.NotAfter.Local().Format(time.Stamp+" 2006"),
+		validity(now.Before(cert.NotAfter), "expired (%s ago)", now.Sub(cert.NotAfter), cert.NotAfter.Sub(now)))
+	fmt.Fprintf(c.out, "  serial:    %s\n", colonHex(cert.SerialNumber.Bytes()))
+
+	if names := altNames(cert); len(names) > 0 {
+		fmt.Fprintf(c.out, "  altNames:  %s\n", strings.Join(names, ", "))
+	}
+
+	if flags := certFlags(cert); len(flags) > 0 {
+		fmt.Fprintf(c.out, "  flags:     %s\n", strings.Join(flags, " "))
+	}
+
+	if len(cert.AuthorityKeyId) > 0 {
+		fmt.Fprintf(c.out, "  authkeyId: %s\n", colonHex(cert.AuthorityKeyId))
+	}
+	if len(cert.SubjectKeyId) > 0 {
+		fmt.Fprintf(c.out, "  subjkeyId: %s\n", colonHex(cert.SubjectKeyId))
+	}
+
+	pubkey := publicKey(cert)
+	if str(m, "has_privkey") == "yes" {
+		pubkey += ", has private key"
+	}
+	fmt.Fprintf(c.out, "  pubkey:    %s\n", pubkey)
+}
+
+// validity formats the result of a validity check. If the check failed, the
+// message is formatted with the first duration. Otherwise, "ok" is returned,
+// with the remaining validity if a second duration is given.
+func validity(ok bool, msg string, d time.Duration, remaining ...time.Duration) string {
+	if !ok {
+		return fmt.Sprintf(msg, days(d))
+	}
+
+	if len(remaining) > 0 {
+		return "ok (expires in " + days(remaining[0]) + ")"
+	}
+
+	return "ok"
+}
+
+func days(d time.Duration) string {
+	n := int(d.Hours() / 24)
+	if n == 1 {
+		return "1 day"
+	}
+
+	return strconv.Itoa(n) + " days"
+}
+
+// attributeNames are the short names of the attribute types of distinguished
+// names, as printed by strongSwan.
+var attributeNames = map[string]string{
+	"2.5.4.3":                    "CN",
+	"2.5.4.4":                    "SN",
+	"2.5.4.5":                    "serialNumber",
+	"2.5.4.6":                    "C",
+	"2.5.4.7":                    "L",
+	"2.5.4.8":                    "ST",
+	"2.5.4.10":                   "O",
+	"2.5.4.11":                   "OU",
+	"2.5.4.12":                   "T",
+	"2.5.4.42":                   "G",
+	"0.9.2342.19200300.100.1.25": "DC",
+	"0.9.2342.19200300.100.1.1":  "UID",
+	"1.2.840.113549.1.9.1":       "E",
+}
+
+// distinguishedName formats a DER encoded distinguished name like strongSwan,
+// i.e. in the encoded order, e.g. "C=CH, O=strongSwan Project, CN=moon".
+func distinguishedName(der []byte) string {
+	var rdns pkix.RDNSequence
+	if _, err := asn1.Unmarshal(der, &rdns); err != nil {
+		return fmt.Sprintf("0x%x", der)
+	}
+
+	var parts []string
+
+	for _, rdn := range rdns {
+		for _, atv := range rdn {
+			name, ok := attributeNames[atv.Type.String()]
+			if !ok {
+				name = atv.Type.String()
+			}
+
+			parts = append(parts, fmt.Sprintf("%s=%v", name, atv.Value))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func altNames(cert *x509.Certificate) []string {
+	var names []string
+
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+
+	return names
+}
+
+func certFlags(cert *x509.Certificate) []string {
+	var flags []string
+
+	if cert.IsCA {
+		flags = append(flags, "CA")
+	}
+
+	for _, usage := range cert.ExtKeyUsage {
+		switch usage {
+		case x509.ExtKeyUsageServerAuth:
+			flags = append(flags, "serverAuth")
+		case x509.ExtKeyUsageClientAuth:
+			flags = append(flags, "clientAuth")
+		case x509.ExtKeyUsageOCSPSigning:
+			flags = append(flags, "ocspSigning")
+		}
+	}
+
+	if cert.Subject.String() == cert.Issuer.String() {
+		flags = append(flags, "self-signed")
+	}
+
+	return flags
+}
+
+func publicKey(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d bits", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %d bits", key.Curve.Params().BitSize)
+	case ed25519.PublicKey:
+		return "ED25519 256 bits"
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
+
+func colonHex(b []byte) string {
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02x", v)
+	}
+
+	return strings.Join(parts, ":")
+}
+
+// stats shows the daemon's statistics.
+func (c *cli) stats(ctx context.Context) error {
+	m, err := c.s.Call(ctx, "stats", nil)
+	if err != nil {
+		return failed("stats", m, err)
+	}
+
+	if c.dump("stats reply", m) {
+		return nil
+	}
+
+	if uptime := section(m, "uptime"); uptime != nil {
+		fmt.Fprintf(c.out, "uptime: %s, since %s\n", str(uptime, "running"), str(uptime, "since"))
+	}
+
+	if workers := section(m, "workers"); workers != nil {
+		fmt.Fprintf(c.out, "worker threads: %s total, %s idle, working: %s\n",
+			str(workers, "total"), str(workers, "idle"), priorities(section(workers, "active")))
+	}
+
+	fmt.Fprintf(c.out, "job queues: %s\n", priorities(section(m, "queues")))
+	fmt.Fprintf(c.out, "jobs scheduled: %s\n", str(m, "scheduled"))
+
+	if ikesas := section(m, "ikesas"); ikesas != nil {
+		fmt.Fprintf(c.out, "IKE_SAs: %s total, %s half-open\n", str(ikesas, "total"), str(ikesas, "half-open"))
+	}
+
+	if mem := section(m, "mem"); mem != nil {
+		fmt.Fprintf(c.out, "allocations: %s bytes, %s allocations\n", str(mem, "total"), str(mem, "allocs"))
+	}
+
+	if mi := section(m, "mallinfo"); mi != nil {
+		fmt.Fprintf(c.out, "mallinfo: sbrk %s, mmap %s, used %s, free %s\n",
+			str(mi, "sbrk"), str(mi, "mmap"), str(mi, "used"), str(mi, "free"))
+	}
+
+	if plugins := list(m, "plugins"); len(plugins) > 0 {
+		fmt.Fprintf(c.out, "loaded plugins: %s\n", strings.Join(plugins, " "))
+	}
+
+	return nil
+}
+
+// priorities formats the values of a section by job priority, e.g. "4/0/1/0".
+func priorities(m *vici.Message) string {
+	if m == nil {
+		return ""
+	}
+
+	return strings.Join([]string{str(m, "critical"), str(m, "high"), str(m, "medium"), str(m, "low")}, "/")
+}
@@ -0,0 +1,601 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// certDirs are the directories of certificates loaded with load-cert, relative
+// to the configuration directory, with the type and flag of their contents.
+var certDirs = []struct {
+	dir  string
+	typ  string
+	flag string
+}{
+	{"x509", "X509", "NONE"},
+	{"x509ca", "X509", "CA"},
+	{"x509aa", "X509", "AA"},
+	{"x509ocsp", "X509", "OCSP"},
+	{"x509crl", "X509_CRL", "NONE"},
+	{"x509ac", "X509_AC", "NONE"},
+}
+
+// keyDirs are the directories of private keys loaded with load-key, relative
+// to the configuration directory, with the type of their contents.
+var keyDirs = []struct {
+	dir string
+	typ string
+}{
+	{"private", "any"},
+	{"rsa", "rsa"},
+	{"ecdsa", "ecdsa"},
+	{"pkcs8", "any"},
+}
+
+// sharedSecrets are the prefixes of the sections in the secrets section which
+// are loaded with load-shared, and their types.
+var sharedSecrets = map[string]string{
+	"eap":   "EAP",
+	"xauth": "XAUTH",
+	"ntlm":  "NTLM",
+	"ike":   "IKE",
+	"ppk":   "PPK",
+}
+
+// connLists are the connection settings which take a comma separated list of
+// values, and are sent to the daemon as lists.
+var connLists = []string{
+	"local_addrs", "remote_addrs", "proposals", "vips", "pools",
+	"esp_proposals", "ah_proposals", "local_ts", "remote_ts",
+	"groups", "cert_policy", "ca_id",
+}
+
+// authFiles are the auth settings which name files, and the directories they
+// are loaded from.
+var authFiles = map[string]string{
+	"certs":   "x509",
+	"cacerts": "x509ca",
+	"pubkeys": "pubkey",
+}
+
+// loadAll loads the credentials, authorities, pools and connections from the
+// configuration file, and unloads the private keys, shared secrets,
+// authorities, pools and connections not configured anymore.
+func (c *cli) loadAll(ctx context.Context) error {
+	conf, err := parseConf(c.opts.file)
+	if err != nil {
+		return fmt.Errorf("parsing the configuration failed: %w", err)
+	}
+
+	dir := filepath.Dir(c.opts.file)
+
+	if err := c.loadCreds(ctx, dir, section(conf, "secrets")); err != nil {
+		return err
+	}
+
+	if err := c.loadAuthorities(ctx, dir, section(conf, "authorities")); err != nil {
+		return err
+	}
+
+	if err := c.loadPools(ctx, section(conf, "pools")); err != nil {
+		return err
+	}
+
+	return c.loadConns(ctx, dir, section(conf, "connections"))
+}
+
+// load makes a load command request, and returns the reply and whether it was
+// dumped.
+func (c *cli) load(ctx context.Context, cmd string, what string, in *vici.Message) (*vici.Message, bool, error) {
+	out, err := c.s.Call(ctx, cmd, in)
+	if err != nil {
+		return nil, false, failed("loading "+what, out, err)
+	}
+
+	return out, c.dump(cmd+" reply", out), nil
+}
+
+// loadCreds loads the certificates and private keys from the configuration
+// directory, and the shared secrets and tokens from the secrets section, and
+// unloads the private keys and shared secrets not configured anymore.
+func (c *cli) loadCreds(ctx context.Context, dir string, secrets *vici.Message) error {
+	// The IDs of the private keys and shared secrets loaded, so that the
+	// others can be unloaded.
+	var keys, shared []string
+
+	for _, d := range certDirs {
+		err := c.loadFiles(ctx, filepath.Join(dir, d.dir), func(path string, data []byte) error {
+			in, err := vici.Build().
+				Set("type", d.typ).
+				Set("flag", d.flag).
+				Set("data", string(data)).
+				Message()
+			if err != nil {
+				return err
+			}
+
+			if _, dumped, err := c.load(ctx, "load-cert", fmt.Sprintf("certificate from '%s'", path), in); err != nil || dumped {
+				return err
+			}
+
+			fmt.Fprintf(c.out, "loaded certificate from '%s'\n", path)
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, d := range keyDirs {
+		err := c.loadFiles(ctx, filepath.Join(dir, d.dir), func(path string, data []byte) error {
+			in, err := vici.Build().
+				Set("type", d.typ).
+				Set("data", string(data)).
+				Message()
+			if err != nil {
+				return err
+			}
+
+			out, dumped, err := c.load(ctx, "load-key", fmt.Sprintf("key from '%s'", path), in)
+			if err != nil {
+				return err
+			}
+
+			keys = appendID(keys, str(out, "id"))
+			if dumped {
+				return nil
+			}
+
+			fmt.Fprintf(c.out, "loaded key from '%s'\n", path)
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if secrets == nil {
+		secrets = vici.NewMessage()
+	}
+
+	// Secrets other than shared secrets and tokens, e.g. passphrases of
+	// private keys, are ignored.
+	names, secs := sections(secrets)
+	for i, name := range names {
+		if strings.HasPrefix(name, "token") {
+			id, err := c.loadToken(ctx, name, secs[i])
+			if err != nil {
+				return err
+			}
+
+			keys = appendID(keys, id)
+
+			continue
+		}
+
+		for prefix, typ := range sharedSecrets {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+
+			if err := c.loadShared(ctx, name, typ, secs[i]); err != nil {
+				return err
+			}
+
+			shared = appendID(shared, name)
+
+			break
+		}
+	}
+
+	out, err := c.s.Call(ctx, "get-keys", nil)
+	if err != nil {
+		return failed("get-keys", out, err)
+	}
+
+	unloaded, err := c.unload(ctx, "unload-key", "private key", "id", list(out, "keys"), keys)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "successfully loaded %d unique private keys, %d unloaded\n", len(keys), unloaded)
+
+	out, err = c.s.Call(ctx, "get-shared", nil)
+	if err != nil {
+		return failed("get-shared", out, err)
+	}
+
+	unloaded, err = c.unload(ctx, "unload-shared", "shared secret", "id", list(out, "keys"), shared)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "successfully loaded %d unique shared secrets, %d unloaded\n", len(shared), unloaded)
+
+	return nil
+}
+
+// appendID appends a non-empty id to ids, unless it is already included.
+func appendID(ids []string, id string) []string {
+	if id == "" || slices.Contains(ids, id) {
+		return ids
+	}
+
+	return append(ids, id)
+}
+
+// loadFiles calls fn with the contents of the files in dir, in lexical order.
+// A missing directory is not an error.
+func (c *cli) loadFiles(ctx context.Context, dir string, fn func(path string, data []byte) error) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, e.Name())
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(path, data); err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadToken loads the private key on the token configured in the named section
+// of the secrets section, and returns its ID.
+func (c *cli) loadToken(ctx context.Context, name string, sec *vici.Message) (string, error) {
+	b := vici.Build()
+	for _, key := range []string{"handle", "slot", "module", "pin"} {
+		if v := str(sec, key); v != "" {
+			b.Set(key, v)
+		}
+	}
+
+	in, err := b.Message()
+	if err != nil {
+		return "", err
+	}
+
+	out, dumped, err := c.load(ctx, "load-token", fmt.Sprintf("token '%s'", name), in)
+	if err != nil {
+		return "", err
+	}
+	if dumped {
+		return str(out, "id"), nil
+	}
+
+	fmt.Fprintf(c.out, "loaded key %s from token\n", name)
+
+	return str(out, "id"), nil
+}
+
+// loadShared loads the shared secret of the given type configured in the named
+// section of the secrets section.
+func (c *cli) loadShared(ctx context.Context, name, typ string, sec *vici.Message) error {
+	data, err := decodeSecret(str(sec, "secret"))
+	if err != nil {
+		return fmt.Errorf("invalid secret '%s': %w", name, err)
+	}
+
+	var owners []string
+	for _, key := range sec.Keys() {
+		if key == "id" || strings.HasPrefix(key, "id-") {
+			owners = append(owners, str(sec, key))
+		}
+	}
+
+	b := vici.Build().
+		Set("id", name).
+		Set("type", typ).
+		Set("data", data)
+	if len(owners) > 0 {
+		b.List("owners", owners...)
+	}
+
+	in, err := b.Message()
+	if err != nil {
+		return err
+	}
+
+	if _, dumped, err := c.load(ctx, "load-shared", fmt.Sprintf("%s secret '%s'", strings.ToLower(typ), name), in); err != nil || dumped {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "loaded %s secret '%s'\n", strings.ToLower(typ), name)
+
+	return nil
+}
+
+// decodeSecret decodes a secret given in hex with the prefix "0x", or in
+// base64 with the prefix "0s". Other secrets are returned as they are.
+func decodeSecret(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, "0x"):
+		b, err := hex.DecodeString(s[2:])
+
+		return string(b), err
+	case strings.HasPrefix(s, "0s"):
+		b, err := base64.StdEncoding.DecodeString(s[2:])
+
+		return string(b), err
+	default:
+		return s, nil
+	}
+}
+
+func (c *cli) loadAuthorities(ctx context.Context, dir string, authorities *vici.Message) error {
+	if authorities == nil {
+		authorities = vici.NewMessage()
+	}
+
+	names, secs := sections(authorities)
+	for i, name := range names {
+		b := vici.Build().Section(name)
+
+		for _, key := range secs[i].Keys() {
+			switch key {
+			case "cacert":
+				path := confPath(dir, "x509ca", str(secs[i], key))
+
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+
+				b.Set(key, string(data))
+			case "crl_uris", "ocsp_uris":
+				b.List(key, splitList(str(secs[i], key))...)
+			default:
+				b.Set(key, secs[i].Get(key))
+			}
+		}
+
+		in, err := b.Message()
+		if err != nil {
+			return err
+		}
+
+		if _, dumped, err := c.load(ctx, "load-authority", fmt.Sprintf("authority '%s'", name), in); err != nil || dumped {
+			return err
+		}
+
+		fmt.Fprintf(c.out, "loaded authority '%s'\n", name)
+	}
+
+	out, err := c.s.Call(ctx, "get-authorities", nil)
+	if err != nil {
+		return failed("get-authorities", out, err)
+	}
+
+	unloaded, err := c.unload(ctx, "unload-authority", "authority", "name", list(out, "authorities"), names)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "successfully loaded %d authorities, %d unloaded\n", len(names), unloaded)
+
+	return nil
+}
+
+func (c *cli) loadPools(ctx context.Context, pools *vici.Message) error {
+	if pools == nil {
+		pools = vici.NewMessage()
+	}
+
+	names, secs := sections(pools)
+	for i, name := range names {
+		b := vici.Build().Section(name)
+
+		for _, key := range secs[i].Keys() {
+			v := str(secs[i], key)
+			if key == "addrs" {
+				b.Set(key, v)
+			} else {
+				b.List(key, splitList(v)...)
+			}
+		}
+
+		in, err := b.Message()
+		if err != nil {
+			return err
+		}
+
+		if _, dumped, err := c.load(ctx, "load-pool", fmt.Sprintf("pool '%s'", name), in); err != nil || dumped {
+			return err
+		}
+
+		fmt.Fprintf(c.out, "loaded pool '%s'\n", name)
+	}
+
+	out, err := c.s.Call(ctx, "get-pools", nil)
+	if err != nil {
+		return failed("get-pools", out, err)
+	}
+
+	loaded, _ := sections(out)
+
+	unloaded, err := c.unload(ctx, "unload-pool", "pool", "name", loaded, names)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "successfully loaded %d pools, %d unloaded\n", len(names), unloaded)
+
+	return nil
+}
+
+func (c *cli) loadConns(ctx context.Context, dir string, conns *vici.Message) error {
+	if conns == nil {
+		conns = vici.NewMessage()
+	}
+
+	names, secs := sections(conns)
+	for i, name := range names {
+		b := vici.Build().Section(name)
+		if err := connSection(b, dir, secs[i]); err != nil {
+			return fmt.Errorf("loading connection '%s' failed: %w", name, err)
+		}
+
+		in, err := b.Message()
+		if err != nil {
+			return fmt.Errorf("loading connection '%s' failed: %w", name, err)
+		}
+
+		if _, dumped, err := c.load(ctx, "load-conn", fmt.Sprintf("connection '%s'", name), in); err != nil || dumped {
+			return err
+		}
+
+		fmt.Fprintf(c.out, "loaded connection '%s'\n", name)
+	}
+
+	out, err := c.s.Call(ctx, "get-conns", nil)
+	if err != nil {
+		return failed("get-conns", out, err)
+	}
+
+	unloaded, err := c.unload(ctx, "unload-conn", "connection", "name", list(out, "conns"), names)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "successfully loaded %d connections, %d unloaded\n", len(names), unloaded)
+
+	return nil
+}
+
+// unload unloads the loaded objects which are not configured, identified by
+// the given key of the unload request, and returns how many were unloaded.
+func (c *cli) unload(ctx context.Context, cmd, what, key string, loaded, configured []string) (int, error) {
+	var n int
+
+	for _, name := range loaded {
+		if slices.Contains(configured, name) {
+			continue
+		}
+
+		in, err := vici.Build().Set(key, name).Message()
+		if err != nil {
+			return n, err
+		}
+
+		out, err := c.s.Call(ctx, cmd, in)
+		if err != nil {
+			return n, failed(fmt.Sprintf("unloading %s '%s'", what, name), out, err)
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// connSection adds a connection section of the configuration to the current
+// section of b as expected by load-conn, i.e. splits the list settings, and
+// replaces the file names of certificates and public keys with their contents.
+func connSection(b *vici.Builder, dir string, conf *vici.Message) error {
+	for _, key := range conf.Keys() {
+		switch v := conf.Get(key).(type) {
+		case *vici.Message:
+			b.Section(key)
+			if err := connSection(b, dir, v); err != nil {
+				return err
+			}
+			b.End()
+
+		case string:
+			switch {
+			case authFiles[key] != "":
+				var files []string
+
+				for _, name := range splitList(v) {
+					data, err := os.ReadFile(confPath(dir, authFiles[key], name))
+					if err != nil {
+						return err
+					}
+					files = append(files, string(data))
+				}
+
+				b.List(key, files...)
+
+			case slices.Contains(connLists, key):
+				b.List(key, splitList(v)...)
+
+			default:
+				b.Set(key, v)
+			}
+		}
+	}
+
+	return nil
+}
+
+// confPath returns the path of a file referenced in the configuration, which
+// is relative to the given subdirectory of the configuration directory unless
+// it is absolute.
+func confPath(dir, sub, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+
+	return filepath.Join(dir, sub, name)
+}
+
+// splitList splits a comma separated list, e.g. "10.1.0.0/16, 10.2.0.0/16".
+func splitList(s string) []string {
+	var l []string
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			l = append(l, v)
+		}
+	}
+
+	return l
+}
@@ -0,0 +1,256 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+func TestLoadAll(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"x509/carol.pem":    "carol cert",
+		"x509ca/ca.pem":     "ca cert",
+		"private/carol.pem": "carol key",
+		"pubkey/moon.pub":   "moon key",
+		"swanctl.conf": `
+connections {
+	home {
+		remote_addrs = moon.strongswan.org
+		proposals = aes128-sha256-x25519, aes256-sha384-ecp384
+		local {
+			certs = carol.pem
+		}
+		remote {
+			pubkeys = moon.pub
+		}
+		children {
+			home {
+				remote_ts = 10.1.0.0/16, 10.2.0.0/16
+			}
+		}
+	}
+}
+pools {
+	rw {
+		addrs = 10.3.0.0/24
+		dns = 10.1.0.1, 10.1.0.2
+	}
+}
+authorities {
+	ca {
+		cacert = ca.pem
+		crl_uris = http://crl.strongswan.org/ca.crl
+	}
+}
+secrets {
+	ike-moon {
+		id = moon.strongswan.org
+		id-2 = carol@strongswan.org
+		secret = 0x736563726574
+	}
+	eap-carol {
+		secret = 0sc2VjcmV0
+	}
+	private-carol {
+		file = carol.pem
+		secret = passphrase
+	}
+}
+`,
+	})
+
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	var (
+		mu   sync.Mutex
+		reqs = make(map[string][]*vici.Message)
+	)
+
+	for _, cmd := range []string{
+		"load-cert", "load-key", "load-shared", "load-authority", "load-pool", "load-conn",
+		"unload-key", "unload-shared", "unload-authority", "unload-pool", "unload-conn",
+	} {
+		srv.Handle(cmd, func(r *vicitest.Request) (*vici.Message, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			reqs[cmd] = append(reqs[cmd], r.Message)
+
+			b := vici.Build().Set("success", "yes")
+			if cmd == "load-key" {
+				b.Set("id", "carol-id")
+			}
+
+			return b.Message()
+		})
+	}
+
+	srv.Handle("get-keys", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().List("keys", "carol-id", "old-key").Message()
+	})
+	srv.Handle("get-shared", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().List("keys", "ike-moon", "old-secret", "eap-carol").Message()
+	})
+
+	srv.Handle("get-authorities", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().List("authorities", "ca", "old-ca").Message()
+	})
+	srv.Handle("get-pools", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().Section("rw").Set("base", "10.3.0.0").End().Message()
+	})
+	srv.Handle("get-conns", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().List("conns", "home", "old-1", "old-2").Message()
+	})
+
+	out, err := goswanctl(context.Background(), srv, "--load-all", "--file", filepath.Join(dir, "swanctl.conf"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectOutput(t, out,
+		"loaded certificate from '"+filepath.Join(dir, "x509/carol.pem")+"'\n",
+		"loaded certificate from '"+filepath.Join(dir, "x509ca/ca.pem")+"'\n",
+		"loaded key from '"+filepath.Join(dir, "private/carol.pem")+"'\n",
+		"loaded ike secret 'ike-moon'\n",
+		"loaded eap secret 'eap-carol'\n",
+		"successfully loaded 1 unique private keys, 1 unloaded\n",
+		"successfully loaded 2 unique shared secrets, 1 unloaded\n",
+		"loaded authority 'ca'\n",
+		"successfully loaded 1 authorities, 1 unloaded\n",
+		"loaded pool 'rw'\n",
+		"successfully loaded 1 pools, 0 unloaded\n",
+		"loaded connection 'home'\n",
+		"successfully loaded 1 connections, 2 unloaded\n",
+	)
+
+	if strings.Contains(out, "private-carol") {
+		t.Errorf("Expected private key passphrase to be skipped, got:\n%s", out)
+	}
+
+	if n := len(reqs["load-cert"]); n != 2 {
+		t.Fatalf("Expected 2 load-cert requests, got %d", n)
+	}
+	if flag := reqs["load-cert"][1].Get("flag"); flag != "CA" {
+		t.Errorf("Expected CA flag for x509ca certificate, got %v", flag)
+	}
+
+	ike := reqs["load-shared"][0]
+	if data, owners := ike.Get("data"), ike.Get("owners"); data != "secret" ||
+		!slices.Equal(owners.([]string), []string{"moon.strongswan.org", "carol@strongswan.org"}) {
+		t.Errorf("Unexpected IKE secret request:\n%v", ike)
+	}
+	if data := reqs["load-shared"][1].Get("data"); data != "secret" {
+		t.Errorf("Expected base64 decoded EAP secret, got %q", data)
+	}
+
+	ca := section(reqs["load-authority"][0], "ca")
+	if cacert, uris := str(ca, "cacert"), list(ca, "crl_uris"); cacert != "ca cert" || len(uris) != 1 {
+		t.Errorf("Unexpected authority request:\n%v", ca)
+	}
+
+	rw := section(reqs["load-pool"][0], "rw")
+	if addrs, dns := rw.Get("addrs"), list(rw, "dns"); addrs != "10.3.0.0/24" || len(dns) != 2 {
+		t.Errorf("Unexpected pool request:\n%v", rw)
+	}
+
+	home := section(reqs["load-conn"][0], "home")
+	if got := list(home, "proposals"); len(got) != 2 {
+		t.Errorf("Expected 2 proposals, got %v", got)
+	}
+	if got := list(section(home, "local"), "certs"); !slices.Equal(got, []string{"carol cert"}) {
+		t.Errorf("Expected certificate data in local auth, got %v", got)
+	}
+	if got := list(section(home, "remote"), "pubkeys"); !slices.Equal(got, []string{"moon key"}) {
+		t.Errorf("Expected public key data in remote auth, got %v", got)
+	}
+	if got := list(section(section(home, "children"), "home"), "remote_ts"); len(got) != 2 {
+		t.Errorf("Expected 2 remote traffic selectors, got %v", got)
+	}
+
+	var unloaded []string
+	for _, m := range append(reqs["unload-key"], reqs["unload-shared"]...) {
+		unloaded = append(unloaded, str(m, "id"))
+	}
+	for _, m := range append(reqs["unload-authority"], reqs["unload-conn"]...) {
+		unloaded = append(unloaded, str(m, "name"))
+	}
+	if want := []string{"old-key", "old-secret", "old-ca", "old-1", "old-2"}; !slices.Equal(unloaded, want) {
+		t.Errorf("Expected %v to be unloaded, got %v", want, unloaded)
+	}
+}
+
+func TestLoadAllFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, map[string]string{
+		"swanctl.conf": "connections {\n\thome {\n\t\tversion = 3\n\t}\n}\n",
+	})
+
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("load-conn", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().Set("success", "no").Set("errmsg", "invalid value for: version, config discarded").Message()
+	})
+	for _, cmd := range []string{"get-keys", "get-shared", "get-authorities"} {
+		srv.Handle(cmd, func(_ *vicitest.Request) (*vici.Message, error) {
+			return vici.NewMessage(), nil
+		})
+	}
+	srv.Handle("get-pools", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.NewMessage(), nil
+	})
+
+	_, err := goswanctl(context.Background(), srv, "--load-all", "--file", filepath.Join(dir, "swanctl.conf"))
+	if want := "loading connection 'home' failed: invalid value for: version, config discarded"; err == nil || err.Error() != want {
+		t.Fatalf("Expected error %q, got %v", want, err)
+	}
+}
+
+func TestDecodeSecret(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "secret", want: "secret"},
+		{in: "0x736563726574", want: "secret"},
+		{in: "0sc2VjcmV0", want: "secret"},
+		{in: "0xzz", err: true},
+		{in: "0s!!", err: true},
+	} {
+		got, err := decodeSecret(tt.in)
+		if (err != nil) != tt.err || got != tt.want && !tt.err {
+			t.Errorf("decodeSecret(%q): expected %q (error: %v), got %q (%v)", tt.in, tt.want, tt.err, got, err)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Command goswanctl is a swanctl compatible command line tool, for systems where
+// the swanctl binary is not available. It supports a subset of the swanctl
+// commands, with swanctl-like output:
+//
+//	goswanctl --list-sas [--ike name] [--ike-id id]
+//	goswanctl --list-conns [--ike name]
+//	goswanctl --list-certs [--subject dn]
+//	goswanctl --load-all [--file swanctl.conf]
+//	goswanctl --initiate --child name [--ike name] [--timeout s] [--loglevel level]
+//	goswanctl --terminate --ike name|--ike-id id|--child name|--child-id id [--force] [--timeout s]
+//	goswanctl --rekey --ike name|--ike-id id|--child name|--child-id id [--reauth]
+//	goswanctl --stats
+//	goswanctl --log
+//
+// With --raw or --pretty, the messages received from the daemon are printed as
+// they are, in a compact or an indented form, respectively. The daemon is
+// reached through the platform default socket, or the one given with --uri,
+// e.g. unix:///var/run/charon.vici or tcp://127.0.0.1:4502.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/strongswan/govici/vici"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout, os.Stderr, nil); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "goswanctl: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// options holds the command line options.
+type options struct {
+	ike      string
+	ikeID    uint
+	child    string
+	childID  uint
+	subject  string
+	file     string
+	timeout  uint
+	loglevel int
+	force    bool
+	reauth   bool
+	raw      bool
+	pretty   bool
+}
+
+// cli runs a command on a session.
+type cli struct {
+	s    *vici.Session
+	out  io.Writer
+	opts *options
+}
+
+// commands are the supported commands, in the order of the usage text.
+var commands = []struct {
+	name  string
+	usage string
+	run   func(c *cli, ctx context.Context) error
+}{
+	{"list-sas", "list currently active IKE_SAs", (*cli).listSAs},
+	{"list-conns", "list loaded configurations", (*cli).listConns},
+	{"list-certs", "list stored certificates", (*cli).listCerts},
+	{"load-all", "load credentials, authorities, pools and connections", (*cli).loadAll},
+	{"initiate", "initiate a connection", (*cli).initiate},
+	{"terminate", "terminate a connection", (*cli).terminate},
+	{"rekey", "rekey an SA", (*cli).rekey},
+	{"stats", "show daemon stats information", (*cli).stats},
+	{"log", "trace logging output", (*cli).log},
+}
+
+// run parses the command line arguments, and runs the selected command. The
+// session is established using dial, or as given with --uri if dial is nil.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, dial func(context.Context, string, string) (net.Conn, error)) error {
+	var (
+		opts     options
+		uri      string
+		selected = make([]bool, len(commands))
+	)
+
+	fs := flag.NewFlagSet("goswanctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	for i, cmd := range commands {
+		fs.BoolVar(&selected[i], cmd.name, false, cmd.usage)
+	}
+
+	fs.StringVar(&opts.ike, "ike", "", "name of the IKE_SA or connection")
+	fs.UintVar(&opts.ikeID, "ike-id", 0, "unique identifier of the IKE_SA")
+	fs.StringVar(&opts.child, "child", "", "name of the CHILD_SA")
+	fs.UintVar(&opts.childID, "child-id", 0, "unique identifier of the CHILD_SA")
+	fs.StringVar(&opts.subject, "subject", "", "subject distinguished name of the certificates to list")
+	fs.StringVar(&opts.file, "file", "/etc/swanctl/swanctl.conf", "configuration file to load")
+	fs.UintVar(&opts.timeout, "timeout", 0, "timeout in seconds before detaching, 0 to wait for completion")
+	fs.IntVar(&opts.loglevel, "loglevel", 1, "verbosity of the log output")
+	fs.BoolVar(&opts.force, "force", false, "terminate without waiting for the peer's response")
+	fs.BoolVar(&opts.reauth, "reauth", false, "reauthenticate instead of rekeying the IKE_SA")
+	fs.BoolVar(&opts.raw, "raw", false, "dump raw response messages")
+	fs.BoolVar(&opts.pretty, "pretty", false, "dump raw response messages in pretty print")
+	fs.StringVar(&uri, "uri", "", "service URI to connect to (default: the platform default socket)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: goswanctl --<command> [options]\n\ncommands:\n")
+		for _, cmd := range commands {
+			fmt.Fprintf(stderr, "  --%-12s %s\n", cmd.name, cmd.usage)
+		}
+		fmt.Fprintf(stderr, "\noptions:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	var run func(c *cli, ctx context.Context) error
+
+	for i, cmd := range commands {
+		if !selected[i] {
+			continue
+		}
+
+		if run != nil {
+			return errors.New("only one command may be given")
+		}
+		run = cmd.run
+	}
+
+	if run == nil {
+		fs.Usage()
+
+		return errors.New("no command given")
+	}
+
+	sopts, err := sessionOptions(uri, dial)
+	if err != nil {
+		return err
+	}
+
+	s, err := vici.NewSessionContext(ctx, sopts...)
+	if err != nil {
+		return fmt.Errorf("connecting to the daemon failed: %w", err)
+	}
+	defer s.Close()
+
+	return run(&cli{s: s, out: stdout, opts: &opts}, ctx)
+}
+
+// sessionOptions returns the options of the session to the daemon.
+func sessionOptions(uri string, dial func(context.Context, string, string) (net.Conn, error)) ([]vici.SessionOption, error) {
+	if dial != nil {
+		return []vici.SessionOption{vici.WithDialContext(dial)}, nil
+	}
+
+	if uri == "" {
+		return nil, nil
+	}
+
+	scheme, addr, ok := strings.Cut(uri, "://")
+	if !ok {
+		return nil, fmt.Errorf("invalid URI %q", uri)
+	}
+
+	switch scheme {
+	case "unix":
+		return []vici.SessionOption{vici.WithSocketPath(addr)}, nil
+	case "tcp":
+		return []vici.SessionOption{vici.WithAddr("tcp", addr)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported URI scheme %q", scheme)
+	}
+}
+
+// failed returns the error of a failed command, using the errmsg of the
+// response if there is one.
+func failed(cmd string, out *vici.Message, err error) error {
+	if out != nil {
+		if msg, ok := out.Get("errmsg").(string); ok && msg != "" {
+			return fmt.Errorf("%s failed: %s", cmd, msg)
+		}
+	}
+
+	return fmt.Errorf("%s failed: %w", cmd, err)
+}
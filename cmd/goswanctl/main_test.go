@@ -0,0 +1,548 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+// goswanctl runs the command line given by args against srv, and returns its
+// output.
+func goswanctl(ctx context.Context, srv *vicitest.Server, args ...string) (string, error) {
+	var out bytes.Buffer
+
+	err := run(ctx, args, &out, io.Discard, srv.DialContext)
+
+	return out.String(), err
+}
+
+func expectOutput(t *testing.T, out string, want ...string) {
+	t.Helper()
+
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Errorf("Expected output to contain %q, got:\n%s", w, out)
+		}
+	}
+}
+
+func TestRunArguments(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "no command", args: nil, err: "no command given"},
+		{name: "two commands", args: []string{"--stats", "--list-sas"}, err: "only one command may be given"},
+		{name: "argument", args: []string{"--stats", "foo"}, err: `unexpected argument "foo"`},
+		{name: "unknown flag", args: []string{"--foo"}, err: "flag provided but not defined"},
+		{name: "initiate without child", args: []string{"--initiate"}, err: "initiate requires --child or --ike"},
+		{name: "terminate without selection", args: []string{"--terminate"}, err: "terminate requires"},
+		{name: "rekey without selection", args: []string{"--rekey"}, err: "rekey requires"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := goswanctl(context.Background(), srv, tt.args...)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestSessionOptions(t *testing.T) {
+	for _, uri := range []string{"", "unix:///var/run/charon.vici", "tcp://127.0.0.1:4502"} {
+		if _, err := sessionOptions(uri, nil); err != nil {
+			t.Errorf("Unexpected error for %q: %v", uri, err)
+		}
+	}
+
+	for _, uri := range []string{"/var/run/charon.vici", "udp://127.0.0.1:4502"} {
+		if _, err := sessionOptions(uri, nil); err == nil {
+			t.Errorf("Expected error for %q", uri)
+		}
+	}
+}
+
+func TestListSAs(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	var ike any
+
+	srv.Handle("list-sas", func(req *vicitest.Request) (*vici.Message, error) {
+		ike = req.Message.Get("ike")
+
+		sa := vicitest.MustBuild(t, vici.Build().
+			Section("home").
+			Set("uniqueid", "1").
+			Set("version", "2").
+			Set("state", "ESTABLISHED").
+			Set("local-host", "192.168.0.100").
+			Set("local-port", "4500").
+			Set("local-id", "carol@strongswan.org").
+			Set("remote-host", "192.168.0.1").
+			Set("remote-port", "4500").
+			Set("remote-id", "moon.strongswan.org").
+			Set("initiator", "yes").
+			Set("initiator-spi", "2cba6b0ec0cba1c4").
+			Set("responder-spi", "63fcfd2d8a0e4a7c").
+			List("local-vips", "10.3.0.1").
+			Set("encr-alg", "AES_CBC").
+			Set("encr-keysize", "128").
+			Set("integ-alg", "HMAC_SHA2_256_128").
+			Set("prf-alg", "PRF_HMAC_SHA2_256").
+			Set("dh-group", "CURVE_25519").
+			Set("established", "10").
+			Set("rekey-time", "13998").
+			Section("child-sas").
+			Section("home-1").
+			Set("name", "home").
+			Set("uniqueid", "1").
+			Set("reqid", "1").
+			Set("state", "INSTALLED").
+			Set("mode", "TUNNEL").
+			Set("encap", "yes").
+			Set("protocol", "ESP").
+			Set("encr-alg", "AES_GCM_16").
+			Set("encr-keysize", "128").
+			Set("spi-in", "c0c1c5d2").
+			Set("spi-out", "c6ecf9b4").
+			Set("bytes-in", "84").
+			Set("packets-in", "1").
+			Set("bytes-out", "84").
+			Set("packets-out", "1").
+			Set("install-time", "10").
+			Set("rekey-time", "3324").
+			Set("life-time", "3950").
+			List("local-ts", "10.3.0.1/32").
+			List("remote-ts", "10.1.0.0/16").
+			End().
+			End().
+			End())
+
+		return nil, req.Emit("list-sa", sa)
+	})
+
+	out, err := goswanctl(context.Background(), srv, "--list-sas", "--ike", "home")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if ike != "home" {
+		t.Errorf("Expected ike=home in request, got %v", ike)
+	}
+
+	expectOutput(t, out,
+		"home: #1, ESTABLISHED, IKEv2, 2cba6b0ec0cba1c4_i* 63fcfd2d8a0e4a7c_r\n",
+		"  local  'carol@strongswan.org' @ 192.168.0.100[4500] [10.3.0.1]\n",
+		"  remote 'moon.strongswan.org' @ 192.168.0.1[4500]\n",
+		"  AES_CBC-128/HMAC_SHA2_256_128/PRF_HMAC_SHA2_256/CURVE_25519\n",
+		"  established 10s ago, rekeying in 13998s\n",
+		"  home: #1, reqid 1, INSTALLED, TUNNEL-in-UDP, ESP:AES_GCM_16-128\n",
+		"    installed 10s ago, rekeying in 3324s, expires in 3950s\n",
+		"    in  c0c1c5d2,     84 bytes,     1 packets\n",
+		"    local  10.3.0.1/32\n",
+		"    remote 10.1.0.0/16\n",
+	)
+}
+
+func TestListSAsRaw(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("list-sas", func(req *vicitest.Request) (*vici.Message, error) {
+		sa := vicitest.MustBuild(t, vici.Build().
+			Section("home").
+			Set("state", "ESTABLISHED").
+			List("local-vips", "10.3.0.1", "fec3::1").
+			End())
+
+		return nil, req.Emit("list-sa", sa)
+	})
+
+	out, err := goswanctl(context.Background(), srv, "--list-sas", "--raw")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if want := "list-sa event {home {state=ESTABLISHED local-vips=[10.3.0.1 fec3::1]}}\n"; out != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+
+	out, err = goswanctl(context.Background(), srv, "--list-sas", "--pretty")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := "list-sa event {\n" +
+		"  home {\n" +
+		"    state = ESTABLISHED\n" +
+		"    local-vips = [\n" +
+		"      10.3.0.1\n" +
+		"      fec3::1\n" +
+		"    ]\n" +
+		"  }\n" +
+		"}\n"
+	if out != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+}
+
+func TestPrintable(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want string
+	}{
+		{in: "moon.strongswan.org", want: "moon.strongswan.org"},
+		{in: "\x30\x82\x01\x0a", want: "0x3082010a"},
+		{in: "\xff", want: "0xff"},
+	} {
+		if got := printable(tt.in); got != tt.want {
+			t.Errorf("printable(%q): expected %q, got %q", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestListConns(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("list-conns", func(req *vicitest.Request) (*vici.Message, error) {
+		conn := vicitest.MustBuild(t, vici.Build().
+			Section("home").
+			List("local_addrs", "%any").
+			List("remote_addrs", "moon.strongswan.org").
+			Set("version", "IKEv2").
+			Set("reauth_time", "0").
+			Set("rekey_time", "14400").
+			Section("local-1").
+			Set("class", "public key").
+			Set("id", "carol@strongswan.org").
+			End().
+			Section("remote-1").
+			Set("class", "EAP").
+			Set("eap-type", "MSCHAPV2").
+			Set("id", "moon.strongswan.org").
+			End().
+			Section("children").
+			Section("home").
+			Set("mode", "TUNNEL").
+			Set("rekey_time", "3600").
+			Set("dpd_action", "restart").
+			List("local-ts", "dynamic").
+			List("remote-ts", "10.1.0.0/16").
+			End().
+			End().
+			End())
+
+		return nil, req.Emit("list-conn", conn)
+	})
+
+	out, err := goswanctl(context.Background(), srv, "--list-conns")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectOutput(t, out,
+		"home: IKEv2, no reauthentication, rekeying every 14400s\n",
+		"  local:  %any\n",
+		"  remote: moon.strongswan.org\n",
+		"  local public key authentication:\n    id: carol@strongswan.org\n",
+		"  remote EAP (MSCHAPV2) authentication:\n    id: moon.strongswan.org\n",
+		"  home: TUNNEL, rekeying every 3600s, dpd action is restart\n",
+		"    local:  dynamic\n",
+		"    remote: 10.1.0.0/16\n",
+	)
+}
+
+func selfSigned(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(0x2a),
+		Subject: pkix.Name{
+			Country:      []string{"CH"},
+			Organization: []string{"strongSwan Project"},
+			CommonName:   "moon.strongswan.org",
+		},
+		DNSNames:    []string{"moon.strongswan.org"},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    time.Now().Add(24 * time.Hour * 365),
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return der
+}
+
+func TestListCerts(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	der := selfSigned(t)
+
+	srv.Handle("list-certs", func(req *vicitest.Request) (*vici.Message, error) {
+		for _, cert := range []*vici.Message{
+			vicitest.MustBuild(t, vici.Build().Set("type", "X509").Set("flag", "NONE").Set("has_privkey", "yes").Set("data", string(der))),
+			vicitest.MustBuild(t, vici.Build().Set("type", "X509_CRL").Set("flag", "NONE").Set("data", "\x30\x00")),
+		} {
+			if err := req.Emit("list-cert", cert); err != nil {
+				return nil, err
+			}
+		}
+
+		return vici.NewMessage(), nil
+	})
+
+	out, err := goswanctl(context.Background(), srv, "--list-certs")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectOutput(t, out,
+		"\nList of X.509 End Entity Certificates\n\n",
+		`  subject:  "C=CH, O=strongSwan Project, CN=moon.strongswan.org"`+"\n",
+		`  issuer:   "C=CH, O=strongSwan Project, CN=moon.strongswan.org"`+"\n",
+		"ok (expires in 364 days)\n",
+		"  serial:    2a\n",
+		"  altNames:  moon.strongswan.org\n",
+		"  flags:     serverAuth self-signed\n",
+		"  pubkey:    ECDSA 256 bits, has private key\n",
+		"\nList of X.509 CRLs\n\n  2 bytes of X509_CRL data\n",
+	)
+}
+
+func TestInitiate(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	var req *vici.Message
+
+	srv.Handle("initiate", func(r *vicitest.Request) (*vici.Message, error) {
+		req = r.Message
+
+		for _, msg := range []string{"initiating IKE_SA home[1] to 192.168.0.1", "IKE_SA home[1] established"} {
+			log := vicitest.MustBuild(t, vici.Build().Set("group", "IKE").Set("level", "1").Set("msg", msg))
+			if err := r.Emit("control-log", log); err != nil {
+				return nil, err
+			}
+		}
+
+		if r.Message.Get("child") == "unknown" {
+			return nil, errors.New("CHILD_SA config 'unknown' not found")
+		}
+
+		return vici.NewMessage(), nil
+	})
+
+	out, err := goswanctl(context.Background(), srv, "--initiate", "--child", "home", "--timeout", "5", "--loglevel", "2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for key, want := range map[string]string{"child": "home", "timeout": "5000", "init-limits": "yes", "loglevel": "2"} {
+		if got := req.Get(key); got != want {
+			t.Errorf("Expected %s=%s in request, got %v", key, want, got)
+		}
+	}
+
+	want := "[IKE] initiating IKE_SA home[1] to 192.168.0.1\n" +
+		"[IKE] IKE_SA home[1] established\n" +
+		"initiate completed successfully\n"
+	if out != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+
+	_, err = goswanctl(context.Background(), srv, "--initiate", "--child", "unknown")
+	if want := "initiate failed: CHILD_SA config 'unknown' not found"; err == nil || err.Error() != want {
+		t.Errorf("Expected error %q, got %v", want, err)
+	}
+}
+
+func TestTerminateAndRekey(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	reqs := make(map[string]*vici.Message)
+
+	for _, cmd := range []string{"terminate", "rekey"} {
+		srv.Handle(cmd, func(r *vicitest.Request) (*vici.Message, error) {
+			reqs[cmd] = r.Message
+
+			return vici.Build().Set("success", "yes").Set("matches", "1").Message()
+		})
+	}
+
+	out, err := goswanctl(context.Background(), srv, "--terminate", "--ike-id", "3", "--force")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectOutput(t, out, "terminate completed successfully\n")
+
+	if id, force := reqs["terminate"].Get("ike-id"), reqs["terminate"].Get("force"); id != "3" || force != "yes" {
+		t.Errorf("Expected ike-id=3 force=yes in terminate request, got %v %v", id, force)
+	}
+
+	out, err = goswanctl(context.Background(), srv, "--rekey", "--ike", "home", "--reauth", "--raw")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if want := "rekey reply {success=yes matches=1}\n"; out != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+
+	if ike, reauth := reqs["rekey"].Get("ike"), reqs["rekey"].Get("reauth"); ike != "home" || reauth != "yes" {
+		t.Errorf("Expected ike=home reauth=yes in rekey request, got %v %v", ike, reauth)
+	}
+}
+
+func TestStats(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("stats", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().
+			Section("uptime").Set("running", "5 minutes").Set("since", "Oct 18 10:00:00 2026").End().
+			Section("workers").Set("total", "16").Set("idle", "11").
+			Section("active").Set("critical", "4").Set("high", "0").Set("medium", "1").Set("low", "0").End().
+			End().
+			Section("queues").Set("critical", "0").Set("high", "0").Set("medium", "0").Set("low", "0").End().
+			Set("scheduled", "3").
+			Section("ikesas").Set("total", "1").Set("half-open", "0").End().
+			List("plugins", "charon", "aes", "sha2").
+			Message()
+	})
+
+	out, err := goswanctl(context.Background(), srv, "--stats")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := "uptime: 5 minutes, since Oct 18 10:00:00 2026\n" +
+		"worker threads: 16 total, 11 idle, working: 4/0/1/0\n" +
+		"job queues: 0/0/0/0\n" +
+		"jobs scheduled: 3\n" +
+		"IKE_SAs: 1 total, 0 half-open\n" +
+		"loaded plugins: charon aes sha2\n"
+	if out != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+}
+
+func TestLog(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r, w := io.Pipe()
+	done := make(chan error, 1)
+
+	go func() {
+		done <- run(ctx, []string{"--log"}, w, io.Discard, srv.DialContext)
+		w.Close()
+	}()
+
+	// Emit until the subscription is in place, and the first line is printed.
+	line := make(chan string, 1)
+
+	go func() {
+		buf := make([]byte, 256)
+		n, _ := r.Read(buf)
+		line <- string(buf[:n])
+		// nolint
+		_, _ = io.Copy(io.Discard, r)
+	}()
+
+	log := vicitest.MustBuild(t, vici.Build().
+		Set("group", "IKE").
+		Set("level", "1").
+		Set("thread", "7").
+		Set("ikesa-name", "home").
+		Set("ikesa-uniqueid", "1").
+		Set("msg", "sending DPD request"))
+
+	var got string
+
+	for got == "" {
+		if err := srv.Emit("log", log); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		select {
+		case got = <-line:
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if want := "07[IKE] <home|1> sending DPD request\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestFailed(t *testing.T) {
+	err := errors.New("command failed")
+
+	if got := failed("stats", nil, err); !errors.Is(got, err) {
+		t.Errorf("Expected error to wrap %v, got %v", err, got)
+	}
+
+	out := vicitest.MustBuild(t, vici.Build().Set("success", "no").Set("errmsg", "no such thing"))
+	if got, want := failed("stats", out, err).Error(), fmt.Sprintf("stats failed: %s", "no such thing"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
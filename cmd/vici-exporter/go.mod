@@ -0,0 +1,30 @@
+module github.com/strongswan/govici/cmd/vici-exporter
+
+go 1.25.0
+
+// The replace directives build this module against the code in this
+// repository. They are ignored when the module is used as a dependency, which
+// then uses the required releases. The modules of this repository are tagged
+// together, e.g. v0.9.0 and cmd/vici-exporter/v0.9.0.
+replace (
+	github.com/strongswan/govici => ../..
+	github.com/strongswan/govici/vici/exporter => ../../vici/exporter
+)
+
+require (
+	github.com/prometheus/client_golang v1.24.1
+	github.com/strongswan/govici v0.9.0
+	github.com/strongswan/govici/vici/exporter v0.9.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.2 // indirect
+	github.com/prometheus/common v0.70.1 // indirect
+	github.com/prometheus/procfs v0.21.1 // indirect
+	go.yaml.in/yaml/v3 v3.0.5 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+)
@@ -0,0 +1,138 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Command vici-exporter exports the statistics of a charon daemon, and its
+// IKE and CHILD SAs, as Prometheus metrics.
+//
+// Usage:
+//
+//	vici-exporter [-listen addr] [-path path] [-socket path] [-interval duration] [-no-counters]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/exporter"
+)
+
+var (
+	listen     = flag.String("listen", ":9814", "address to serve metrics on")
+	path       = flag.String("path", "/metrics", "path to serve metrics on")
+	socket     = flag.String("socket", "", "path of the charon vici socket (default: the platform default)")
+	interval   = flag.Duration("interval", 30*time.Second, "interval between refreshes of the metrics")
+	noCounters = flag.Bool("no-counters", false, "do not query the counters plugin")
+)
+
+func main() {
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	if err := run(logger); err != nil {
+		logger.Error("exiting", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
+func run(logger *slog.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var opts []vici.SessionOption
+	if *socket != "" {
+		opts = append(opts, vici.WithSocketPath(*socket))
+	}
+	opts = append(opts, vici.WithLogger(logger))
+
+	s, err := vici.NewSessionContext(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	eopts := []exporter.Option{
+		exporter.WithInterval(*interval),
+		exporter.WithLogger(logger),
+	}
+	if *noCounters {
+		eopts = append(eopts, exporter.WithoutCounters())
+	}
+
+	e := exporter.New(s, eopts...)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		e,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle(*path, promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 2)
+
+	go func() {
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+
+	go func() {
+		errs <- e.Run(ctx)
+	}()
+
+	logger.Info("serving metrics", slog.String("addr", *listen), slog.String("path", *path))
+
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// nolint
+	_ = srv.Shutdown(shutdownCtx)
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	return err
+}
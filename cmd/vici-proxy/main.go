@@ -0,0 +1,190 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Command vici-proxy shares the charon vici socket with unprivileged clients.
+// Clients connect to the proxy's own UNIX socket, and may only use read-only
+// commands, unless they run as one of the users given with -admin-uids.
+//
+// Usage:
+//
+//	vici-proxy [-listen path] [-mode mode] [-socket path] [-admin-uids uid,...] [-deny-failure] [-audit-log path]
+//
+// With -audit-log, the mutating commands made by clients are recorded in the
+// given file, see package audit.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/audit"
+	"github.com/strongswan/govici/vici/proxy"
+)
+
+var (
+	listen      = flag.String("listen", "/run/vici-proxy.vici", "path of the socket to accept clients on")
+	mode        = flag.String("mode", "0666", "file mode of the socket given with -listen")
+	socket      = flag.String("socket", "", "path of the charon vici socket (default: the platform default)")
+	adminUIDs   = flag.String("admin-uids", "0", "comma-separated uids of clients allowed to use all commands")
+	denyFailure = flag.Bool("deny-failure", false, "answer denied commands with a failure, instead of as unknown commands")
+	auditLog    = flag.String("audit-log", "", "path of a file to record mutating commands in")
+)
+
+func main() {
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	if err := run(logger); err != nil {
+		logger.Error("exiting", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
+func run(logger *slog.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	admins, err := parseUIDs(*adminUIDs)
+	if err != nil {
+		return err
+	}
+
+	perm, err := strconv.ParseUint(*mode, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid -mode: %w", err)
+	}
+
+	var opts []vici.SessionOption
+	if *socket != "" {
+		opts = append(opts, vici.WithSocketPath(*socket))
+	}
+	opts = append(opts, vici.WithLogger(logger))
+
+	if *auditLog != "" {
+		sink, err := audit.NewFileSink(*auditLog)
+		if err != nil {
+			return err
+		}
+		defer sink.Close()
+
+		handleError := audit.WithErrorHandler(func(e *audit.Entry, err error) {
+			logger.Error("failed to write audit entry",
+				slog.String("command", e.Command),
+				slog.String("identity", e.Identity),
+				slog.Any("error", err),
+			)
+		})
+
+		opts = append(opts,
+			vici.WithUnaryInterceptor(audit.UnaryInterceptor(sink, handleError)),
+			vici.WithStreamInterceptor(audit.StreamInterceptor(sink, handleError)),
+		)
+	}
+
+	s, err := vici.NewSessionContext(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	readOnly := *proxy.ReadOnly
+	unrestricted := *proxy.Unrestricted
+	if *denyFailure {
+		readOnly.Denial = proxy.DenyFailure
+		unrestricted.Denial = proxy.DenyFailure
+	}
+
+	p := proxy.New(s,
+		proxy.WithLogger(logger),
+		proxy.WithPolicy(func(c *proxy.Client) *proxy.Policy {
+			if c.Credentials != nil && slices.Contains(admins, c.Credentials.UID) {
+				return &unrestricted
+			}
+
+			return &readOnly
+		}),
+	)
+	defer p.Close()
+
+	// Remove a stale socket left behind by a previous instance.
+	if err := os.Remove(*listen); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	l, err := net.Listen("unix", *listen)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(*listen, os.FileMode(perm)); err != nil {
+		l.Close()
+
+		return err
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- p.Serve(l)
+	}()
+
+	logger.Info("accepting clients", slog.String("listen", *listen))
+
+	select {
+	case err = <-errs:
+	case <-s.Done():
+		err = s.Err()
+	case <-ctx.Done():
+		err = nil
+	}
+
+	return err
+}
+
+func parseUIDs(s string) ([]uint32, error) {
+	var uids []uint32
+
+	for field := range strings.SplitSeq(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		uid, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uid %q: %w", field, err)
+		}
+
+		uids = append(uids, uint32(uid))
+	}
+
+	return uids, nil
+}
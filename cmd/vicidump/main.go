@@ -0,0 +1,229 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Command vicidump prints the vici packets in a capture, with their time and
+// direction, and the message tree of each packet.
+//
+// The capture is either a capture written by a session using vici.WithCapture,
+// or a pcap capture of TCP connections to a vici endpoint, e.g. the tlsterm
+// upstream or a socat relay. Captures in pcapng format must first be converted,
+// e.g. with editcap -F pcap. If no file is given, or file is -, the capture is
+// read from standard input.
+//
+// Usage:
+//
+//	vicidump [-port port] [-json] [file]
+//
+// Secrets, e.g. the data of load-shared requests, are redacted from the output.
+// With -json, each packet is printed as a JSON object on its own line.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+)
+
+var (
+	port   = flag.Uint("port", 4502, "TCP port of the vici endpoint in pcap captures")
+	asJSON = flag.Bool("json", false, "print each packet as a JSON object on its own line")
+)
+
+func main() {
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	if err := run(os.Stdout); err != nil {
+		logger.Error("exiting", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
+func run(w io.Writer) error {
+	if flag.NArg() > 1 {
+		return errors.New("too many arguments, expected at most one capture file")
+	}
+
+	if *port == 0 || *port > 65535 {
+		return fmt.Errorf("invalid -port %d", *port)
+	}
+
+	f := os.Stdin
+	if name := flag.Arg(0); name != "" && name != "-" {
+		var err error
+
+		f, err = os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+
+	r, err := newReader(bufio.NewReader(f), uint16(*port))
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	for {
+		rec, err := r.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			err = printJSON(bw, rec)
+		} else {
+			err = printText(bw, rec)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// record is a vici packet read from a capture. For pcap captures, it holds the
+// connection it was transmitted over, and it may hold an error instead of a
+// packet if the connection cannot be decoded.
+type record struct {
+	vici.CaptureRecord
+
+	conn string
+	err  error
+}
+
+// reader reads the vici packets of a capture.
+type reader interface {
+	// next returns the next packet, or io.EOF at the end of the capture.
+	next() (*record, error)
+}
+
+// newReader returns a reader for the capture read from r, depending on its
+// format.
+func newReader(r *bufio.Reader, port uint16) (reader, error) {
+	magic, err := r.Peek(8)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	switch {
+	case isPcap(magic):
+		return newPcapReader(r, port)
+
+	case len(magic) >= 4 && string(magic[:4]) == "\x0a\x0d\x0d\x0a":
+		return nil, errors.New("pcapng captures are not supported, convert the capture to pcap first")
+
+	default:
+		cr, err := vici.NewCaptureReader(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return captureReader{cr}, nil
+	}
+}
+
+// captureReader reads a capture written by vici.WithCapture.
+type captureReader struct {
+	cr *vici.CaptureReader
+}
+
+func (r captureReader) next() (*record, error) {
+	rec, err := r.cr.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	return &record{CaptureRecord: *rec}, nil
+}
+
+const timeFormat = "2006-01-02 15:04:05.000000"
+
+func printText(w io.Writer, rec *record) error {
+	var conn string
+	if rec.conn != "" {
+		conn = " (" + rec.conn + ")"
+	}
+
+	ts := rec.Time.Format(timeFormat)
+
+	if rec.err != nil {
+		_, err := fmt.Fprintf(w, "%s error%s: %v\n", ts, conn, rec.err)
+
+		return err
+	}
+
+	p := rec.Packet
+
+	name := ""
+	if p.Name != "" {
+		name = " " + p.Name
+	}
+
+	_, err := fmt.Fprintf(w, "%s %s %s%s%s\n%s", ts, rec.Direction, p.Type, name, conn, p.Message)
+
+	return err
+}
+
+// jsonRecord is the JSON form of a record.
+type jsonRecord struct {
+	Time      time.Time     `json:"time"`
+	Conn      string        `json:"conn,omitempty"`
+	Direction string        `json:"direction"`
+	Type      string        `json:"type,omitempty"`
+	Name      string        `json:"name,omitempty"`
+	Message   *vici.Message `json:"message,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+func printJSON(w io.Writer, rec *record) error {
+	jr := jsonRecord{
+		Time:      rec.Time,
+		Conn:      rec.conn,
+		Direction: rec.Direction.String(),
+	}
+
+	if rec.err != nil {
+		jr.Error = rec.err.Error()
+	} else {
+		jr.Type = rec.Packet.Type.String()
+		jr.Name = rec.Packet.Name
+		jr.Message = rec.Packet.Message
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	return enc.Encode(jr)
+}
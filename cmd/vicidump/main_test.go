@@ -0,0 +1,115 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+func TestNewReaderFormats(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "pcapng", data: "\x0a\x0d\x0d\x0a\x00\x00\x00\x1c"},
+		{name: "unknown", data: "not a capture"},
+		{name: "truncated pcap", data: "\xd4\xc3\xb2\xa1\x02\x00"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newReader(bufio.NewReader(strings.NewReader(tt.data)), 4502); err == nil {
+				t.Fatal("Expected error")
+			}
+		})
+	}
+}
+
+func TestPrintCapture(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("load-shared", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().Set("success", "yes").Message()
+	})
+
+	var capture bytes.Buffer
+
+	s, err := vici.NewSession(vici.WithDialContext(srv.DialContext), vici.WithCapture(&capture))
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	in, err := vici.Build().Set("type", "IKE").Set("data", "very-secret").Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Call(context.Background(), "load-shared", in); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.Close()
+
+	r, err := newReader(bufio.NewReader(bytes.NewReader(capture.Bytes())), 4502)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var text, js bytes.Buffer
+
+	for _, rec := range readAll(t, r) {
+		if err := printText(&text, rec); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := printJSON(&js, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range []string{
+		"sent CMD_REQUEST load-shared\n{\n  type = IKE\n  data = <redacted>\n}\n",
+		"received CMD_RESPONSE\n{\n  success = yes\n}\n",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, text.String())
+		}
+	}
+
+	for _, want := range []string{
+		`"direction":"sent","type":"CMD_REQUEST","name":"load-shared","message":{"type":"IKE","data":"<redacted>"}}`,
+		`"direction":"received","type":"CMD_RESPONSE","message":{"success":"yes"}}`,
+	} {
+		if !strings.Contains(js.String(), want) {
+			t.Errorf("Expected JSON output to contain %s, got:\n%s", want, js.String())
+		}
+	}
+
+	if strings.Contains(text.String()+js.String(), "very-secret") {
+		t.Error("Output contains secret")
+	}
+}
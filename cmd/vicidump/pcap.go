@@ -0,0 +1,450 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// Link types of the pcap captures that can be read.
+const (
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLinuxSLL = 113
+	linkTypeSLL2     = 276
+)
+
+// Limits on data that is buffered for a TCP stream. Beyond these, the stream
+// is given up on, as it is most likely not vici.
+const (
+	maxPacketLength   = 1 << 24
+	maxPendingSegment = 1024
+)
+
+// isPcap reports whether magic is the magic number of a pcap capture.
+func isPcap(magic []byte) bool {
+	if len(magic) < 4 {
+		return false
+	}
+
+	switch binary.LittleEndian.Uint32(magic) {
+	case 0xa1b2c3d4, 0xd4c3b2a1, 0xa1b23c4d, 0x4d3cb2a1:
+		return true
+	default:
+		return false
+	}
+}
+
+// pcapReader reads the vici packets transmitted over the TCP connections to a
+// port in a pcap capture.
+type pcapReader struct {
+	r        *bufio.Reader
+	order    binary.ByteOrder
+	nano     bool
+	linkType uint32
+	port     uint16
+
+	streams map[string]*stream
+
+	// Records completed by the last pcap record, but not returned yet.
+	pending []*record
+}
+
+// newPcapReader returns a pcapReader reading the pcap capture from r, and
+// the vici packets transmitted to and from port.
+func newPcapReader(r *bufio.Reader, port uint16) (*pcapReader, error) {
+	hdr := make([]byte, 24)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, fmt.Errorf("failed to read pcap header: %w", err)
+	}
+
+	pr := &pcapReader{
+		r:       r,
+		port:    port,
+		streams: make(map[string]*stream),
+	}
+
+	switch binary.LittleEndian.Uint32(hdr) {
+	case 0xa1b2c3d4:
+		pr.order = binary.LittleEndian
+	case 0xa1b23c4d:
+		pr.order = binary.LittleEndian
+		pr.nano = true
+	case 0xd4c3b2a1:
+		pr.order = binary.BigEndian
+	case 0x4d3cb2a1:
+		pr.order = binary.BigEndian
+		pr.nano = true
+	default:
+		return nil, errors.New("not a pcap capture")
+	}
+
+	pr.linkType = pr.order.Uint32(hdr[20:]) & 0xffff
+
+	switch pr.linkType {
+	case linkTypeNull, linkTypeEthernet, linkTypeRaw, linkTypeLinuxSLL, linkTypeSLL2:
+	default:
+		return nil, fmt.Errorf("unsupported pcap link type %d", pr.linkType)
+	}
+
+	return pr, nil
+}
+
+// next returns the next vici packet, or io.EOF at the end of the capture.
+func (pr *pcapReader) next() (*record, error) {
+	for len(pr.pending) == 0 {
+		if err := pr.readRecord(); err != nil {
+			return nil, err
+		}
+	}
+
+	rec := pr.pending[0]
+	pr.pending = pr.pending[1:]
+
+	return rec, nil
+}
+
+// readRecord reads a pcap record, and adds the vici packets it completes to
+// pending.
+func (pr *pcapReader) readRecord() error {
+	hdr := make([]byte, 16)
+	if _, err := io.ReadFull(pr.r, hdr); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("truncated pcap record header: %w", err)
+		}
+
+		return err
+	}
+
+	sec := int64(pr.order.Uint32(hdr[0:]))
+	frac := int64(pr.order.Uint32(hdr[4:]))
+	if !pr.nano {
+		frac *= int64(time.Microsecond)
+	}
+	ts := time.Unix(sec, frac)
+
+	data := make([]byte, pr.order.Uint32(hdr[8:]))
+	if _, err := io.ReadFull(pr.r, data); err != nil {
+		return fmt.Errorf("truncated pcap record: %w", err)
+	}
+
+	seg, ok := parseTCP(pr.linkType, data)
+	if !ok {
+		return nil
+	}
+
+	var dir vici.CaptureDirection
+
+	switch pr.port {
+	case seg.dst.Port():
+		dir = vici.CaptureSent
+	case seg.src.Port():
+		dir = vici.CaptureReceived
+	default:
+		return nil
+	}
+
+	key := seg.src.String() + " > " + seg.dst.String()
+
+	st, ok := pr.streams[key]
+	if !ok {
+		conn := key
+		if dir == vici.CaptureReceived {
+			conn = seg.dst.String() + " > " + seg.src.String()
+		}
+
+		st = &stream{conn: conn, dir: dir}
+		pr.streams[key] = st
+	}
+
+	pr.pending = append(pr.pending, st.add(ts, seg)...)
+
+	return nil
+}
+
+// segment is a TCP segment.
+type segment struct {
+	src, dst netip.AddrPort
+	seq      uint32
+	syn      bool
+	data     []byte
+}
+
+// parseTCP returns the TCP segment in a link layer frame, if it holds one.
+// IP fragments, and IPv6 extension headers, are not supported.
+func parseTCP(linkType uint32, frame []byte) (*segment, bool) {
+	var (
+		ip    []byte
+		proto uint16
+	)
+
+	switch linkType {
+	case linkTypeNull, linkTypeRaw:
+		if linkType == linkTypeNull {
+			if len(frame) < 4 {
+				return nil, false
+			}
+			frame = frame[4:]
+		}
+
+		if len(frame) < 1 {
+			return nil, false
+		}
+
+		switch frame[0] >> 4 {
+		case 4:
+			proto = 0x0800
+		case 6:
+			proto = 0x86dd
+		}
+		ip = frame
+
+	case linkTypeEthernet:
+		if len(frame) < 14 {
+			return nil, false
+		}
+		proto = binary.BigEndian.Uint16(frame[12:])
+		ip = frame[14:]
+
+		// Skip VLAN tags.
+		for (proto == 0x8100 || proto == 0x88a8) && len(ip) >= 4 {
+			proto = binary.BigEndian.Uint16(ip[2:])
+			ip = ip[4:]
+		}
+
+	case linkTypeLinuxSLL:
+		if len(frame) < 16 {
+			return nil, false
+		}
+		proto = binary.BigEndian.Uint16(frame[14:])
+		ip = frame[16:]
+
+	case linkTypeSLL2:
+		if len(frame) < 20 {
+			return nil, false
+		}
+		proto = binary.BigEndian.Uint16(frame[0:])
+		ip = frame[20:]
+	}
+
+	var (
+		src, dst netip.Addr
+		tcp      []byte
+	)
+
+	switch proto {
+	case 0x0800:
+		if len(ip) < 20 || ip[0]>>4 != 4 || ip[9] != 6 {
+			return nil, false
+		}
+
+		// Skip fragments.
+		if binary.BigEndian.Uint16(ip[6:])&0x3fff != 0 {
+			return nil, false
+		}
+
+		ihl := int(ip[0]&0x0f) * 4
+		total := int(binary.BigEndian.Uint16(ip[2:]))
+		if ihl < 20 || total < ihl || total > len(ip) {
+			return nil, false
+		}
+
+		src = netip.AddrFrom4([4]byte(ip[12:16]))
+		dst = netip.AddrFrom4([4]byte(ip[16:20]))
+		tcp = ip[ihl:total]
+
+	case 0x86dd:
+		if len(ip) < 40 || ip[0]>>4 != 6 || ip[6] != 6 {
+			return nil, false
+		}
+
+		total := 40 + int(binary.BigEndian.Uint16(ip[4:]))
+		if total > len(ip) {
+			return nil, false
+		}
+
+		src = netip.AddrFrom16([16]byte(ip[8:24]))
+		dst = netip.AddrFrom16([16]byte(ip[24:40]))
+		tcp = ip[40:total]
+
+	default:
+		return nil, false
+	}
+
+	if len(tcp) < 20 {
+		return nil, false
+	}
+
+	off := int(tcp[12]>>4) * 4
+	if off < 20 || off > len(tcp) {
+		return nil, false
+	}
+
+	return &segment{
+		src:  netip.AddrPortFrom(src, binary.BigEndian.Uint16(tcp[0:])),
+		dst:  netip.AddrPortFrom(dst, binary.BigEndian.Uint16(tcp[2:])),
+		seq:  binary.BigEndian.Uint32(tcp[4:]),
+		syn:  tcp[13]&0x02 != 0,
+		data: tcp[off:],
+	}, true
+}
+
+// stream reassembles one direction of a TCP connection, and splits it into
+// vici packets.
+type stream struct {
+	// The connection, as "client > daemon", and the direction of the
+	// stream.
+	conn string
+	dir  vici.CaptureDirection
+
+	started bool
+	failed  bool
+
+	// The next expected sequence number, the data received up to it which
+	// does not form a whole packet yet, and segments received out of order.
+	next    uint32
+	buf     bytes.Buffer
+	pending map[uint32][]byte
+}
+
+// add adds a segment received at time ts to the stream, and returns the vici
+// packets it completes.
+func (st *stream) add(ts time.Time, seg *segment) []*record {
+	if seg.syn {
+		// A new connection. The SYN takes up one sequence number.
+		st.started = true
+		st.failed = false
+		st.next = seg.seq + 1
+		st.buf.Reset()
+		st.pending = nil
+
+		return nil
+	}
+
+	if st.failed || len(seg.data) == 0 {
+		return nil
+	}
+
+	if !st.started {
+		// The capture started after the connection was established.
+		// Assume that the first segment starts a packet.
+		st.started = true
+		st.next = seg.seq
+	}
+
+	if int32(seg.seq-st.next) > 0 {
+		if st.pending == nil {
+			st.pending = make(map[uint32][]byte)
+		}
+
+		if len(st.pending) >= maxPendingSegment {
+			return st.fail(ts, errors.New("too many segments missing"))
+		}
+		st.pending[seg.seq] = seg.data
+
+		return nil
+	}
+
+	st.append(seg.seq, seg.data)
+
+	// Add the segments received out of order, that follow now.
+	for len(st.pending) > 0 {
+		added := false
+
+		for seq, data := range st.pending {
+			if int32(seq-st.next) <= 0 {
+				delete(st.pending, seq)
+				st.append(seq, data)
+				added = true
+			}
+		}
+
+		if !added {
+			break
+		}
+	}
+
+	return st.packets(ts)
+}
+
+// append appends the data of a segment starting at seq, which is not after
+// the next expected sequence number, skipping data that was already received.
+func (st *stream) append(seq uint32, data []byte) {
+	skip := int(st.next - seq)
+	if skip >= len(data) {
+		return
+	}
+
+	st.buf.Write(data[skip:])
+	st.next += uint32(len(data) - skip)
+}
+
+// packets returns the whole packets in buf.
+func (st *stream) packets(ts time.Time) []*record {
+	var records []*record
+
+	for st.buf.Len() >= 4 {
+		n := binary.BigEndian.Uint32(st.buf.Bytes())
+		if n > maxPacketLength {
+			return append(records, st.fail(ts, fmt.Errorf("implausible packet length %d", n))...)
+		}
+
+		if st.buf.Len() < 4+int(n) {
+			break
+		}
+
+		p, err := vici.ReadPacket(bytes.NewReader(st.buf.Next(4 + int(n))))
+		if err != nil {
+			return append(records, st.fail(ts, err)...)
+		}
+
+		records = append(records, &record{
+			CaptureRecord: vici.CaptureRecord{Time: ts, Direction: st.dir, Packet: p},
+			conn:          st.conn,
+		})
+	}
+
+	return records
+}
+
+// fail gives up on the stream, until the connection is established again, and
+// returns a record of the error.
+func (st *stream) fail(ts time.Time, err error) []*record {
+	st.failed = true
+	st.buf.Reset()
+	st.pending = nil
+
+	return []*record{{
+		CaptureRecord: vici.CaptureRecord{Time: ts, Direction: st.dir},
+		conn:          st.conn,
+		err:           fmt.Errorf("failed to decode %s stream: %w", st.dir, err),
+	}}
+}
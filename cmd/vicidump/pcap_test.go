@@ -0,0 +1,245 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// pcapWriter writes pcap captures for tests.
+type pcapWriter struct {
+	buf      bytes.Buffer
+	linkType uint32
+	ts       time.Time
+}
+
+func newPcapWriter(linkType uint32) *pcapWriter {
+	pw := &pcapWriter{linkType: linkType, ts: time.Unix(1700000000, 0)}
+
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(hdr[4:], 2)
+	binary.LittleEndian.PutUint16(hdr[6:], 4)
+	binary.LittleEndian.PutUint32(hdr[16:], 65535)
+	binary.LittleEndian.PutUint32(hdr[20:], linkType)
+	pw.buf.Write(hdr)
+
+	return pw
+}
+
+// segment writes a TCP segment, one millisecond after the previous one.
+func (pw *pcapWriter) segment(src, dst netip.AddrPort, seq uint32, syn bool, data []byte) {
+	tcp := make([]byte, 20, 20+len(data))
+	binary.BigEndian.PutUint16(tcp[0:], src.Port())
+	binary.BigEndian.PutUint16(tcp[2:], dst.Port())
+	binary.BigEndian.PutUint32(tcp[4:], seq)
+	tcp[12] = 5 << 4
+	tcp[13] = 0x10
+	if syn {
+		tcp[13] |= 0x02
+	}
+	tcp = append(tcp, data...)
+
+	var frame []byte
+
+	if src.Addr().Is4() {
+		ip := make([]byte, 20, 20+len(tcp))
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:], uint16(20+len(tcp)))
+		ip[8] = 64
+		ip[9] = 6
+		copy(ip[12:], src.Addr().AsSlice())
+		copy(ip[16:], dst.Addr().AsSlice())
+		ip = append(ip, tcp...)
+
+		if pw.linkType == linkTypeEthernet {
+			frame = make([]byte, 14, 14+len(ip))
+			binary.BigEndian.PutUint16(frame[12:], 0x0800)
+		}
+		frame = append(frame, ip...)
+	} else {
+		ip := make([]byte, 40, 40+len(tcp))
+		ip[0] = 0x60
+		binary.BigEndian.PutUint16(ip[4:], uint16(len(tcp)))
+		ip[6] = 6
+		ip[7] = 64
+		copy(ip[8:], src.Addr().AsSlice())
+		copy(ip[24:], dst.Addr().AsSlice())
+		frame = append(ip, tcp...)
+	}
+
+	pw.ts = pw.ts.Add(time.Millisecond)
+
+	rec := make([]byte, 16)
+	binary.LittleEndian.PutUint32(rec[0:], uint32(pw.ts.Unix()))
+	binary.LittleEndian.PutUint32(rec[4:], uint32(pw.ts.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(rec[8:], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(rec[12:], uint32(len(frame)))
+	pw.buf.Write(rec)
+	pw.buf.Write(frame)
+}
+
+func encodePacket(t *testing.T, p *vici.Packet) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := vici.WritePacket(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func readAll(t *testing.T, r reader) []*record {
+	t.Helper()
+
+	var records []*record
+	for {
+		rec, err := r.next()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		records = append(records, rec)
+	}
+}
+
+func TestPcapReader(t *testing.T) {
+	for _, tt := range []struct {
+		name             string
+		linkType         uint32
+		client, daemon   netip.AddrPort
+		expectedRequests int
+	}{
+		{
+			name:     "ipv4 ethernet",
+			linkType: linkTypeEthernet,
+			client:   netip.MustParseAddrPort("192.0.2.1:50000"),
+			daemon:   netip.MustParseAddrPort("192.0.2.2:4502"),
+		},
+		{
+			name:     "ipv6 raw",
+			linkType: linkTypeRaw,
+			client:   netip.MustParseAddrPort("[2001:db8::1]:50000"),
+			daemon:   netip.MustParseAddrPort("[2001:db8::2]:4502"),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			in, err := vici.Build().Set("ike", "home").Message()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := encodePacket(t, &vici.Packet{Type: vici.PacketCmdRequest, Name: "terminate", Message: in})
+			resp := encodePacket(t, &vici.Packet{Type: vici.PacketCmdResponse})
+			ev := encodePacket(t, &vici.Packet{Type: vici.PacketEvent, Name: "control-log", Message: in})
+
+			pw := newPcapWriter(tt.linkType)
+
+			// Handshake, then the request split across the length
+			// header, with a retransmission of the first part.
+			pw.segment(tt.client, tt.daemon, 999, true, nil)
+			pw.segment(tt.daemon, tt.client, 4999, true, nil)
+			pw.segment(tt.client, tt.daemon, 1000, false, req[:2])
+			pw.segment(tt.client, tt.daemon, 1000, false, req[:2])
+			pw.segment(tt.client, tt.daemon, 1002, false, req[2:])
+
+			// The response is received out of order, after the event.
+			pw.segment(tt.daemon, tt.client, 5000+uint32(len(ev)), false, resp)
+			pw.segment(tt.daemon, tt.client, 5000, false, ev)
+
+			// Other traffic is ignored.
+			pw.segment(netip.MustParseAddrPort("192.0.2.1:53"), netip.MustParseAddrPort("192.0.2.9:53"), 1, false, []byte("dns"))
+
+			r, err := newReader(bufio.NewReader(&pw.buf), 4502)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			records := readAll(t, r)
+			if len(records) != 3 {
+				t.Fatalf("Expected 3 records, got %d", len(records))
+			}
+
+			conn := tt.client.String() + " > " + tt.daemon.String()
+
+			for i, e := range []struct {
+				dir   vici.CaptureDirection
+				ptype vici.PacketType
+				name  string
+			}{
+				{vici.CaptureSent, vici.PacketCmdRequest, "terminate"},
+				{vici.CaptureReceived, vici.PacketEvent, "control-log"},
+				{vici.CaptureReceived, vici.PacketCmdResponse, ""},
+			} {
+				rec := records[i]
+
+				if rec.err != nil {
+					t.Fatalf("Unexpected error in record %d: %v", i, rec.err)
+				}
+
+				if rec.Direction != e.dir || rec.Packet.Type != e.ptype || rec.Packet.Name != e.name || rec.conn != conn {
+					t.Errorf("Expected record %d to be %v %v %q on %s, got %v %v %q on %s",
+						i, e.dir, e.ptype, e.name, conn, rec.Direction, rec.Packet.Type, rec.Packet.Name, rec.conn)
+				}
+			}
+
+			if got := records[0].Packet.Message.Get("ike"); got != "home" {
+				t.Errorf("Expected request to hold ike=home, got %v", got)
+			}
+
+			// The request is complete with the third segment of the client.
+			if want := time.Unix(1700000000, 0).Add(5 * time.Millisecond); !records[0].Time.Equal(want) {
+				t.Errorf("Expected request time %v, got %v", want, records[0].Time)
+			}
+		})
+	}
+}
+
+func TestPcapReaderGarbage(t *testing.T) {
+	client := netip.MustParseAddrPort("192.0.2.1:50000")
+	daemon := netip.MustParseAddrPort("192.0.2.2:4502")
+
+	pw := newPcapWriter(linkTypeEthernet)
+	pw.segment(client, daemon, 1, false, []byte("GET / HTTP/1.1\r\n\r\n"))
+
+	r, err := newReader(bufio.NewReader(&pw.buf), 4502)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	records := readAll(t, r)
+	if len(records) != 1 || records[0].err == nil {
+		t.Fatalf("Expected a single error record, got %v", records)
+	}
+}
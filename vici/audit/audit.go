@@ -0,0 +1,340 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package audit records the configuration changes made through vici sessions.
+//
+// The interceptors returned by UnaryInterceptor and StreamInterceptor write an
+// Entry to a Sink for each request for a mutating command, e.g. load-conn or
+// terminate, with the time, the caller's identity, the command, its redacted
+// arguments, and the outcome. They are installed with the vici.WithUnaryInterceptor
+// and vici.WithStreamInterceptor session options:
+//
+//	sink, err := audit.NewFileSink("/var/log/vici-audit.log")
+//	if err != nil {
+//		return err
+//	}
+//	defer sink.Close()
+//
+//	s, err := vici.NewSession(
+//		vici.WithUnaryInterceptor(audit.UnaryInterceptor(sink)),
+//		vici.WithStreamInterceptor(audit.StreamInterceptor(sink)),
+//	)
+//
+// The caller's identity is taken from the request context, see WithIdentity.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// Outcome is the outcome of an audited command request.
+type Outcome string
+
+const (
+	// OutcomeSuccess means the daemon reported success.
+	OutcomeSuccess Outcome = "success"
+
+	// OutcomeFailure means the daemon reported failure, see Entry.Errmsg.
+	OutcomeFailure Outcome = "failure"
+
+	// OutcomeError means the request failed without a response from the
+	// daemon, e.g. because the connection ended, see Entry.Error.
+	OutcomeError Outcome = "error"
+)
+
+// Entry is the audit record of a command request.
+type Entry struct {
+	// Time is when the request was made.
+	Time time.Time
+
+	// Identity identifies the caller, see WithIdentity.
+	Identity string
+
+	// Command is the requested command.
+	Command string
+
+	// Request holds the redacted command arguments.
+	Request *vici.Message
+
+	// Outcome is the outcome of the request.
+	Outcome Outcome
+
+	// Errmsg is the errmsg given by the daemon for a failed command.
+	Errmsg string
+
+	// Error describes why a request failed with OutcomeError.
+	Error string
+}
+
+// MutatingCommands are the commands audited by default, i.e. the commands that
+// change the daemon's configuration or state.
+var MutatingCommands = []string{
+	"initiate",
+	"terminate",
+	"rekey",
+	"redirect",
+	"install",
+	"uninstall",
+	"reload-settings",
+	"load-conn",
+	"unload-conn",
+	"load-cert",
+	"load-key",
+	"unload-key",
+	"load-token",
+	"load-shared",
+	"unload-shared",
+	"load-authority",
+	"unload-authority",
+	"load-pool",
+	"unload-pool",
+	"flush-certs",
+	"clear-creds",
+	"reset-counters",
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the identity of the caller, which
+// is recorded in the Entry of requests made with the returned context.
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity set with WithIdentity, or an empty
+// string.
+func IdentityFromContext(ctx context.Context) string {
+	identity, _ := ctx.Value(identityKey{}).(string)
+
+	return identity
+}
+
+// Option is used to specify options to UnaryInterceptor and StreamInterceptor.
+type Option interface {
+	apply(*config)
+}
+
+type funcOption struct {
+	f func(*config)
+}
+
+func (fo *funcOption) apply(c *config) {
+	fo.f(c)
+}
+
+// WithCommands specifies the commands to audit. If this option is not specified,
+// MutatingCommands are audited.
+func WithCommands(cmds ...string) Option {
+	return &funcOption{func(c *config) {
+		c.commands = make(map[string]struct{}, len(cmds))
+
+		for _, cmd := range cmds {
+			c.commands[cmd] = struct{}{}
+		}
+	}}
+}
+
+// WithRedaction specifies the func used to redact secrets from the command
+// arguments before they are recorded. If this option is not specified, the
+// values of keys which commonly hold secrets are replaced, see Redact. When
+// entries are encoded as JSON, vici.DefaultRedactor is applied as well.
+func WithRedaction(redact func(*vici.Message) *vici.Message) Option {
+	return &funcOption{func(c *config) {
+		c.redact = redact
+	}}
+}
+
+// WithErrorHandler specifies the func called when an entry cannot be written
+// to the sink. If this option is not specified, such errors are ignored. The
+// audited request itself is not affected.
+func WithErrorHandler(handler func(*Entry, error)) Option {
+	return &funcOption{func(c *config) {
+		c.handleError = handler
+	}}
+}
+
+type config struct {
+	sink        Sink
+	commands    map[string]struct{}
+	redact      func(*vici.Message) *vici.Message
+	handleError func(*Entry, error)
+	now         func() time.Time
+}
+
+func newConfig(sink Sink, opts []Option) *config {
+	c := &config{
+		sink:        sink,
+		redact:      Redact,
+		handleError: func(*Entry, error) {},
+		now:         time.Now,
+	}
+
+	WithCommands(MutatingCommands...).apply(c)
+
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+
+	return c
+}
+
+func (c *config) audited(cmd string) bool {
+	_, ok := c.commands[cmd]
+
+	return ok
+}
+
+// newEntry returns the entry for a request, before it is made.
+func (c *config) newEntry(ctx context.Context, cmd string, in *vici.Message) *Entry {
+	e := &Entry{
+		Time:     c.now(),
+		Identity: IdentityFromContext(ctx),
+		Command:  cmd,
+	}
+
+	if in != nil {
+		e.Request = c.redact(in)
+	}
+
+	return e
+}
+
+// record completes the entry with the outcome of the request, and writes it.
+func (c *config) record(e *Entry, out *vici.Message, err error) {
+	switch {
+	case err == nil:
+		e.Outcome = OutcomeSuccess
+
+	case out != nil:
+		e.Outcome = OutcomeFailure
+
+		if errmsg, ok := out.Get("errmsg").(string); ok {
+			e.Errmsg = errmsg
+		} else {
+			e.Errmsg = err.Error()
+		}
+
+	default:
+		e.Outcome = OutcomeError
+		e.Error = err.Error()
+	}
+
+	if err := c.sink.Write(e); err != nil {
+		c.handleError(e, err)
+	}
+}
+
+// UnaryInterceptor returns an interceptor that writes an Entry to sink for each
+// audited command request made with vici.Session.Call.
+func UnaryInterceptor(sink Sink, opts ...Option) vici.UnaryInterceptor {
+	c := newConfig(sink, opts)
+
+	return func(ctx context.Context, cmd string, in *vici.Message, invoker vici.UnaryInvoker) (*vici.Message, error) {
+		if !c.audited(cmd) {
+			return invoker(ctx, cmd, in)
+		}
+
+		e := c.newEntry(ctx, cmd, in)
+
+		out, err := invoker(ctx, cmd, in)
+
+		c.record(e, out, err)
+
+		return out, err
+	}
+}
+
+// StreamInterceptor returns an interceptor that writes an Entry to sink for each
+// audited command request made with vici.Session.CallStreaming or
+// vici.Session.CallStreamingFunc, e.g. initiate.
+// The entry is written once the iteration is complete. If the caller stops
+// iterating before the command is complete, the outcome is recorded as success,
+// unless an error was seen.
+func StreamInterceptor(sink Sink, opts ...Option) vici.StreamInterceptor {
+	c := newConfig(sink, opts)
+
+	return func(ctx context.Context, cmd string, event string, in *vici.Message, invoker vici.StreamInvoker) iter.Seq2[*vici.Message, error] {
+		if !c.audited(cmd) {
+			return invoker(ctx, cmd, event, in)
+		}
+
+		return func(yield func(*vici.Message, error) bool) {
+			e := c.newEntry(ctx, cmd, in)
+
+			var (
+				last *vici.Message
+				serr error
+			)
+
+			defer func() {
+				c.record(e, last, serr)
+			}()
+
+			for m, err := range invoker(ctx, cmd, event, in) {
+				if err != nil {
+					// The message, if any, is the failed command
+					// response.
+					last, serr = m, err
+				}
+
+				if !yield(m, err) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Redact returns a copy of m in which the values that hold secrets, e.g. the
+// data of load-key and load-shared requests, are replaced. It is equivalent to
+// vici.DefaultRedactor.Redact.
+func Redact(m *vici.Message) *vici.Message {
+	return vici.DefaultRedactor.Redact(m)
+}
+
+// jsonEntry is the JSON form of an Entry.
+type jsonEntry struct {
+	Time     time.Time     `json:"time"`
+	Identity string        `json:"identity,omitempty"`
+	Command  string        `json:"command"`
+	Request  *vici.Message `json:"request,omitempty"`
+	Outcome  Outcome       `json:"outcome"`
+	Errmsg   string        `json:"errmsg,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. The entry is encoded as an object,
+// with the request arguments encoded by vici.Message.MarshalJSON.
+func (e *Entry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonEntry{
+		Time:     e.Time,
+		Identity: e.Identity,
+		Command:  e.Command,
+		Request:  e.Request,
+		Outcome:  e.Outcome,
+		Errmsg:   e.Errmsg,
+		Error:    e.Error,
+	})
+}
@@ -0,0 +1,264 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package audit_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/audit"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+// entries is a Sink collecting the entries written to it.
+type entries struct {
+	mu   sync.Mutex
+	list []*audit.Entry
+}
+
+func (es *entries) Write(e *audit.Entry) error {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	es.list = append(es.list, e)
+
+	return nil
+}
+
+func (es *entries) get() []*audit.Entry {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	return slices.Clone(es.list)
+}
+
+func newTestSession(t *testing.T, sink audit.Sink, opts ...audit.Option) *vici.Session {
+	t.Helper()
+
+	srv := vicitest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	srv.Handle("version", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().Set("daemon", "charon").Message()
+	})
+	srv.Handle("load-shared", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().Set("success", "yes").Message()
+	})
+	srv.Handle("load-conn", func(_ *vicitest.Request) (*vici.Message, error) {
+		return nil, errors.New("invalid proposal")
+	})
+	srv.Handle("initiate", func(req *vicitest.Request) (*vici.Message, error) {
+		m, err := vici.Build().Set("msg", "initiating IKE_SA gw[1]").Message()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := req.Emit("control-log", m); err != nil {
+			return nil, err
+		}
+
+		return vici.Build().Set("success", "yes").Message()
+	})
+
+	s, err := vici.NewSession(
+		vici.WithDialContext(srv.DialContext),
+		vici.WithUnaryInterceptor(audit.UnaryInterceptor(sink, opts...)),
+		vici.WithStreamInterceptor(audit.StreamInterceptor(sink, opts...)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	sink := &entries{}
+	s := newTestSession(t, sink)
+
+	ctx := audit.WithIdentity(context.Background(), "uid=1000")
+
+	// Not a mutating command, so it is not audited.
+	if _, err := s.Call(ctx, "version", nil); err != nil {
+		t.Fatalf("Unexpected error calling version: %v", err)
+	}
+
+	in, err := vici.Build().
+		List("owners", "gw.example.com").
+		Set("type", "IKE").
+		Set("data", "s3cr3t").
+		Message()
+	if err != nil {
+		t.Fatalf("Unexpected error building message: %v", err)
+	}
+
+	if _, err := s.Call(ctx, "load-shared", in); err != nil {
+		t.Fatalf("Unexpected error calling load-shared: %v", err)
+	}
+
+	if _, err := s.Call(ctx, "load-conn", nil); err == nil {
+		t.Fatal("Expected error calling load-conn")
+	}
+
+	if _, err := s.Call(ctx, "unload-conn", nil); err == nil {
+		t.Fatal("Expected error calling unknown command")
+	}
+
+	got := sink.get()
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(got))
+	}
+
+	shared := got[0]
+	if shared.Command != "load-shared" || shared.Identity != "uid=1000" || shared.Outcome != audit.OutcomeSuccess {
+		t.Fatalf("Unexpected entry for load-shared: %+v", *shared)
+	}
+
+	if shared.Time.IsZero() {
+		t.Fatal("Expected entry time to be set")
+	}
+
+	if v := shared.Request.Get("data"); v != "<redacted>" {
+		t.Fatalf("Expected data to be redacted, got %v", v)
+	}
+
+	if v := shared.Request.Get("type"); v != "IKE" {
+		t.Fatalf("Expected type to be recorded, got %v", v)
+	}
+
+	// The request itself must not be redacted.
+	if v := in.Get("data"); v != "s3cr3t" {
+		t.Fatalf("Expected request to be unchanged, got data=%v", v)
+	}
+
+	conn := got[1]
+	if conn.Outcome != audit.OutcomeFailure || conn.Errmsg != "invalid proposal" {
+		t.Fatalf("Unexpected entry for load-conn: %+v", *conn)
+	}
+
+	unknown := got[2]
+	if unknown.Outcome != audit.OutcomeError || unknown.Error == "" {
+		t.Fatalf("Unexpected entry for unknown command: %+v", *unknown)
+	}
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	sink := &entries{}
+	s := newTestSession(t, sink)
+
+	in, err := vici.Build().Set("child", "net").Message()
+	if err != nil {
+		t.Fatalf("Unexpected error building message: %v", err)
+	}
+
+	for _, err := range s.CallStreaming(context.Background(), "initiate", "control-log", in) {
+		if err != nil {
+			t.Fatalf("Unexpected error streaming initiate: %v", err)
+		}
+	}
+
+	got := sink.get()
+	if len(got) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(got))
+	}
+
+	if e := got[0]; e.Command != "initiate" || e.Outcome != audit.OutcomeSuccess || e.Request.Get("child") != "net" {
+		t.Fatalf("Unexpected entry for initiate: %+v", *e)
+	}
+}
+
+func TestWithCommands(t *testing.T) {
+	sink := &entries{}
+	s := newTestSession(t, sink, audit.WithCommands("version"))
+
+	if _, err := s.Call(context.Background(), "version", nil); err != nil {
+		t.Fatalf("Unexpected error calling version: %v", err)
+	}
+	if _, err := s.Call(context.Background(), "load-shared", nil); err != nil {
+		t.Fatalf("Unexpected error calling load-shared: %v", err)
+	}
+
+	got := sink.get()
+	if len(got) != 1 || got[0].Command != "version" {
+		t.Fatalf("Expected only version to be audited, got %d entries", len(got))
+	}
+}
+
+func TestWithErrorHandler(t *testing.T) {
+	failed := errors.New("disk full")
+
+	var handled error
+	sink := audit.SinkFunc(func(*audit.Entry) error {
+		return failed
+	})
+
+	s := newTestSession(t, sink, audit.WithErrorHandler(func(_ *audit.Entry, err error) {
+		handled = err
+	}))
+
+	// The request is not affected by the failing sink.
+	if _, err := s.Call(context.Background(), "load-shared", nil); err != nil {
+		t.Fatalf("Unexpected error calling load-shared: %v", err)
+	}
+
+	if !errors.Is(handled, failed) {
+		t.Fatalf("Expected sink error to be handled, got %v", handled)
+	}
+}
+
+func TestEntryMarshalJSON(t *testing.T) {
+	req, err := vici.Build().
+		Section("gw").
+		List("remote_addrs", "192.0.2.1").
+		Set("version", 2).
+		End().
+		Message()
+	if err != nil {
+		t.Fatalf("Unexpected error building message: %v", err)
+	}
+
+	e := &audit.Entry{
+		Time:     time.Date(2026, time.March, 2, 10, 20, 30, 0, time.UTC),
+		Identity: "uid=0",
+		Command:  "load-conn",
+		Request:  req,
+		Outcome:  audit.OutcomeFailure,
+		Errmsg:   "invalid proposal",
+	}
+
+	b, err := e.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Unexpected error marshaling entry: %v", err)
+	}
+
+	expected := `{"time":"2026-03-02T10:20:30Z","identity":"uid=0","command":"load-conn",` +
+		`"request":{"gw":{"remote_addrs":["192.0.2.1"],"version":"2"}},"outcome":"failure","errmsg":"invalid proposal"}`
+
+	if string(b) != expected {
+		t.Fatalf("Unexpected JSON:\n got: %s\nwant: %s", b, expected)
+	}
+}
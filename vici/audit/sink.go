@@ -0,0 +1,282 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Sink receives audit entries. Write may be called by concurrent requests, and
+// must be safe for concurrent use.
+type Sink interface {
+	Write(e *Entry) error
+}
+
+// SinkFunc is an adapter to use an ordinary func as a Sink.
+type SinkFunc func(e *Entry) error
+
+// Write calls f(e).
+func (f SinkFunc) Write(e *Entry) error {
+	return f(e)
+}
+
+// JSONSink writes entries to an io.Writer, as one JSON object per line.
+type JSONSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONSink returns a JSONSink writing to w.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+// Write implements Sink.
+func (s *JSONSink) Write(e *Entry) error {
+	b, err := marshalLine(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(b)
+
+	return err
+}
+
+func marshalLine(e *Entry) ([]byte, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
+
+// FileOption is used to specify options to NewFileSink.
+type FileOption interface {
+	apply(*FileSink)
+}
+
+type funcFileOption struct {
+	f func(*FileSink)
+}
+
+func (fo *funcFileOption) apply(s *FileSink) {
+	fo.f(s)
+}
+
+// WithMaxSize specifies the size in bytes at which the file is rotated. If this
+// option is not specified, the file is rotated at 100 MiB.
+func WithMaxSize(size int64) FileOption {
+	return &funcFileOption{func(s *FileSink) {
+		s.maxSize = size
+	}}
+}
+
+// WithMaxBackups specifies the number of rotated files to keep. If this option
+// is not specified, 5 rotated files are kept. If n is 0, the file is truncated
+// instead of being rotated.
+func WithMaxBackups(n int) FileOption {
+	return &funcFileOption{func(s *FileSink) {
+		s.maxBackups = n
+	}}
+}
+
+// FileSink writes entries to a file, as one JSON object per line. Each entry
+// is synced to stable storage before Write returns.
+//
+// When writing an entry would grow the file beyond its maximum size, the file
+// is rotated: it is renamed with the suffix ".1", previously rotated files are
+// renamed to the next number, and the oldest is removed. If the file cannot be
+// rotated, the entry is written to the current file anyway, Write returns the
+// error, and rotating is retried on the next Write.
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// NewFileSink returns a FileSink appending to the file at path, which is
+// created if it does not exist.
+func NewFileSink(path string, opts ...FileOption) (*FileSink, error) {
+	s := &FileSink{
+		path:       path,
+		maxSize:    100 << 20,
+		maxBackups: 5,
+	}
+
+	for _, opt := range opts {
+		opt.apply(s)
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Write implements Sink.
+func (s *FileSink) Write(e *Entry) error {
+	b, err := marshalLine(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return os.ErrClosed
+	}
+
+	var rerr error
+	if s.size > 0 && s.size+int64(len(b)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			rerr = fmt.Errorf("audit: rotating %s: %w", s.path, err)
+		}
+	}
+
+	n, err := s.f.Write(b)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	if err := s.f.Sync(); err != nil {
+		return err
+	}
+
+	return rerr
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return nil
+	}
+
+	err := s.f.Close()
+	s.f = nil
+
+	return err
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+
+		return err
+	}
+
+	s.f = f
+	s.size = info.Size()
+
+	return nil
+}
+
+// rotate replaces the current file with a new one. The current file remains
+// in use if that fails.
+func (s *FileSink) rotate() error {
+	if s.maxBackups <= 0 {
+		if err := s.f.Truncate(0); err != nil {
+			return err
+		}
+		s.size = 0
+
+		return nil
+	}
+
+	// Create the new file first, under a temporary name, so that nothing
+	// has been renamed yet if that fails.
+	tmp := s.path + ".new"
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if err := s.shift(); err != nil {
+		f.Close()
+
+		// nolint
+		os.Remove(tmp)
+
+		return err
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		f.Close()
+
+		// Put the current file back in place.
+		// nolint
+		os.Remove(tmp)
+		// nolint
+		os.Rename(s.backup(1), s.path)
+
+		return err
+	}
+
+	// nolint
+	s.f.Close()
+	s.f = f
+	s.size = 0
+
+	return nil
+}
+
+// shift renames the rotated files to the next number, and the current file to
+// the first one.
+func (s *FileSink) shift() error {
+	for i := s.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(s.backup(i), s.backup(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return os.Rename(s.path, s.backup(1))
+}
+
+func (s *FileSink) backup(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
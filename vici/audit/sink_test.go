@@ -0,0 +1,236 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package audit_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/strongswan/govici/vici/audit"
+)
+
+func TestJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+
+	sink := audit.NewJSONSink(&buf)
+
+	for _, cmd := range []string{"load-conn", "unload-conn"} {
+		if err := sink.Write(&audit.Entry{Command: cmd, Outcome: audit.OutcomeSuccess}); err != nil {
+			t.Fatalf("Unexpected error writing entry: %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+
+	for i, cmd := range []string{"load-conn", "unload-conn"} {
+		var v struct {
+			Command string `json:"command"`
+			Outcome string `json:"outcome"`
+		}
+
+		if err := json.Unmarshal([]byte(lines[i]), &v); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", lines[i], err)
+		}
+
+		if v.Command != cmd || v.Outcome != "success" {
+			t.Fatalf("Unexpected line %q", lines[i])
+		}
+	}
+}
+
+// readCommands returns the commands of the entries in the file at path.
+func readCommands(t *testing.T, path string) []string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var cmds []string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var v struct {
+			Command string `json:"command"`
+		}
+
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			t.Fatalf("Invalid JSON line in %s: %v", path, err)
+		}
+
+		cmds = append(cmds, v.Command)
+	}
+
+	return cmds
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	// Each entry is about 80 bytes, so every file holds two entries.
+	sink, err := audit.NewFileSink(path, audit.WithMaxSize(200), audit.WithMaxBackups(2))
+	if err != nil {
+		t.Fatalf("Unexpected error creating sink: %v", err)
+	}
+	defer sink.Close()
+
+	for _, cmd := range []string{"cmd-1", "cmd-2", "cmd-3", "cmd-4", "cmd-5", "cmd-6", "cmd-7"} {
+		if err := sink.Write(&audit.Entry{Command: cmd, Outcome: audit.OutcomeSuccess}); err != nil {
+			t.Fatalf("Unexpected error writing entry: %v", err)
+		}
+	}
+
+	expected := map[string][]string{
+		path:        {"cmd-7"},
+		path + ".1": {"cmd-5", "cmd-6"},
+		path + ".2": {"cmd-3", "cmd-4"},
+	}
+
+	for p, cmds := range expected {
+		if got := readCommands(t, p); strings.Join(got, ",") != strings.Join(cmds, ",") {
+			t.Errorf("Unexpected entries in %s: got %v, expected %v", filepath.Base(p), got, cmds)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected only 2 backups, got %s.3", filepath.Base(path))
+	}
+}
+
+func TestFileSinkRotationFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := audit.NewFileSink(path, audit.WithMaxSize(200), audit.WithMaxBackups(2))
+	if err != nil {
+		t.Fatalf("Unexpected error creating sink: %v", err)
+	}
+	defer sink.Close()
+
+	// Make creating the new file fail, by taking its name.
+	if err := os.Mkdir(path+".new", 0o700); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, cmd := range []string{"cmd-1", "cmd-2"} {
+		if err := sink.Write(&audit.Entry{Command: cmd, Outcome: audit.OutcomeSuccess}); err != nil {
+			t.Fatalf("Unexpected error writing entry: %v", err)
+		}
+	}
+
+	// The file cannot be rotated, the entries are written to it anyway.
+	for _, cmd := range []string{"cmd-3", "cmd-4"} {
+		if err := sink.Write(&audit.Entry{Command: cmd, Outcome: audit.OutcomeSuccess}); err == nil {
+			t.Fatalf("Expected rotation error writing %s", cmd)
+		}
+	}
+
+	if err := os.Remove(path + ".new"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Rotating is retried on the next write.
+	if err := sink.Write(&audit.Entry{Command: "cmd-5", Outcome: audit.OutcomeSuccess}); err != nil {
+		t.Fatalf("Unexpected error writing entry: %v", err)
+	}
+
+	expected := map[string][]string{
+		path:        {"cmd-5"},
+		path + ".1": {"cmd-1", "cmd-2", "cmd-3", "cmd-4"},
+	}
+
+	for p, cmds := range expected {
+		if got := readCommands(t, p); strings.Join(got, ",") != strings.Join(cmds, ",") {
+			t.Errorf("Unexpected entries in %s: got %v, expected %v", filepath.Base(p), got, cmds)
+		}
+	}
+}
+
+func TestFileSinkNoBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := audit.NewFileSink(path, audit.WithMaxSize(200), audit.WithMaxBackups(0))
+	if err != nil {
+		t.Fatalf("Unexpected error creating sink: %v", err)
+	}
+	defer sink.Close()
+
+	for _, cmd := range []string{"cmd-1", "cmd-2", "cmd-3"} {
+		if err := sink.Write(&audit.Entry{Command: cmd, Outcome: audit.OutcomeSuccess}); err != nil {
+			t.Fatalf("Unexpected error writing entry: %v", err)
+		}
+	}
+
+	// The file is truncated instead of being rotated.
+	if got := readCommands(t, path); strings.Join(got, ",") != "cmd-3" {
+		t.Fatalf("Expected only cmd-3 after truncating, got %v", got)
+	}
+
+	if _, err := os.Stat(path + ".1"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected no backups, got %s.1", filepath.Base(path))
+	}
+}
+
+func TestFileSinkAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	for _, cmd := range []string{"cmd-1", "cmd-2"} {
+		sink, err := audit.NewFileSink(path)
+		if err != nil {
+			t.Fatalf("Unexpected error creating sink: %v", err)
+		}
+
+		if err := sink.Write(&audit.Entry{Command: cmd}); err != nil {
+			t.Fatalf("Unexpected error writing entry: %v", err)
+		}
+
+		if err := sink.Close(); err != nil {
+			t.Fatalf("Unexpected error closing sink: %v", err)
+		}
+
+		if err := sink.Write(&audit.Entry{Command: cmd}); !errors.Is(err, os.ErrClosed) {
+			t.Fatalf("Expected os.ErrClosed writing to closed sink, got %v", err)
+		}
+	}
+
+	if got := readCommands(t, path); strings.Join(got, ",") != "cmd-1,cmd-2" {
+		t.Fatalf("Expected entries to be appended, got %v", got)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("Expected file mode 0600, got %v", perm)
+	}
+}
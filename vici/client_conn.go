@@ -21,16 +21,16 @@
 package vici
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"iter"
+	"log/slog"
 	"net"
 	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -40,14 +40,46 @@ var (
 
 	// Received EVENT_UNKNOWN from server
 	errEventUnknown = errors.New("vici: unknown event type")
+
+	// ErrUnknownCommand is returned by a command request when the daemon does
+	// not know the requested command.
+	ErrUnknownCommand = fmt.Errorf("%w: unknown command", errUnexpectedResponse)
+
+	// ErrSessionClosed is returned by Session.Err, and by requests made on the
+	// Session, after the Session is closed with Session.Close.
+	ErrSessionClosed = errors.New("vici: session closed")
+
+	// ErrResponseDropped is returned by a request when the response from the
+	// daemon was received, but had to be dropped because the response buffer
+	// was full. This happens when responses and streamed events are received
+	// faster than they are consumed.
+	ErrResponseDropped = errors.New("vici: response dropped")
 )
 
+// How long to wait for the daemon to unregister a streamed event once the stream
+// is done, if the stream was interrupted before the command was complete.
+const unregisterTimeout = 5 * time.Second
+
 type clientConn struct {
-	sync.Mutex
+	// Held by the caller making a request for the whole exchange with the
+	// daemon. It is a chan rather than a sync.Mutex, so that callers waiting
+	// for it can give up when their context is done.
+	sem chan struct{}
+
 	conn net.Conn
 
-	// Propagate errors from the listen() loop.
-	err chan error
+	// Logger, whether to log the payload of every packet, and the redactor
+	// for logged payloads.
+	logger      *slog.Logger
+	logPayloads bool
+	redactor    *Redactor
+
+	// Closed once the connection has ended, either because the listen() loop
+	// exited, or because the connection was closed locally. The reason is
+	// stored in doneErr before done is closed.
+	done     chan struct{}
+	doneOnce sync.Once
+	doneErr  error
 
 	// Read and write sequence counters used to associate command responses with
 	// the caller. This keeps coherency when callers abandon their response, e.g.
@@ -55,43 +87,144 @@ type clientConn struct {
 	rseq uint64
 	wseq uint64
 
+	// Number of requests written, or being written, whose response has not
+	// been received yet. A response received while there are none is not
+	// solicited, e.g. a duplicate, and is ignored.
+	pending atomic.Int64
+
 	// Packet chan buffer. The listen() function is responseible for reading
 	// all data from the server, and this chan buffer is used to dispatch
 	// reponses to waiting callers.
 	pc chan *Message
 
+	// When a response has to be dropped because pc is full, its sequence
+	// number is stored in droppedSeq, and overflow is signaled so that
+	// the waiting caller can give up.
+	droppedSeq atomic.Uint64
+	overflow   chan struct{}
+
+	// Counters reported by Session.Stats.
+	stats struct {
+		packetsRead      atomic.Uint64
+		packetsWritten   atomic.Uint64
+		eventsDispatched atomic.Uint64
+		eventsDropped    atomic.Uint64
+		responsesDropped atomic.Uint64
+	}
+
 	events struct {
 		sync.Mutex
 
-		list        []string
-		streaming   string
-		subscribers map[chan<- Event]struct{}
+		list      []string
+		streaming string
+
+		// Subscribers, and the number of events dropped for each.
+		subscribers map[chan<- Event]uint64
+
+		// Number of users of each event registration with the server. An
+		// event is registered by subscriptions (i.e. events in list), and by
+		// an active streaming call.
+		refs map[string]int
 	}
 }
 
 func newClientConn(conn net.Conn) *clientConn {
 	cc := &clientConn{
-		conn: conn,
-		pc:   make(chan *Message, 128),
-		err:  make(chan error, 1),
+		sem:      make(chan struct{}, 1),
+		conn:     conn,
+		logger:   discardLogger,
+		pc:       make(chan *Message, 128),
+		overflow: make(chan struct{}, 1),
+		done:     make(chan struct{}),
 		events: struct {
 			sync.Mutex
 			list        []string
 			streaming   string
-			subscribers map[chan<- Event]struct{}
+			subscribers map[chan<- Event]uint64
+			refs        map[string]int
 		}{
 			list:        make([]string, 0),
-			subscribers: make(map[chan<- Event]struct{}),
+			subscribers: make(map[chan<- Event]uint64),
+			refs:        make(map[string]int),
 		},
 	}
 
 	return cc
 }
 
+// Lock acquires exclusive use of the connection, waiting as long as needed.
+func (cc *clientConn) Lock() {
+	cc.sem <- struct{}{}
+}
+
+// LockContext acquires exclusive use of the connection like Lock, but gives up
+// and returns the context's error once ctx is done.
+func (cc *clientConn) LockContext(ctx context.Context) error {
+	if ctx == nil {
+		return errors.New("context cannot be nil")
+	}
+
+	select {
+	case cc.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryLock acquires exclusive use of the connection if it is available, and
+// reports whether it did.
+func (cc *clientConn) TryLock() bool {
+	select {
+	case cc.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Unlock releases the connection acquired with Lock, LockContext or TryLock.
+func (cc *clientConn) Unlock() {
+	<-cc.sem
+}
+
 func (cc *clientConn) Close() error {
+	cc.terminate(ErrSessionClosed)
+
 	return cc.conn.Close()
 }
 
+// fail ends the connection because of err.
+func (cc *clientConn) fail(err error) {
+	cc.terminate(err)
+
+	// nolint
+	cc.conn.Close()
+}
+
+// terminate records err as the reason the connection ended, and closes the
+// done chan. Only the first call has an effect.
+func (cc *clientConn) terminate(err error) {
+	cc.doneOnce.Do(func() {
+		cc.logger.LogAttrs(context.Background(), terminationLevel(err), "connection ended",
+			slog.Any("error", err),
+		)
+
+		cc.doneErr = err
+		close(cc.done)
+	})
+}
+
+// Err returns the reason the connection ended, or nil if it has not.
+func (cc *clientConn) Err() error {
+	select {
+	case <-cc.done:
+		return cc.doneErr
+	default:
+		return nil
+	}
+}
+
 // listen is responsible for reading all data from the server. It dispatches
 // read packets depending on the type.
 //
@@ -107,9 +240,17 @@ func (cc *clientConn) listen() {
 	for {
 		p, err := cc.read()
 		if err != nil {
-			cc.err <- err
+			if errors.Is(err, io.EOF) {
+				err = fmt.Errorf("vici: connection closed by daemon: %w", err)
+			} else {
+				err = fmt.Errorf("vici: error reading from daemon: %w", err)
+			}
+			cc.terminate(err)
+
 			return
 		}
+		cc.stats.packetsRead.Add(1)
+		cc.logPacket("received packet", p)
 
 		switch p.header.ptype {
 		case /* We received an event from the server. */
@@ -128,6 +269,14 @@ func (cc *clientConn) listen() {
 			pktEventConfirm,
 			pktEventUnknown:
 
+			if !cc.solicited() {
+				cc.logger.LogAttrs(context.Background(), slog.LevelWarn, "ignoring unsolicited response",
+					slog.String("type", PacketType(p.header.ptype).String()),
+				)
+
+				continue
+			}
+
 			// Only increment this counter for direct response packets.
 			cc.rseq++
 			p.header.seq = cc.rseq
@@ -135,8 +284,20 @@ func (cc *clientConn) listen() {
 			select {
 			case cc.pc <- p:
 			default:
-				// For now, silently drop the packet. Do not block
-				// if the chan is full.
+				// Do not block if the chan is full. Drop the packet,
+				// and let the waiting caller know.
+				cc.stats.responsesDropped.Add(1)
+				cc.droppedSeq.Store(p.header.seq)
+
+				cc.logger.LogAttrs(context.Background(), slog.LevelWarn, "dropped response, response buffer is full",
+					slog.String("type", PacketType(p.header.ptype).String()),
+					slog.Uint64("seq", p.header.seq),
+				)
+
+				select {
+				case cc.overflow <- struct{}{}:
+				default:
+				}
 			}
 
 		case /* These are only handled server-side, ignore. */
@@ -144,9 +305,17 @@ func (cc *clientConn) listen() {
 			pktEventRegister,
 			pktEventUnregister:
 
+			cc.logger.LogAttrs(context.Background(), slog.LevelWarn, "ignoring unexpected packet",
+				slog.String("type", PacketType(p.header.ptype).String()),
+			)
+
 			continue
 		default:
 			/* We should not be here, unless a bogus message was received. */
+			cc.logger.LogAttrs(context.Background(), slog.LevelWarn, "ignoring unexpected packet",
+				slog.String("type", PacketType(p.header.ptype).String()),
+			)
+
 			continue
 		}
 	}
@@ -164,26 +333,7 @@ func (cc *clientConn) stop() {
 }
 
 func (cc *clientConn) read() (*Message, error) {
-	p := NewMessage()
-
-	buf := make([]byte, 4 /* header length */)
-	_, err := io.ReadFull(cc.conn, buf)
-	if err != nil {
-		return nil, err
-	}
-	pl := binary.BigEndian.Uint32(buf)
-
-	buf = make([]byte, int(pl))
-	_, err = io.ReadFull(cc.conn, buf)
-	if err != nil {
-		return nil, err
-	}
-
-	if err := p.decode(buf); err != nil {
-		return nil, err
-	}
-
-	return p, nil
+	return readPacket(cc.conn)
 }
 
 func (cc *clientConn) write(ctx context.Context, p *Message) error {
@@ -191,6 +341,10 @@ func (cc *clientConn) write(ctx context.Context, p *Message) error {
 		return errors.New("context cannot be nil")
 	}
 
+	if err := cc.Err(); err != nil {
+		return err
+	}
+
 	if p == nil {
 		return errors.New("message cannot be nil")
 	}
@@ -203,20 +357,7 @@ func (cc *clientConn) write(ctx context.Context, p *Message) error {
 		return fmt.Errorf("invalid request with packet type %v", p.header.ptype)
 	}
 
-	buf := bytes.NewBuffer([]byte{})
-
-	b, err := p.encode()
-	if err != nil {
-		return err
-	}
-
-	// Write the packet length
-	if err := safePutUint32(buf, len(b)); err != nil {
-		return err
-	}
-
-	// Write the payload
-	_, err = buf.Write(b)
+	b, err := p.encodePacket()
 	if err != nil {
 		return err
 	}
@@ -226,11 +367,15 @@ func (cc *clientConn) write(ctx context.Context, p *Message) error {
 		return err
 	}
 
+	// The response may be received before the write returns, so it must
+	// be expected before writing.
+	cc.pending.Add(1)
+
 	rc := make(chan error, 1)
 	go func() {
 		defer close(rc)
 
-		_, err = cc.conn.Write(buf.Bytes())
+		_, err := cc.conn.Write(b)
 		rc <- err
 	}()
 
@@ -240,22 +385,30 @@ func (cc *clientConn) write(ctx context.Context, p *Message) error {
 		// write and bail. Once we set the deadline, wait for
 		// the goroutune above to return.
 		if err := cc.conn.SetWriteDeadline(time.Now()); err != nil {
+			cc.pending.Add(-1)
+
 			return err
 		}
 
 		if err := <-rc; err != nil {
+			cc.pending.Add(-1)
+
 			// Assuming the write did fail, return the context's
 			// error for clarity.
 			return ctx.Err()
 		}
 	case err := <-rc:
 		if err != nil {
+			cc.pending.Add(-1)
+
 			return err
 		}
 	}
 
 	// Increment the write sequence on successful writes.
 	cc.wseq++
+	cc.stats.packetsWritten.Add(1)
+	cc.logPacket("sent packet", p)
 
 	return nil
 }
@@ -277,17 +430,24 @@ func (cc *clientConn) wait(ctx context.Context) (*Message, error) {
 			deadline = d.Add(5 * time.Second)
 		}
 
-		if err := cc.conn.SetReadDeadline(deadline); err != nil {
-			return nil, err
-		}
+		// Setting the deadline fails if the connection is closed, e.g.
+		// by the daemon. The listen loop then ends the connection with
+		// the reason, which is returned below.
+		// nolint
+		cc.conn.SetReadDeadline(deadline)
 
 		select {
 		case <-ctx.Done():
+			cc.logger.LogAttrs(ctx, slog.LevelInfo, "stopped waiting for response",
+				slog.Uint64("seq", cc.wseq),
+				slog.Any("error", ctx.Err()),
+			)
+
 			return nil, ctx.Err()
 
 		case p, ok := <-cc.pc:
 			if !ok {
-				return nil, fmt.Errorf("vici: error waiting for response: %w", io.ErrClosedPipe)
+				return nil, cc.Err()
 			}
 
 			if p.header.ptype != pktEvent {
@@ -299,12 +459,28 @@ func (cc *clientConn) wait(ctx context.Context) (*Message, error) {
 
 			return p, nil
 
-		case err, ok := <-cc.err:
-			if !ok {
-				err = io.ErrClosedPipe
+		case <-cc.overflow:
+			if cc.droppedSeq.Load() == cc.wseq {
+				return nil, ErrResponseDropped
 			}
 
-			return nil, fmt.Errorf("vici: error waiting for data: %w", err)
+		case <-cc.done:
+			return nil, cc.Err()
+		}
+	}
+}
+
+// solicited reports whether a response was expected, i.e. whether a request is
+// pending, and if so, marks the request as answered.
+func (cc *clientConn) solicited() bool {
+	for {
+		n := cc.pending.Load()
+		if n <= 0 {
+			return false
+		}
+
+		if cc.pending.CompareAndSwap(n, n-1) {
+			return true
 		}
 	}
 }
@@ -324,6 +500,11 @@ func (cc *clientConn) request(ctx context.Context, ptype uint8, name string, in
 	}
 
 	p, err := cc.wait(ctx)
+	for err == nil && p.header.ptype == pktEvent {
+		// This is a leftover event from a streaming call that ended
+		// early, not the response to this request.
+		p, err = cc.wait(ctx)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -333,6 +514,9 @@ func (cc *clientConn) request(ctx context.Context, ptype uint8, name string, in
 	case /* Command request */
 		pktCmdRequest:
 
+		if p.header.ptype == pktCmdUnknown {
+			return nil, fmt.Errorf("%w: %v", ErrUnknownCommand, name)
+		}
 		if p.header.ptype != pktCmdResponse {
 			return nil, fmt.Errorf("%w: %v", errUnexpectedResponse, p.header.ptype)
 		}
@@ -355,133 +539,283 @@ func (cc *clientConn) request(ctx context.Context, ptype uint8, name string, in
 	return p, p.Err()
 }
 
+// stream returns an iterator over the events streamed by a command request, see
+// streamFunc. A failed command response is yielded with its error.
 func (cc *clientConn) stream(ctx context.Context, cmd string, event string, in *Message) iter.Seq2[*Message, error] {
 	return func(yield func(*Message, error) bool) {
-		if in == nil {
-			in = NewMessage()
+		out, err := cc.streamFunc(ctx, cmd, event, in, func(m *Message) bool {
+			return yield(m, m.Err())
+		})
+		if err != nil {
+			yield(out, err)
 		}
+	}
+}
 
-		in.header = &header{
-			ptype: pktCmdRequest,
-			name:  cmd,
-		}
+// streamFunc makes a command request which streams event until the command is
+// complete. Each streamed event is passed to fn, and the command response is
+// returned. If fn returns false, streamFunc returns a nil Message and error
+// without waiting for the response.
+func (cc *clientConn) streamFunc(ctx context.Context, cmd string, event string, in *Message, fn func(*Message) bool) (out *Message, err error) {
+	var (
+		start    = time.Now()
+		n        int
+		complete bool
+	)
+
+	defer func() {
+		cc.logRequest(ctx, "streamed command request", err,
+			slog.String("command", cmd),
+			slog.String("event", event),
+			slog.Int("events", n),
+			slog.Duration("duration", time.Since(start)),
+		)
+	}()
 
-		// Initialize the associated event streaming.
-		if _, err := cc.request(ctx, pktEventRegister, event, nil); err != nil {
-			yield(nil, err)
-			return
-		}
+	if in == nil {
+		in = NewMessage()
+	}
+
+	in.header = &header{
+		ptype: pktCmdRequest,
+		name:  cmd,
+	}
+
+	// Initialize the associated event streaming. If the event is
+	// already registered for a subscription, it is left registered
+	// when the stream is done.
+	if err := cc.ref(ctx, event); err != nil {
+		return nil, err
+	}
+	cc.events.Lock()
+	cc.events.streaming = event
+	cc.events.Unlock()
+	defer func() {
 		cc.events.Lock()
-		cc.events.streaming = event
+		cc.events.streaming = ""
 		cc.events.Unlock()
-		defer func() {
-			// nolint
-			_, _ = cc.request(ctx, pktEventUnregister, event, nil)
 
-			cc.events.Lock()
-			cc.events.streaming = ""
-			cc.events.Unlock()
-		}()
+		if !complete || ctx.Err() != nil {
+			// The daemon only confirms the unregistration once the
+			// command is complete, so do not make the caller wait
+			// for it.
+			go cc.release(event)
 
-		if err := cc.write(ctx, in); err != nil {
-			yield(nil, err)
 			return
 		}
 
-		for {
-			p, err := cc.wait(ctx)
-			if err != nil {
-				yield(nil, err)
-				return
-			}
+		// nolint
+		_ = cc.unref(ctx, event)
+	}()
+
+	if err := cc.write(ctx, in); err != nil {
+		return nil, err
+	}
 
-			switch p.header.ptype {
-			case /* Event packet. There may be more. */
-				pktEvent:
+	for {
+		p, err := cc.wait(ctx)
+		if err != nil {
+			return nil, err
+		}
 
-				if p.header.name != event {
-					continue
-				}
+		switch p.header.ptype {
+		case /* Event packet. There may be more. */
+			pktEvent:
 
-				if !yield(p, p.Err()) {
-					return
-				}
-			case /* Command response, stream is complete. */
-				pktCmdResponse:
-
-				// If the last message contains an error,
-				// propagate it. Otherwise, the previous event
-				// should be the last message seen by the
-				// caller.
-				if err := p.Err(); err != nil {
-					yield(p, p.Err())
-				}
+			if p.header.name != event {
+				continue
+			}
 
-				return
-			default:
-				yield(nil, fmt.Errorf("%w: %v", errUnexpectedResponse, p.header.ptype))
-				return
+			n++
+			if !fn(p) {
+				return nil, nil
 			}
+		case /* Command response, stream is complete. */
+			pktCmdResponse:
+
+			complete = true
+
+			return p, p.Err()
+		case /* The daemon does not know the command. */
+			pktCmdUnknown:
+
+			complete = true
+
+			return nil, fmt.Errorf("%w: %v", ErrUnknownCommand, cmd)
+		default:
+			complete = true
+
+			return nil, fmt.Errorf("%w: %v", errUnexpectedResponse, p.header.ptype)
 		}
 	}
 }
 
+// release releases the registration of a stream's event in the background, once
+// the caller released the connection. It is used for streams interrupted before
+// the command was complete, e.g. because ctx is done, and does not wait for the
+// daemon for long.
+func (cc *clientConn) release(event string) {
+	cc.Lock()
+	defer cc.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), unregisterTimeout)
+	defer cancel()
+
+	// nolint
+	_ = cc.unref(ctx, event)
+}
+
 func (cc *clientConn) call(ctx context.Context, cmd string, in *Message) (*Message, error) {
-	return cc.request(ctx, pktCmdRequest, cmd, in)
+	start := time.Now()
+
+	out, err := cc.request(ctx, pktCmdRequest, cmd, in)
+
+	cc.logRequest(ctx, "command request", err,
+		slog.String("command", cmd),
+		slog.Duration("duration", time.Since(start)),
+	)
+
+	return out, err
 }
 
+// subscribe registers the given events with the server. The caller must
+// hold the clientConn lock. The events lock is not held while waiting for a
+// response, because the listener needs it to dispatch events received in
+// the meantime.
 func (cc *clientConn) subscribe(ctx context.Context, events ...string) error {
-	cc.events.Lock()
-	defer cc.events.Unlock()
-
 	if events == nil {
 		return errors.New("must specify at least one event")
 	}
 
 	for _, event := range events {
-		if slices.Contains(cc.events.list, event) {
+		if cc.subscribed(event) {
 			continue
 		}
 
-		if _, err := cc.request(ctx, pktEventRegister, event, nil); err != nil {
+		// Add the event before registering it, so that events the server
+		// sends right after confirming the registration are dispatched,
+		// even if they are received before ref returns.
+		cc.events.Lock()
+		cc.events.list = append(cc.events.list, event)
+		cc.events.Unlock()
+
+		if err := cc.ref(ctx, event); err != nil {
+			cc.events.Lock()
+			cc.events.list = slices.DeleteFunc(cc.events.list, func(e string) bool {
+				return e == event
+			})
+			cc.events.Unlock()
+
 			return err
 		}
-
-		cc.events.list = append(cc.events.list, event)
 	}
 
 	return nil
 }
 
+// unsubscribe unregisters the given events, or all registered events if none
+// are given. Like subscribe, the caller must hold the clientConn lock.
 func (cc *clientConn) unsubscribe(ctx context.Context, events ...string) error {
-	cc.events.Lock()
-	defer cc.events.Unlock()
-
 	if events == nil {
+		cc.events.Lock()
 		events = slices.Clone(cc.events.list)
+		cc.events.Unlock()
 	}
 
 	for _, event := range events {
-		index := slices.Index(cc.events.list, event)
-		if index < 0 {
+		if !cc.subscribed(event) {
 			continue
 		}
 
-		if _, err := cc.request(ctx, pktEventUnregister, event, nil); err != nil {
+		err := cc.unref(ctx, event)
+
+		cc.events.Lock()
+		cc.events.list = slices.DeleteFunc(cc.events.list, func(e string) bool {
+			return e == event
+		})
+		cc.events.Unlock()
+
+		if err != nil {
 			return err
 		}
+	}
+
+	return nil
+}
 
-		cc.events.list = slices.Delete(cc.events.list, index, index+1)
+// ref registers event with the server, unless it is already registered, and
+// counts the new user of the registration. The caller must hold the clientConn
+// lock.
+func (cc *clientConn) ref(ctx context.Context, event string) error {
+	cc.events.Lock()
+	n := cc.events.refs[event]
+	cc.events.Unlock()
+
+	if n == 0 {
+		_, err := cc.request(ctx, pktEventRegister, event, nil)
+
+		cc.logRequest(ctx, "event registration", err, slog.String("event", event))
+
+		if err != nil {
+			return err
+		}
 	}
 
+	cc.events.Lock()
+	cc.events.refs[event]++
+	cc.events.Unlock()
+
 	return nil
 }
 
+// unref releases a user of the event registration, and unregisters event with
+// the server once there are no users left. The caller must hold the clientConn
+// lock.
+func (cc *clientConn) unref(ctx context.Context, event string) error {
+	cc.events.Lock()
+	n := cc.events.refs[event]
+	cc.events.Unlock()
+
+	var err error
+	if n == 1 {
+		_, err = cc.request(ctx, pktEventUnregister, event, nil)
+
+		cc.logRequest(ctx, "event unregistration", err, slog.String("event", event))
+	}
+
+	// The user is released even if the unregistration failed. Otherwise,
+	// the registration would never be released, and the event would not
+	// be registered again when it is next needed.
+	cc.events.Lock()
+	if cc.events.refs[event]--; cc.events.refs[event] <= 0 {
+		delete(cc.events.refs, event)
+	}
+	cc.events.Unlock()
+
+	return err
+}
+
+func (cc *clientConn) subscribed(event string) bool {
+	cc.events.Lock()
+	defer cc.events.Unlock()
+
+	return slices.Contains(cc.events.list, event)
+}
+
+func (cc *clientConn) subscriptions() []string {
+	cc.events.Lock()
+	defer cc.events.Unlock()
+
+	return slices.Clone(cc.events.list)
+}
+
 func (cc *clientConn) notify(c chan<- Event) {
 	cc.events.Lock()
 	defer cc.events.Unlock()
 
-	cc.events.subscribers[c] = struct{}{}
+	if _, ok := cc.events.subscribers[c]; !ok {
+		cc.events.subscribers[c] = 0
+	}
 }
 
 func (cc *clientConn) unnotify(c chan<- Event) {
@@ -495,15 +829,21 @@ func (cc *clientConn) dispatch(ev Event) {
 	cc.events.Lock()
 	defer cc.events.Unlock()
 
+	cc.stats.eventsDispatched.Add(1)
+
 	if ev.Name == cc.events.streaming {
 		// This event is associated with an active streaming call.
-		// Dispatch internally only.
+		// Dispatch it internally, and then to subscribers too in
+		// case the session is also subscribed to this event.
 		select {
 		case cc.pc <- ev.Message:
 		default:
-		}
+			cc.stats.eventsDropped.Add(1)
 
-		return
+			cc.logger.LogAttrs(context.Background(), slog.LevelWarn, "dropped streamed event, response buffer is full",
+				slog.String("event", ev.Name),
+			)
+		}
 	}
 
 	if !slices.Contains(cc.events.list, ev.Name) {
@@ -515,6 +855,59 @@ func (cc *clientConn) dispatch(ev Event) {
 		select {
 		case c <- ev:
 		default:
+			cc.stats.eventsDropped.Add(1)
+			cc.events.subscribers[c]++
+
+			cc.logger.LogAttrs(context.Background(), slog.LevelWarn, "dropped event, subscriber is not ready",
+				slog.String("event", ev.Name),
+			)
 		}
 	}
 }
+
+// statistics returns a snapshot of the connection's counters.
+func (cc *clientConn) statistics() Stats {
+	st := Stats{
+		PacketsRead:      cc.stats.packetsRead.Load(),
+		PacketsWritten:   cc.stats.packetsWritten.Load(),
+		EventsDispatched: cc.stats.eventsDispatched.Load(),
+		EventsDropped:    cc.stats.eventsDropped.Load(),
+		ResponsesDropped: cc.stats.responsesDropped.Load(),
+	}
+
+	cc.events.Lock()
+	defer cc.events.Unlock()
+
+	st.SubscriberDrops = make(map[chan<- Event]uint64, len(cc.events.subscribers))
+	for c, n := range cc.events.subscribers {
+		st.SubscriberDrops[c] = n
+	}
+
+	return st
+}
+
+// logRequest logs the outcome of a request at debug level, or at warning level
+// if it failed.
+func (cc *clientConn) logRequest(ctx context.Context, msg string, err error, attrs ...slog.Attr) {
+	level := slog.LevelDebug
+
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.Any("error", err))
+	}
+
+	cc.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// logPacket logs the packet and its redacted payload, if payload logging is enabled.
+func (cc *clientConn) logPacket(msg string, p *Message) {
+	if !cc.logPayloads {
+		return
+	}
+
+	cc.logger.LogAttrs(context.Background(), slog.LevelDebug, msg,
+		slog.String("type", PacketType(p.header.ptype).String()),
+		slog.String("name", p.header.name),
+		slog.Any("payload", payloadValue{p, cc.redactor}),
+	)
+}
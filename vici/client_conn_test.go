@@ -25,6 +25,7 @@ import (
 	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"os"
@@ -54,47 +55,15 @@ func newTestServer(conn net.Conn) *testServer {
 }
 
 func (ts *testServer) read() (*Message, error) {
-	p := NewMessage()
-
-	buf := make([]byte, 4 /* header length */)
-	_, err := io.ReadFull(ts.conn, buf)
-	if err != nil {
-		return nil, err
-	}
-	pl := binary.BigEndian.Uint32(buf)
-
-	buf = make([]byte, int(pl))
-	_, err = io.ReadFull(ts.conn, buf)
-	if err != nil {
-		return nil, err
-	}
-
-	if err := p.decode(buf); err != nil {
-		return nil, err
-	}
-
-	return p, nil
+	return readPacket(ts.conn)
 }
 
 func (ts *testServer) write(p *Message) error {
-	buf := bytes.NewBuffer([]byte{})
-
-	b, err := p.encode()
-	if err != nil {
-		return err
-	}
-
-	// Write the packet length
-	if err := safePutUint32(buf, len(b)); err != nil {
-		return err
-	}
-
-	// Write the payload
-	_, err = buf.Write(b)
+	b, err := p.encodePacket()
 	if err != nil {
 		return err
 	}
-	_, err = ts.conn.Write(buf.Bytes())
+	_, err = ts.conn.Write(b)
 
 	return err
 }
@@ -218,6 +187,8 @@ func (ts *testServer) commandHandler(p *Message) (*Message, error) {
 		return ts.commandHandlerStream(p)
 	case "cmd-no-response":
 		return ts.commandHandlerNoResponse(p)
+	case "version":
+		return ts.commandHandlerOK(p)
 	default:
 		return nil, nil
 	}
@@ -310,7 +281,21 @@ func (ts *testServer) eventRegisterHandlerStream(p *Message) (*Message, error) {
 	return resp, nil
 }
 
+// registered reports whether event is currently registered with the server.
+func (ts *testServer) registered(event string) bool {
+	ts.Lock()
+	defer ts.Unlock()
+
+	registered, _ := ts.data["registered:"+event].(bool)
+
+	return registered
+}
+
 func (ts *testServer) handleEventRegistration(p *Message) (*Message, error) {
+	ts.Lock()
+	ts.data["registered:"+p.header.name] = p.header.ptype == pktEventRegister
+	ts.Unlock()
+
 	switch p.header.name {
 	case "event-confirm":
 		return ts.eventRegisterHandlerConfirm(p)
@@ -318,6 +303,16 @@ func (ts *testServer) handleEventRegistration(p *Message) (*Message, error) {
 		return ts.eventRegisterHandlerSimple(p)
 	case "event-stream":
 		return ts.eventRegisterHandlerStream(p)
+	case "event-no-response":
+		return nil, syscall.ENODATA
+	case "event-no-unregister":
+		// Like charon while a command is pending, confirm the
+		// registration, but not the unregistration.
+		if p.header.ptype == pktEventUnregister {
+			return nil, syscall.ENODATA
+		}
+
+		return ts.eventRegisterHandlerConfirm(p)
 	default:
 		resp := &Message{
 			header: &header{
@@ -338,7 +333,9 @@ func (ts *testServer) serve() {
 
 		p, err := ts.read()
 		if err != nil {
-			if errors.Is(err, io.ErrClosedPipe) {
+			// The client may also close the connection in the middle
+			// of a packet, e.g. while a health check is written.
+			if errors.Is(err, io.ErrClosedPipe) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
 				return
 			}
 			panic(err)
@@ -358,6 +355,10 @@ func (ts *testServer) serve() {
 
 			case pktEventRegister, pktEventUnregister:
 				resp, err = ts.handleEventRegistration(p)
+				if errors.Is(err, syscall.ENODATA) {
+					// Specical case that means send no response.
+					return
+				}
 				if err != nil {
 					panic(err)
 				}
@@ -379,6 +380,10 @@ func (ts *testServer) serve() {
 			}
 
 			if err := ts.write(resp); err != nil {
+				if errors.Is(err, io.ErrClosedPipe) {
+					// The client went away before the response was sent.
+					return
+				}
 				panic(err)
 			}
 		}()
@@ -680,6 +685,66 @@ func TestClientConnSubscribe(t *testing.T) {
 	}
 }
 
+func TestClientConnSubscribeEagerEvent(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	cc := newClientConn(client)
+	go cc.listen()
+
+	ec := make(chan Event, 1)
+	cc.notify(ec)
+	defer cc.unnotify(ec)
+
+	ts := newTestServer(server)
+
+	errs := make(chan error, 1)
+	go func() {
+		p, err := ts.read()
+		if err != nil {
+			errs <- err
+			return
+		}
+		if p.header.ptype != pktEventRegister {
+			errs <- fmt.Errorf("unexpected packet type %v", p.header.ptype)
+			return
+		}
+
+		// Send the event ahead of the confirmation. This is what the
+		// client sees when it reads an event sent right after the
+		// confirmation before the subscribing caller has been woken up.
+		ev := NewMessage()
+		ev.header = &header{
+			ptype: pktEvent,
+			name:  p.header.name,
+		}
+		if err := ts.write(ev); err != nil {
+			errs <- err
+			return
+		}
+
+		errs <- ts.write(&Message{header: &header{ptype: pktEventConfirm}})
+	}()
+
+	if err := cc.subscribe(context.Background(), "event-eager"); err != nil {
+		t.Fatalf("Unexpected failure: %v", err)
+	}
+
+	if err := <-errs; err != nil {
+		t.Fatalf("Unexpected server error: %v", err)
+	}
+
+	select {
+	case ev := <-ec:
+		if ev.Name != "event-eager" {
+			t.Fatalf("Received unexpected event %s", ev.Name)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Did not receive event sent during the registration")
+	}
+}
+
 func TestClientConnNotify(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		cc, ts := newTestClientServer()
@@ -755,3 +820,183 @@ func TestClientConnStream(t *testing.T) {
 	default:
 	}
 }
+
+func TestClientConnStreamCancel(t *testing.T) {
+	cc, ts := newTestClientServer()
+	defer cc.conn.Close()
+	defer ts.conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, err := range cc.stream(ctx, "cmd-stream", "event-stream", nil) {
+		if err != nil {
+			t.Fatalf("Unexpected failure: %v", err)
+		}
+
+		// Give up on the stream after the first event, with the
+		// context cancelled, e.g. because the caller is shutting down.
+		cancel()
+		break
+	}
+
+	// The event registration must be released although the stream's
+	// context was cancelled. It is released in the background, once the
+	// connection is free again.
+	released := func() bool {
+		cc.events.Lock()
+		defer cc.events.Unlock()
+
+		return len(cc.events.refs) == 0
+	}
+
+	timeout := time.After(5 * time.Second)
+	for !released() {
+		select {
+		case <-timeout:
+			t.Fatal("Event registration was not released after the stream was cancelled")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if ts.registered("event-stream") {
+		t.Fatal("Event is still registered after the stream was cancelled")
+	}
+}
+
+func TestClientConnStreamDeadline(t *testing.T) {
+	cc, ts := newTestClientServer()
+	defer cc.conn.Close()
+	defer ts.conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	for _, err := range cc.stream(ctx, "cmd-no-response", "event-no-unregister", nil) {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	}
+
+	// The stream must not wait for the unregistration, which the daemon
+	// does not confirm while the command is pending.
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Expected the stream to end promptly after the deadline, took %v", d)
+	}
+}
+
+func TestClientConnStreamWhileSubscribed(t *testing.T) {
+	cc, ts := newTestClientServer()
+	defer cc.conn.Close()
+	defer ts.conn.Close()
+
+	ec := make(chan Event, 4)
+	cc.notify(ec)
+	defer cc.unnotify(ec)
+
+	if err := cc.subscribe(context.Background(), "event-stream"); err != nil {
+		t.Fatalf("Unexpected failure: %v", err)
+	}
+
+	n := 0
+	for _, err := range cc.stream(context.Background(), "cmd-stream", "event-stream", nil) {
+		if err != nil {
+			t.Fatalf("Unexpected failure: %v", err)
+		}
+		n++
+	}
+
+	if n != 3 {
+		t.Fatalf("Expected 3 events from stream, got %d", n)
+	}
+
+	// The subscriber must see the events too.
+	for i := range 3 {
+		select {
+		case ev := <-ec:
+			if ev.Name != "event-stream" {
+				t.Fatalf("Unexpected event: %s", ev.Name)
+			}
+		default:
+			t.Fatalf("Expected 3 events for subscriber, got %d", i)
+		}
+	}
+
+	// The stream must not unregister the subscription.
+	if !ts.registered("event-stream") {
+		t.Fatal("Event was unregistered at the end of the stream")
+	}
+
+	if err := cc.unsubscribe(context.Background(), "event-stream"); err != nil {
+		t.Fatalf("Unexpected failure: %v", err)
+	}
+
+	if ts.registered("event-stream") {
+		t.Fatal("Event is still registered after unsubscribe")
+	}
+
+	if len(cc.events.refs) != 0 {
+		t.Fatalf("Expected no event registrations, got %v", cc.events.refs)
+	}
+}
+
+func TestClientConnResponseDropped(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+
+		cc := newClientConn(client)
+		go cc.listen()
+
+		// Pretend that a request was sent, and that the packet chan
+		// is full of abandoned responses.
+		cc.wseq = 1
+		cc.pending.Store(1)
+		for range cap(cc.pc) {
+			cc.pc <- &Message{header: &header{ptype: pktCmdResponse}}
+		}
+
+		ts := newTestServer(server)
+		if err := ts.write(&Message{header: &header{ptype: pktCmdResponse}}); err != nil {
+			t.Fatalf("Unexpected error writing response: %v", err)
+		}
+
+		synctest.Wait()
+		if n := cc.stats.responsesDropped.Load(); n != 1 {
+			t.Fatalf("Expected 1 dropped response, got %d", n)
+		}
+
+		if _, err := cc.wait(context.Background()); !errors.Is(err, ErrResponseDropped) {
+			t.Fatalf("Expected %v, but got %v", ErrResponseDropped, err)
+		}
+	})
+}
+
+func TestClientConnUnsolicitedResponse(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+
+		cc := newClientConn(client)
+		go cc.listen()
+
+		// No request is pending, so this must be ignored.
+		ts := newTestServer(server)
+		if err := ts.write(&Message{header: &header{ptype: pktCmdResponse}}); err != nil {
+			t.Fatalf("Unexpected error writing response: %v", err)
+		}
+
+		synctest.Wait()
+		if n := len(cc.pc); n != 0 {
+			t.Fatalf("Expected unsolicited response to be ignored, got %d packets", n)
+		}
+
+		if cc.rseq != 0 {
+			t.Fatalf("Expected read sequence to be unchanged, got %d", cc.rseq)
+		}
+	})
+}
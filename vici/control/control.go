@@ -0,0 +1,356 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package control provides high-level helpers for the daemon's control
+// commands, which report their progress through control-log events.
+//
+// Initiate initiates a CHILD SA, waits for the outcome, and returns the
+// identifiers of the established SAs, or an InitiateError classifying the
+// failure from the daemon's log messages, e.g. as ErrAuthFailed:
+//
+//	res, err := control.Initiate(ctx, s, "net", "gw", control.WithTimeout(30*time.Second))
+//	if errors.Is(err, control.ErrPeerUnreachable) {
+//		...
+//	}
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Printf("IKE SA %s[%s] established\n", res.IKEName, res.IKEID)
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+)
+
+var (
+	// ErrAuthFailed means the authentication of either peer failed.
+	ErrAuthFailed = errors.New("authentication failed")
+
+	// ErrNoProposalChosen means the peers could not agree on a proposal.
+	ErrNoProposalChosen = errors.New("no proposal chosen")
+
+	// ErrTSUnacceptable means the peers could not agree on traffic
+	// selectors.
+	ErrTSUnacceptable = errors.New("traffic selectors unacceptable")
+
+	// ErrPeerUnreachable means the peer did not respond, or could not be
+	// reached at all.
+	ErrPeerUnreachable = errors.New("peer unreachable")
+
+	// ErrTimeout means the SA was not established within the timeout.
+	ErrTimeout = errors.New("timeout")
+
+	// ErrFailed means the initiation failed for a reason that was not
+	// classified.
+	ErrFailed = errors.New("initiate failed")
+)
+
+// LogLine is a message of a control-log event.
+type LogLine struct {
+	Group   string `vici:"group"`
+	Level   int    `vici:"level"`
+	IKEName string `vici:"ikesa-name"`
+	IKEID   string `vici:"ikesa-uniqueid"`
+	Msg     string `vici:"msg"`
+}
+
+// String formats l like swanctl, e.g. "[IKE] IKE_SA gw[1] established".
+func (l LogLine) String() string {
+	return fmt.Sprintf("[%s] %s", l.Group, l.Msg)
+}
+
+// Result is the result of a successful Initiate.
+type Result struct {
+	// IKEName and IKEID identify the IKE SA, i.e. its connection name and
+	// unique ID.
+	IKEName string
+	IKEID   string
+
+	// ChildName and ChildID identify the CHILD SA. They are empty if the
+	// log did not report the CHILD SA, e.g. with a log level below 1.
+	ChildName string
+	ChildID   string
+
+	// Log holds the log messages of the initiation.
+	Log []LogLine
+}
+
+// InitiateError is returned by Initiate if the initiation failed.
+type InitiateError struct {
+	// Reason classifies the failure. It is one of ErrAuthFailed,
+	// ErrNoProposalChosen, ErrTSUnacceptable, ErrPeerUnreachable, ErrTimeout
+	// and ErrFailed.
+	Reason error
+
+	// Errmsg is the errmsg of the daemon's response, if any.
+	Errmsg string
+
+	// Err is the error of the command request, i.e. the error of the
+	// daemon's response, or e.g. a connection error.
+	Err error
+
+	// Log holds the log messages of the initiation.
+	Log []LogLine
+}
+
+// Error describes the failure, e.g. "initiate failed: authentication failed:
+// establishing CHILD_SA 'net' failed".
+func (e *InitiateError) Error() string {
+	msg := e.Errmsg
+	if msg == "" && e.Err != nil {
+		msg = e.Err.Error()
+	}
+
+	if e.Reason == ErrFailed {
+		return fmt.Sprintf("%v: %s", ErrFailed, msg)
+	}
+
+	return fmt.Sprintf("%v: %v: %s", ErrFailed, e.Reason, msg)
+}
+
+// Unwrap returns the Reason and Err, so errors.Is can be used to test for
+// either.
+func (e *InitiateError) Unwrap() []error {
+	errs := []error{e.Reason}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+
+	return errs
+}
+
+// Option is used to specify options to Initiate.
+type Option interface {
+	apply(*config)
+}
+
+type funcOption struct {
+	f func(*config)
+}
+
+func (fo *funcOption) apply(c *config) {
+	fo.f(c)
+}
+
+// WithTimeout specifies how long the daemon waits for the SA to be
+// established. If this option is not specified, the daemon waits until the
+// initiation completes or fails, which may take several minutes if the peer
+// does not respond.
+func WithTimeout(timeout time.Duration) Option {
+	return &funcOption{func(c *config) {
+		c.timeout = timeout
+	}}
+}
+
+// WithLogLevel specifies the level of the log messages collected. If this
+// option is not specified, level 1 is used, which includes the messages used
+// to classify failures and to identify the established SAs.
+func WithLogLevel(level int) Option {
+	return &funcOption{func(c *config) {
+		c.loglevel = level
+	}}
+}
+
+// WithInitLimits specifies whether the daemon's limits for half-open and
+// initiating IKE SAs apply. If this option is not specified, they do not.
+func WithInitLimits(limits bool) Option {
+	return &funcOption{func(c *config) {
+		c.initLimits = limits
+	}}
+}
+
+type config struct {
+	timeout    time.Duration
+	loglevel   int
+	initLimits bool
+}
+
+// Initiate initiates the CHILD SA child of the connection ike, and waits for
+// the outcome. Either child or ike may be empty, in which case the daemon
+// looks up the other, or initiates only the IKE SA, respectively.
+//
+// If the initiation fails, an *InitiateError is returned, which classifies
+// the failure. If the session fails, or ctx is done, the InitiateError wraps
+// the error, and ctx's deadline passing is classified as ErrTimeout.
+func Initiate(ctx context.Context, s *vici.Session, child, ike string, opts ...Option) (*Result, error) {
+	c := &config{loglevel: 1}
+
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+
+	b := vici.Build()
+	if child != "" {
+		b.Set("child", child)
+	}
+	if ike != "" {
+		b.Set("ike", ike)
+	}
+
+	in, err := b.
+		Set("timeout", c.timeout.Milliseconds()).
+		Set("init-limits", c.initLimits).
+		Set("loglevel", c.loglevel).
+		Message()
+	if err != nil {
+		return nil, err
+	}
+
+	var log []LogLine
+
+	for m, err := range s.CallStreaming(ctx, "initiate", "control-log", in) {
+		if err != nil {
+			return nil, failure(ctx, m, err, log)
+		}
+
+		var line LogLine
+		if err := vici.UnmarshalMessage(m, &line); err != nil {
+			return nil, fmt.Errorf("invalid control-log event: %w", err)
+		}
+
+		log = append(log, line)
+	}
+
+	res := &Result{Log: log}
+	established(res, log)
+
+	return res, nil
+}
+
+var (
+	ikeEstablished   = regexp.MustCompile(`IKE_SA (\S+)\[(\d+)\] established`)
+	childEstablished = regexp.MustCompile(`CHILD_SA (\S+)\{(\d+)\} established`)
+)
+
+// established sets the identifiers of the established SAs in res from the
+// log messages announcing them.
+func established(res *Result, log []LogLine) {
+	for _, l := range log {
+		if m := ikeEstablished.FindStringSubmatch(l.Msg); m != nil {
+			res.IKEName, res.IKEID = m[1], m[2]
+		}
+
+		if m := childEstablished.FindStringSubmatch(l.Msg); m != nil {
+			res.ChildName, res.ChildID = m[1], m[2]
+		}
+	}
+
+	// The IKE SA is not announced if it was established before, but the
+	// log messages still refer to it.
+	if res.IKEID == "" {
+		for _, l := range log {
+			if l.IKEID != "" {
+				res.IKEName, res.IKEID = l.IKEName, l.IKEID
+			}
+		}
+	}
+}
+
+// failure returns the InitiateError of a failed initiation.
+func failure(ctx context.Context, out *vici.Message, err error, log []LogLine) error {
+	e := &InitiateError{
+		Err: err,
+		Log: log,
+	}
+
+	if out != nil {
+		e.Errmsg, _ = out.Get("errmsg").(string)
+	}
+
+	e.Reason = classify(log)
+
+	if e.Reason == nil {
+		switch {
+		case strings.Contains(e.Errmsg, "not established after"):
+			e.Reason = ErrTimeout
+		case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) ||
+			errors.Is(ctx.Err(), context.DeadlineExceeded):
+			e.Reason = ErrTimeout
+		default:
+			e.Reason = ErrFailed
+		}
+	}
+
+	return e
+}
+
+// classifiers map fragments of log messages to the failure they indicate.
+// Fragments are matched case-insensitively, in order.
+var classifiers = []struct {
+	fragment string
+	reason   error
+}{
+	{"authentication_failed", ErrAuthFailed},
+	{"authentication of", ErrAuthFailed},
+	{"mac mismatched", ErrAuthFailed},
+	{"no trusted", ErrAuthFailed},
+	{"no shared key found", ErrAuthFailed},
+	{"no private key found", ErrAuthFailed},
+	{"eap_failure", ErrAuthFailed},
+	{"constraint check failed", ErrAuthFailed},
+	{"no_proposal_chosen", ErrNoProposalChosen},
+	{"no acceptable proposal", ErrNoProposalChosen},
+	{"ts_unacceptable", ErrTSUnacceptable},
+	{"traffic selectors", ErrTSUnacceptable},
+	{"giving up after", ErrPeerUnreachable},
+	{"peer not responding", ErrPeerUnreachable},
+	{"unable to resolve", ErrPeerUnreachable},
+	{"no route", ErrPeerUnreachable},
+	{"unreachable", ErrPeerUnreachable},
+	{"error writing to socket", ErrPeerUnreachable},
+}
+
+// classify returns the failure indicated by the log messages, or nil. The
+// first message indicating a failure determines it, as later messages tend
+// to describe consequences, e.g. the deletion of the SA.
+func classify(log []LogLine) error {
+	for _, l := range log {
+		msg := strings.ToLower(l.Msg)
+
+		for _, c := range classifiers {
+			if !strings.Contains(msg, c.fragment) {
+				continue
+			}
+
+			// "authentication of 'moon' with RSA signature successful"
+			// is not a failure.
+			if c.fragment == "authentication of" && !strings.Contains(msg, "failed") {
+				continue
+			}
+
+			// "selected proposal" lines may mention traffic selectors.
+			if c.fragment == "traffic selectors" && !strings.Contains(msg, "unacceptable") {
+				continue
+			}
+
+			return c.reason
+		}
+	}
+
+	return nil
+}
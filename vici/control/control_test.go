@@ -0,0 +1,265 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package control_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/control"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+// newSession returns a session to a daemon which answers initiate requests by
+// emitting the log messages of the requested child, and failing with its
+// errmsg, if any.
+func newSession(t *testing.T, logs map[string][]string, errmsgs map[string]string) (*vici.Session, <-chan *vici.Message) {
+	t.Helper()
+
+	srv := vicitest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	reqs := make(chan *vici.Message, 16)
+
+	srv.Handle("initiate", func(req *vicitest.Request) (*vici.Message, error) {
+		reqs <- req.Message
+
+		child, _ := req.Message.Get("child").(string)
+
+		for _, msg := range logs[child] {
+			m, err := vici.Build().
+				Set("group", "IKE").
+				Set("level", 1).
+				Set("ikesa-name", "gw").
+				Set("ikesa-uniqueid", "1").
+				Set("msg", msg).
+				Message()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := req.Emit("control-log", m); err != nil {
+				return nil, err
+			}
+		}
+
+		if errmsg, ok := errmsgs[child]; ok {
+			return nil, errors.New(errmsg)
+		}
+
+		return vici.NewMessage(), nil
+	})
+
+	s, err := vici.NewSession(vici.WithDialContext(srv.DialContext))
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s, reqs
+}
+
+func TestInitiate(t *testing.T) {
+	s, reqs := newSession(t, map[string][]string{
+		"net": {
+			"initiating IKE_SA gw[1] to 192.0.2.1",
+			"authentication of 'moon.strongswan.org' with ECDSA_WITH_SHA256_DER successful",
+			"IKE_SA gw[1] established between 192.0.2.2[sun.strongswan.org]...192.0.2.1[moon.strongswan.org]",
+			"CHILD_SA net{3} established with SPIs c1e2b5a1_i cc2a5d4c_o and TS 10.2.0.0/16 === 10.1.0.0/16",
+		},
+	}, nil)
+
+	res, err := control.Initiate(context.Background(), s, "net", "gw", control.WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if res.IKEName != "gw" || res.IKEID != "1" || res.ChildName != "net" || res.ChildID != "3" {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+
+	if len(res.Log) != 4 {
+		t.Fatalf("Expected 4 log lines, got %d", len(res.Log))
+	}
+
+	if want := "[IKE] initiating IKE_SA gw[1] to 192.0.2.1"; res.Log[0].String() != want {
+		t.Errorf("Expected %q, got %q", want, res.Log[0])
+	}
+
+	req := <-reqs
+	for key, want := range map[string]string{"child": "net", "ike": "gw", "timeout": "5000", "init-limits": "no", "loglevel": "1"} {
+		if got := req.Get(key); got != want {
+			t.Errorf("Expected %s=%s in request, got %v", key, want, got)
+		}
+	}
+}
+
+func TestInitiateExistingIKESA(t *testing.T) {
+	s, _ := newSession(t, map[string][]string{
+		"net": {"CHILD_SA net{7} established with SPIs c1e2b5a1_i cc2a5d4c_o and TS 10.2.0.0/16 === 10.1.0.0/16"},
+	}, nil)
+
+	res, err := control.Initiate(context.Background(), s, "net", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The IKE SA is taken from the log message's IKE SA.
+	if res.IKEName != "gw" || res.IKEID != "1" || res.ChildID != "7" {
+		t.Errorf("Unexpected result: %+v", res)
+	}
+}
+
+func TestInitiateFailures(t *testing.T) {
+	logs := map[string][]string{
+		"auth": {
+			"initiating IKE_SA gw[1] to 192.0.2.1",
+			"received AUTHENTICATION_FAILED notify error",
+		},
+		"psk": {
+			"tried 1 shared key for 'sun' - 'moon', but MAC mismatched",
+			"sending AUTHENTICATION_FAILED notify",
+		},
+		"proposal": {
+			"authentication of 'moon.strongswan.org' with pre-shared key successful",
+			"received NO_PROPOSAL_CHOSEN notify error",
+		},
+		"ts": {
+			"received TS_UNACCEPTABLE notify, no CHILD_SA built",
+		},
+		"unreachable": {
+			"retransmit 5 of request with message ID 0",
+			"giving up after 5 retransmits",
+			"establishing IKE_SA failed, peer not responding",
+		},
+		"timeout": {
+			"retransmit 1 of request with message ID 0",
+		},
+	}
+
+	errmsgs := map[string]string{
+		"auth":        "establishing CHILD_SA 'auth' failed",
+		"psk":         "establishing CHILD_SA 'psk' failed",
+		"proposal":    "establishing CHILD_SA 'proposal' failed",
+		"ts":          "establishing CHILD_SA 'ts' failed",
+		"unreachable": "establishing CHILD_SA 'unreachable' failed",
+		"timeout":     "CHILD_SA 'timeout' not established after 5000ms",
+		"unknown":     "CHILD_SA config 'unknown' not found",
+	}
+
+	s, _ := newSession(t, logs, errmsgs)
+
+	for _, tt := range []struct {
+		child  string
+		reason error
+	}{
+		{child: "auth", reason: control.ErrAuthFailed},
+		{child: "psk", reason: control.ErrAuthFailed},
+		{child: "proposal", reason: control.ErrNoProposalChosen},
+		{child: "ts", reason: control.ErrTSUnacceptable},
+		{child: "unreachable", reason: control.ErrPeerUnreachable},
+		{child: "timeout", reason: control.ErrTimeout},
+		{child: "unknown", reason: control.ErrFailed},
+	} {
+		t.Run(tt.child, func(t *testing.T) {
+			res, err := control.Initiate(context.Background(), s, tt.child, "")
+			if res != nil {
+				t.Errorf("Expected nil result, got %+v", res)
+			}
+
+			var ie *control.InitiateError
+			if !errors.As(err, &ie) {
+				t.Fatalf("Expected InitiateError, got %v", err)
+			}
+
+			if !errors.Is(err, tt.reason) || ie.Reason != tt.reason {
+				t.Errorf("Expected reason %v, got %v", tt.reason, ie.Reason)
+			}
+
+			if ie.Errmsg != errmsgs[tt.child] {
+				t.Errorf("Expected errmsg %q, got %q", errmsgs[tt.child], ie.Errmsg)
+			}
+
+			if len(ie.Log) != len(logs[tt.child]) {
+				t.Errorf("Expected %d log lines, got %d", len(logs[tt.child]), len(ie.Log))
+			}
+		})
+	}
+}
+
+func TestInitiateErrorString(t *testing.T) {
+	err := &control.InitiateError{
+		Reason: control.ErrAuthFailed,
+		Errmsg: "establishing CHILD_SA 'net' failed",
+	}
+
+	if want := "initiate failed: authentication failed: establishing CHILD_SA 'net' failed"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+
+	err = &control.InitiateError{
+		Reason: control.ErrFailed,
+		Errmsg: "CHILD_SA config 'net' not found",
+	}
+
+	if want := "initiate failed: CHILD_SA config 'net' not found"; err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestInitiateContextDeadline(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	srv.Handle("initiate", func(_ *vicitest.Request) (*vici.Message, error) {
+		<-release
+
+		return vici.NewMessage(), nil
+	})
+
+	s, err := vici.NewSession(vici.WithDialContext(srv.DialContext))
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err = control.Initiate(ctx, s, "net", "")
+	if !errors.Is(err, control.ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+
+	// Initiate must not wait for the daemon, which does not answer while
+	// the initiate request is pending, once ctx is done.
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Expected Initiate to return promptly after the deadline, took %v", d)
+	}
+}
@@ -35,7 +35,9 @@
 // analogous to the message you need to send or receive in a command, and using
 // the MarshalMessage and UnmarshalMessage functions with that struct. There
 // are however, Get, Set, and Unset methods on the Message type for simpler
-// messages.
+// messages, and the Build function returns a Builder for constructing nested
+// messages with a chain of method calls. Message.Walk and Message.All traverse
+// the contents of nested messages.
 //
 // In order to use this package, it is important to have a basic understanding
 // of the VICI protocol as desribed in the link above. The 'Client-initiated
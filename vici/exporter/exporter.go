@@ -0,0 +1,522 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package exporter provides a Prometheus collector for the statistics of a
+// charon daemon, and its IKE and CHILD SAs.
+//
+// The metrics are gathered from the stats, list-sas and get-counters commands.
+// Rather than querying the daemon on each scrape, the Exporter keeps a snapshot
+// of the metrics, which is updated by Exporter.Refresh. Exporter.Run refreshes
+// the snapshot periodically, and whenever an IKE or CHILD SA goes up or down:
+//
+//	e := exporter.New(s)
+//	prometheus.MustRegister(e)
+//
+//	go e.Run(ctx)
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/strongswan/govici/vici"
+)
+
+const namespace = "vici"
+
+var (
+	upDesc = prometheus.NewDesc(namespace+"_up",
+		"Whether the last refresh of the metrics from the daemon succeeded.", nil, nil)
+
+	uptimeDesc = prometheus.NewDesc(namespace+"_uptime_seconds",
+		"Time since the daemon started.", nil, nil)
+
+	workersDesc = prometheus.NewDesc(namespace+"_workers",
+		"Number of worker threads.", nil, nil)
+	workersIdleDesc = prometheus.NewDesc(namespace+"_workers_idle",
+		"Number of idle worker threads.", nil, nil)
+	workersActiveDesc = prometheus.NewDesc(namespace+"_workers_active",
+		"Number of worker threads processing jobs, by job priority.", []string{"priority"}, nil)
+
+	queuedJobsDesc = prometheus.NewDesc(namespace+"_queued_jobs",
+		"Number of queued jobs, by job priority.", []string{"priority"}, nil)
+	scheduledJobsDesc = prometheus.NewDesc(namespace+"_scheduled_jobs",
+		"Number of scheduled jobs.", nil, nil)
+
+	ikeSAsHalfOpenDesc = prometheus.NewDesc(namespace+"_ike_sas_half_open",
+		"Number of half-open IKE SAs.", nil, nil)
+	ikeSAsDesc = prometheus.NewDesc(namespace+"_ike_sas",
+		"Number of IKE SAs, by state.", []string{"state"}, nil)
+
+	ikeSALabels = []string{"ike", "ike_id"}
+
+	ikeSAEstablishedDesc = prometheus.NewDesc(namespace+"_ike_sa_established_seconds",
+		"Time since the IKE SA was established.", ikeSALabels, nil)
+	ikeSARekeyDesc = prometheus.NewDesc(namespace+"_ike_sa_rekey_seconds",
+		"Time until the IKE SA is rekeyed.", ikeSALabels, nil)
+	ikeSAReauthDesc = prometheus.NewDesc(namespace+"_ike_sa_reauth_seconds",
+		"Time until the IKE SA is reauthenticated.", ikeSALabels, nil)
+
+	childSALabels = []string{"ike", "ike_id", "child", "child_id"}
+
+	childSABytesInDesc = prometheus.NewDesc(namespace+"_child_sa_in_bytes_total",
+		"Number of bytes received by the CHILD SA.", childSALabels, nil)
+	childSABytesOutDesc = prometheus.NewDesc(namespace+"_child_sa_out_bytes_total",
+		"Number of bytes sent by the CHILD SA.", childSALabels, nil)
+	childSAPacketsInDesc = prometheus.NewDesc(namespace+"_child_sa_in_packets_total",
+		"Number of packets received by the CHILD SA.", childSALabels, nil)
+	childSAPacketsOutDesc = prometheus.NewDesc(namespace+"_child_sa_out_packets_total",
+		"Number of packets sent by the CHILD SA.", childSALabels, nil)
+	childSARekeyDesc = prometheus.NewDesc(namespace+"_child_sa_rekey_seconds",
+		"Time until the CHILD SA is rekeyed.", childSALabels, nil)
+	childSALifetimeDesc = prometheus.NewDesc(namespace+"_child_sa_lifetime_seconds",
+		"Time until the CHILD SA expires.", childSALabels, nil)
+
+	ikeEventsDesc = prometheus.NewDesc(namespace+"_ike_events_total",
+		"Number of IKE events from the counters plugin, globally (conn=\"\") or by connection.",
+		[]string{"conn", "event"}, nil)
+)
+
+var allDescs = []*prometheus.Desc{
+	upDesc,
+	uptimeDesc,
+	workersDesc,
+	workersIdleDesc,
+	workersActiveDesc,
+	queuedJobsDesc,
+	scheduledJobsDesc,
+	ikeSAsHalfOpenDesc,
+	ikeSAsDesc,
+	ikeSAEstablishedDesc,
+	ikeSARekeyDesc,
+	ikeSAReauthDesc,
+	childSABytesInDesc,
+	childSABytesOutDesc,
+	childSAPacketsInDesc,
+	childSAPacketsOutDesc,
+	childSARekeyDesc,
+	childSALifetimeDesc,
+	ikeEventsDesc,
+}
+
+// Response of the stats command.
+type stats struct {
+	Uptime struct {
+		Running string `vici:"running"`
+		Since   string `vici:"since"`
+	} `vici:"uptime"`
+
+	Workers struct {
+		Total  uint64            `vici:"total"`
+		Idle   uint64            `vici:"idle"`
+		Active map[string]uint64 `vici:"active"`
+	} `vici:"workers"`
+
+	Queues    map[string]uint64 `vici:"queues"`
+	Scheduled uint64            `vici:"scheduled"`
+
+	IKESAs struct {
+		Total    uint64 `vici:"total"`
+		HalfOpen uint64 `vici:"half-open"`
+	} `vici:"ikesas"`
+}
+
+// An IKE SA from a list-sa event.
+type ikeSA struct {
+	UniqueID    string             `vici:"uniqueid"`
+	State       string             `vici:"state"`
+	Established *uint64            `vici:"established"`
+	RekeyTime   *uint64            `vici:"rekey-time"`
+	ReauthTime  *uint64            `vici:"reauth-time"`
+	ChildSAs    map[string]childSA `vici:"child-sas"`
+}
+
+// A CHILD SA from a list-sa event.
+type childSA struct {
+	Name       string  `vici:"name"`
+	UniqueID   string  `vici:"uniqueid"`
+	BytesIn    uint64  `vici:"bytes-in"`
+	BytesOut   uint64  `vici:"bytes-out"`
+	PacketsIn  uint64  `vici:"packets-in"`
+	PacketsOut uint64  `vici:"packets-out"`
+	RekeyTime  *uint64 `vici:"rekey-time"`
+	LifeTime   *uint64 `vici:"life-time"`
+}
+
+// Response of the get-counters command.
+type counters struct {
+	Counters map[string]map[string]uint64 `vici:"counters"`
+}
+
+// Option is used to specify options to New.
+type Option interface {
+	apply(*Exporter)
+}
+
+type funcOption struct {
+	f func(*Exporter)
+}
+
+func (fo *funcOption) apply(e *Exporter) {
+	fo.f(e)
+}
+
+// WithInterval specifies how often Exporter.Run refreshes the metrics. If this
+// option is not specified, the metrics are refreshed every 30 seconds.
+func WithInterval(interval time.Duration) Option {
+	return &funcOption{func(e *Exporter) {
+		e.interval = interval
+	}}
+}
+
+// WithoutCounters disables the get-counters command, e.g. if the counters
+// plugin is not loaded by the daemon.
+func WithoutCounters() Option {
+	return &funcOption{func(e *Exporter) {
+		e.counters = false
+	}}
+}
+
+// WithLogger specifies the logger used to report failed refreshes from
+// Exporter.Run. If this option is not specified, nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return &funcOption{func(e *Exporter) {
+		e.logger = logger
+	}}
+}
+
+// Exporter is a prometheus.Collector for the statistics of a charon daemon.
+type Exporter struct {
+	s *vici.Session
+
+	interval time.Duration
+	counters bool
+	logger   *slog.Logger
+
+	// Returns the current time, for uptime calculation.
+	now func() time.Time
+
+	mu      sync.Mutex
+	metrics []prometheus.Metric
+}
+
+// New returns an Exporter which queries the daemon using s. The metrics are
+// empty, apart from vici_up, until the first refresh.
+func New(s *vici.Session, opts ...Option) *Exporter {
+	e := &Exporter{
+		s:        s,
+		interval: 30 * time.Second,
+		counters: true,
+		logger:   slog.New(slog.DiscardHandler),
+		now:      time.Now,
+	}
+
+	for _, opt := range opts {
+		opt.apply(e)
+	}
+
+	e.metrics = []prometheus.Metric{
+		prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0),
+	}
+
+	return e
+}
+
+// Describe implements prometheus.Collector.
+func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range allDescs {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector. It collects the metrics from the last
+// refresh.
+func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.Lock()
+	metrics := e.metrics
+	e.mu.Unlock()
+
+	for _, m := range metrics {
+		ch <- m
+	}
+}
+
+// Refresh queries the daemon, and replaces the collected metrics with the results.
+// If the stats or list-sas command fails, only vici_up is collected until the next
+// successful refresh. If only get-counters fails, the error is returned, but the
+// other metrics are still updated.
+func (e *Exporter) Refresh(ctx context.Context) error {
+	metrics, err := e.gather(ctx)
+	if metrics == nil {
+		metrics = []prometheus.Metric{
+			prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0),
+		}
+	}
+
+	e.mu.Lock()
+	e.metrics = metrics
+	e.mu.Unlock()
+
+	return err
+}
+
+// Run refreshes the metrics periodically, and whenever an ike-updown or child-updown
+// event is received, until ctx is done or the session ends. Run subscribes the session
+// to these events for its duration. Events that the session is already subscribed to
+// when Run starts are left subscribed when it returns.
+func (e *Exporter) Run(ctx context.Context) error {
+	ec := make(chan vici.Event, 16)
+	e.s.NotifyEvents(ec)
+	defer e.s.StopEvents(ec)
+
+	subscribed := e.s.Subscriptions()
+	events := slices.DeleteFunc([]string{"ike-updown", "child-updown"}, func(event string) bool {
+		return slices.Contains(subscribed, event)
+	})
+
+	if len(events) > 0 {
+		if err := e.s.SubscribeContext(ctx, events...); err != nil {
+			return err
+		}
+		defer func() {
+			// nolint
+			_ = e.s.UnsubscribeContext(context.WithoutCancel(ctx), events...)
+		}()
+	}
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Refresh(ctx); err != nil {
+			e.logger.LogAttrs(ctx, slog.LevelWarn, "failed to refresh metrics", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-e.s.Done():
+			return e.s.Err()
+
+		case <-ticker.C:
+
+		case _, ok := <-ec:
+			if !ok {
+				return e.s.Err()
+			}
+
+			// SAs often go up or down in bursts, refresh only
+			// once for all events received so far.
+			drain(ec)
+		}
+	}
+}
+
+func drain(ec <-chan vici.Event) {
+	for {
+		select {
+		case _, ok := <-ec:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (e *Exporter) gather(ctx context.Context) ([]prometheus.Metric, error) {
+	var metrics []prometheus.Metric
+
+	add := func(desc *prometheus.Desc, vt prometheus.ValueType, v float64, labels ...string) {
+		metrics = append(metrics, prometheus.MustNewConstMetric(desc, vt, v, labels...))
+	}
+
+	out, err := e.s.Call(ctx, "stats", nil)
+	if err != nil {
+		return nil, fmt.Errorf("stats: %w", err)
+	}
+
+	var st stats
+	if err := vici.UnmarshalMessage(out, &st); err != nil {
+		return nil, fmt.Errorf("stats: %w", err)
+	}
+
+	if uptime, ok := e.uptime(st.Uptime.Since, st.Uptime.Running); ok {
+		add(uptimeDesc, prometheus.GaugeValue, uptime.Seconds())
+	}
+
+	add(workersDesc, prometheus.GaugeValue, float64(st.Workers.Total))
+	add(workersIdleDesc, prometheus.GaugeValue, float64(st.Workers.Idle))
+	for priority, n := range st.Workers.Active {
+		add(workersActiveDesc, prometheus.GaugeValue, float64(n), priority)
+	}
+	for priority, n := range st.Queues {
+		add(queuedJobsDesc, prometheus.GaugeValue, float64(n), priority)
+	}
+	add(scheduledJobsDesc, prometheus.GaugeValue, float64(st.Scheduled))
+	add(ikeSAsHalfOpenDesc, prometheus.GaugeValue, float64(st.IKESAs.HalfOpen))
+
+	// Collect the SAs first, as the session must not be used while
+	// iterating over the stream.
+	var sas []*vici.Message
+
+	for m, err := range e.s.CallStreaming(ctx, "list-sas", "list-sa", nil) {
+		if err != nil {
+			return nil, fmt.Errorf("list-sas: %w", err)
+		}
+		sas = append(sas, m)
+	}
+
+	states := make(map[string]int)
+
+	for _, m := range sas {
+		// Each list-sa event contains a single IKE SA, in a section
+		// named after its config.
+		for _, name := range m.Keys() {
+			sec, ok := m.Get(name).(*vici.Message)
+			if !ok {
+				continue
+			}
+
+			var ike ikeSA
+			if err := vici.UnmarshalMessage(sec, &ike); err != nil {
+				return nil, fmt.Errorf("list-sas: %w", err)
+			}
+
+			states[ike.State]++
+
+			labels := []string{name, ike.UniqueID}
+
+			if ike.Established != nil {
+				add(ikeSAEstablishedDesc, prometheus.GaugeValue, float64(*ike.Established), labels...)
+			}
+			if ike.RekeyTime != nil {
+				add(ikeSARekeyDesc, prometheus.GaugeValue, float64(*ike.RekeyTime), labels...)
+			}
+			if ike.ReauthTime != nil {
+				add(ikeSAReauthDesc, prometheus.GaugeValue, float64(*ike.ReauthTime), labels...)
+			}
+
+			for _, child := range ike.ChildSAs {
+				labels := []string{name, ike.UniqueID, child.Name, child.UniqueID}
+
+				add(childSABytesInDesc, prometheus.CounterValue, float64(child.BytesIn), labels...)
+				add(childSABytesOutDesc, prometheus.CounterValue, float64(child.BytesOut), labels...)
+				add(childSAPacketsInDesc, prometheus.CounterValue, float64(child.PacketsIn), labels...)
+				add(childSAPacketsOutDesc, prometheus.CounterValue, float64(child.PacketsOut), labels...)
+
+				if child.RekeyTime != nil {
+					add(childSARekeyDesc, prometheus.GaugeValue, float64(*child.RekeyTime), labels...)
+				}
+				if child.LifeTime != nil {
+					add(childSALifetimeDesc, prometheus.GaugeValue, float64(*child.LifeTime), labels...)
+				}
+			}
+		}
+	}
+
+	for state, n := range states {
+		add(ikeSAsDesc, prometheus.GaugeValue, float64(n), state)
+	}
+
+	var errs []error
+
+	if e.counters {
+		if err := e.gatherCounters(ctx, add); err != nil {
+			// Do not fail the whole refresh, the counters plugin
+			// is optional.
+			errs = append(errs, fmt.Errorf("get-counters: %w", err))
+		}
+	}
+
+	add(upDesc, prometheus.GaugeValue, 1)
+
+	return metrics, errors.Join(errs...)
+}
+
+func (e *Exporter) gatherCounters(ctx context.Context, add func(*prometheus.Desc, prometheus.ValueType, float64, ...string)) error {
+	in, err := vici.Build().Set("all", true).Message()
+	if err != nil {
+		return err
+	}
+
+	out, err := e.s.Call(ctx, "get-counters", in)
+	if err != nil {
+		return err
+	}
+
+	var c counters
+	if err := vici.UnmarshalMessage(out, &c); err != nil {
+		return err
+	}
+
+	for conn, values := range c.Counters {
+		for counter, v := range values {
+			add(ikeEventsDesc, prometheus.CounterValue, float64(v), conn, counter)
+		}
+	}
+
+	return nil
+}
+
+// uptime returns the daemon uptime. The daemon reports the start time, e.g.
+// "Mar 02 10:20:30 2026", and the time it is running in a coarse, human readable
+// form, e.g. "5 minutes", which is only used as a fallback.
+func (e *Exporter) uptime(since, running string) (time.Duration, bool) {
+	for _, layout := range []string{"Jan 02 15:04:05 2006", "Jan _2 15:04:05 2006"} {
+		t, err := time.ParseInLocation(layout, since, time.Local)
+		if err == nil {
+			return e.now().Sub(t), true
+		}
+	}
+
+	value, unit, ok := strings.Cut(running, " ")
+	if !ok {
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	switch strings.TrimSuffix(unit, "s") {
+	case "second":
+		return time.Duration(n) * time.Second, true
+	case "minute":
+		return time.Duration(n) * time.Minute, true
+	case "hour":
+		return time.Duration(n) * time.Hour, true
+	case "day":
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,395 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+func newTestServer(t *testing.T) *vicitest.Server {
+	t.Helper()
+
+	srv := vicitest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	srv.Handle("stats", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().
+			Section("uptime").Set("running", "5 minutes").Set("since", "Mar 02 10:20:30 2026").End().
+			Section("workers").Set("total", 16).Set("idle", 11).
+			Section("active").Set("critical", 4).Set("high", 0).Set("medium", 1).Set("low", 0).End().
+			End().
+			Section("queues").Set("critical", 0).Set("high", 0).Set("medium", 2).Set("low", 0).End().
+			Set("scheduled", 7).
+			Section("ikesas").Set("total", 2).Set("half-open", 1).End().
+			Message()
+	})
+
+	srv.Handle("list-sas", func(req *vicitest.Request) (*vici.Message, error) {
+		gw1, err := vici.Build().
+			Section("gw-1").
+			Set("uniqueid", 1).
+			Set("state", "ESTABLISHED").
+			Set("established", 120).
+			Set("rekey-time", 3000).
+			Section("child-sas").
+			Section("net-1").
+			Set("name", "net").
+			Set("uniqueid", 3).
+			Set("bytes-in", 1024).
+			Set("packets-in", 8).
+			Set("bytes-out", 2048).
+			Set("packets-out", 16).
+			Set("rekey-time", 600).
+			Set("life-time", 900).
+			End().
+			End().
+			End().
+			Message()
+		if err != nil {
+			return nil, err
+		}
+
+		gw2, err := vici.Build().
+			Section("gw-2").
+			Set("uniqueid", 2).
+			Set("state", "CONNECTING").
+			End().
+			Message()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range []*vici.Message{gw1, gw2} {
+			if err := req.Emit("list-sa", m); err != nil {
+				return nil, err
+			}
+		}
+
+		return vici.NewMessage(), nil
+	})
+
+	srv.Handle("get-counters", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().
+			Section("counters").
+			Section("gw-2").Set("ike-rekey-init", 3).End().
+			Section("gw-1").Set("ike-rekey-init", 1).End().
+			End().
+			Set("success", "yes").
+			Message()
+	})
+
+	return srv
+}
+
+func newTestExporter(t *testing.T, srv *vicitest.Server, opts ...Option) *Exporter {
+	t.Helper()
+
+	s, err := vici.NewSession(vici.WithDialContext(srv.DialContext))
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	e := New(s, opts...)
+	e.now = func() time.Time {
+		return time.Date(2026, time.March, 2, 11, 20, 30, 0, time.Local)
+	}
+
+	return e
+}
+
+func TestExporterRefresh(t *testing.T) {
+	e := newTestExporter(t, newTestServer(t))
+
+	if err := e.Refresh(context.Background()); err != nil {
+		t.Fatalf("Unexpected error refreshing metrics: %v", err)
+	}
+
+	expected := `
+# HELP vici_up Whether the last refresh of the metrics from the daemon succeeded.
+# TYPE vici_up gauge
+vici_up 1
+# HELP vici_uptime_seconds Time since the daemon started.
+# TYPE vici_uptime_seconds gauge
+vici_uptime_seconds 3600
+# HELP vici_workers Number of worker threads.
+# TYPE vici_workers gauge
+vici_workers 16
+# HELP vici_workers_active Number of worker threads processing jobs, by job priority.
+# TYPE vici_workers_active gauge
+vici_workers_active{priority="critical"} 4
+vici_workers_active{priority="high"} 0
+vici_workers_active{priority="low"} 0
+vici_workers_active{priority="medium"} 1
+# HELP vici_queued_jobs Number of queued jobs, by job priority.
+# TYPE vici_queued_jobs gauge
+vici_queued_jobs{priority="critical"} 0
+vici_queued_jobs{priority="high"} 0
+vici_queued_jobs{priority="low"} 0
+vici_queued_jobs{priority="medium"} 2
+# HELP vici_ike_sas Number of IKE SAs, by state.
+# TYPE vici_ike_sas gauge
+vici_ike_sas{state="CONNECTING"} 1
+vici_ike_sas{state="ESTABLISHED"} 1
+# HELP vici_ike_sa_established_seconds Time since the IKE SA was established.
+# TYPE vici_ike_sa_established_seconds gauge
+vici_ike_sa_established_seconds{ike="gw-1",ike_id="1"} 120
+# HELP vici_ike_sa_rekey_seconds Time until the IKE SA is rekeyed.
+# TYPE vici_ike_sa_rekey_seconds gauge
+vici_ike_sa_rekey_seconds{ike="gw-1",ike_id="1"} 3000
+# HELP vici_child_sa_in_bytes_total Number of bytes received by the CHILD SA.
+# TYPE vici_child_sa_in_bytes_total counter
+vici_child_sa_in_bytes_total{child="net",child_id="3",ike="gw-1",ike_id="1"} 1024
+# HELP vici_child_sa_out_packets_total Number of packets sent by the CHILD SA.
+# TYPE vici_child_sa_out_packets_total counter
+vici_child_sa_out_packets_total{child="net",child_id="3",ike="gw-1",ike_id="1"} 16
+# HELP vici_child_sa_rekey_seconds Time until the CHILD SA is rekeyed.
+# TYPE vici_child_sa_rekey_seconds gauge
+vici_child_sa_rekey_seconds{child="net",child_id="3",ike="gw-1",ike_id="1"} 600
+# HELP vici_child_sa_lifetime_seconds Time until the CHILD SA expires.
+# TYPE vici_child_sa_lifetime_seconds gauge
+vici_child_sa_lifetime_seconds{child="net",child_id="3",ike="gw-1",ike_id="1"} 900
+# HELP vici_ike_events_total Number of IKE events from the counters plugin, globally (conn="") or by connection.
+# TYPE vici_ike_events_total counter
+vici_ike_events_total{conn="gw-1",event="ike-rekey-init"} 1
+vici_ike_events_total{conn="gw-2",event="ike-rekey-init"} 3
+`
+
+	names := []string{
+		"vici_up",
+		"vici_uptime_seconds",
+		"vici_workers",
+		"vici_workers_active",
+		"vici_queued_jobs",
+		"vici_ike_sas",
+		"vici_ike_sa_established_seconds",
+		"vici_ike_sa_rekey_seconds",
+		"vici_child_sa_in_bytes_total",
+		"vici_child_sa_out_packets_total",
+		"vici_child_sa_rekey_seconds",
+		"vici_child_sa_lifetime_seconds",
+		"vici_ike_events_total",
+	}
+
+	if err := testutil.CollectAndCompare(e, strings.NewReader(expected), names...); err != nil {
+		t.Fatalf("Unexpected metrics: %v", err)
+	}
+}
+
+func TestExporterLint(t *testing.T) {
+	e := newTestExporter(t, newTestServer(t))
+
+	if err := e.Refresh(context.Background()); err != nil {
+		t.Fatalf("Unexpected error refreshing metrics: %v", err)
+	}
+
+	problems, err := testutil.CollectAndLint(e)
+	if err != nil {
+		t.Fatalf("Unexpected error linting metrics: %v", err)
+	}
+
+	for _, p := range problems {
+		t.Errorf("Lint problem with %s: %s", p.Metric, p.Text)
+	}
+}
+
+func TestExporterRefreshError(t *testing.T) {
+	srv := newTestServer(t)
+	e := newTestExporter(t, srv)
+
+	if err := e.Refresh(context.Background()); err != nil {
+		t.Fatalf("Unexpected error refreshing metrics: %v", err)
+	}
+
+	srv.Handle("stats", func(_ *vicitest.Request) (*vici.Message, error) {
+		return nil, errors.New("stats unavailable")
+	})
+
+	if err := e.Refresh(context.Background()); err == nil {
+		t.Fatalf("Expected error refreshing metrics")
+	}
+
+	// Only vici_up should be left, and report the failure.
+	if n := testutil.CollectAndCount(e); n != 1 {
+		t.Fatalf("Expected 1 metric after failed refresh, got %d", n)
+	}
+
+	if v := testutil.ToFloat64(e); v != 0 {
+		t.Fatalf("Expected vici_up to be 0, got %v", v)
+	}
+}
+
+func TestExporterWithoutCounters(t *testing.T) {
+	srv := newTestServer(t)
+	srv.Handle("get-counters", func(_ *vicitest.Request) (*vici.Message, error) {
+		return nil, errors.New("counters plugin not loaded")
+	})
+
+	e := newTestExporter(t, srv, WithoutCounters())
+
+	if err := e.Refresh(context.Background()); err != nil {
+		t.Fatalf("Unexpected error refreshing metrics: %v", err)
+	}
+
+	if n := testutil.CollectAndCount(e, "vici_ike_events_total"); n != 0 {
+		t.Fatalf("Expected no counters, got %d", n)
+	}
+}
+
+func TestExporterCountersUnavailable(t *testing.T) {
+	srv := newTestServer(t)
+	srv.Handle("get-counters", func(_ *vicitest.Request) (*vici.Message, error) {
+		return nil, errors.New("counters plugin not loaded")
+	})
+
+	e := newTestExporter(t, srv)
+
+	// The refresh reports the error, but the other metrics are
+	// still updated.
+	if err := e.Refresh(context.Background()); err == nil {
+		t.Fatalf("Expected error from get-counters")
+	}
+
+	up := testutil.CollectAndCount(e, "vici_up")
+	sas := testutil.CollectAndCount(e, "vici_ike_sas")
+	if up != 1 || sas != 2 {
+		t.Fatalf("Expected metrics despite get-counters error, got vici_up=%d vici_ike_sas=%d", up, sas)
+	}
+}
+
+func TestExporterRunRefreshesOnEvents(t *testing.T) {
+	srv := newTestServer(t)
+
+	refreshed := make(chan struct{}, 16)
+	srv.Handle("stats", func(_ *vicitest.Request) (*vici.Message, error) {
+		refreshed <- struct{}{}
+
+		return vici.NewMessage(), nil
+	})
+
+	// Use a long interval, so that refreshes after the first one can
+	// only be triggered by events.
+	e := newTestExporter(t, srv, WithInterval(time.Hour), WithoutCounters())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+	go func() { errs <- e.Run(ctx) }()
+
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for initial refresh")
+	}
+
+	up, err := vici.Build().Set("up", "yes").Message()
+	if err != nil {
+		t.Fatalf("Unexpected error building event: %v", err)
+	}
+
+	for _, event := range []string{"ike-updown", "child-updown"} {
+		// The subscription may not be confirmed yet at the time of the
+		// first refresh, so emit the event until it is received.
+		timeout := time.After(5 * time.Second)
+
+	emit:
+		for {
+			if err := srv.Emit(event, up); err != nil {
+				t.Fatalf("Unexpected error emitting %s: %v", event, err)
+			}
+
+			select {
+			case <-refreshed:
+				break emit
+			case <-time.After(50 * time.Millisecond):
+			case <-timeout:
+				t.Fatalf("Timed out waiting for refresh after %s", event)
+			}
+		}
+	}
+
+	cancel()
+
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled from Run, got %v", err)
+	}
+}
+
+func TestExporterRunKeepsSubscriptions(t *testing.T) {
+	srv := newTestServer(t)
+	e := newTestExporter(t, srv, WithInterval(time.Hour), WithoutCounters())
+
+	if err := e.s.Subscribe("ike-updown"); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+	go func() { errs <- e.Run(ctx) }()
+
+	timeout := time.After(5 * time.Second)
+	for !slices.Contains(e.s.Subscriptions(), "child-updown") {
+		select {
+		case <-timeout:
+			t.Fatalf("Timed out waiting for Run to subscribe")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	cancel()
+
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled from Run, got %v", err)
+	}
+
+	// Only the event subscribed by Run is unsubscribed again.
+	if events := e.s.Subscriptions(); !slices.Equal(events, []string{"ike-updown"}) {
+		t.Fatalf("Expected ike-updown to remain subscribed, got %v", events)
+	}
+}
+
+func TestExporterDescribe(t *testing.T) {
+	ch := make(chan *prometheus.Desc, len(allDescs))
+	New(nil).Describe(ch)
+	close(ch)
+
+	n := 0
+	for range ch {
+		n++
+	}
+
+	if n != len(allDescs) {
+		t.Fatalf("Expected %d descriptions, got %d", len(allDescs), n)
+	}
+}
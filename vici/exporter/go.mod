@@ -0,0 +1,27 @@
+module github.com/strongswan/govici/vici/exporter
+
+go 1.25.0
+
+// The replace directives build this module against the code in this
+// repository. They are ignored when the module is used as a dependency, which
+// then uses the required releases. The modules of this repository are tagged
+// together, e.g. v0.9.0 and vici/exporter/v0.9.0.
+replace github.com/strongswan/govici => ../..
+
+require (
+	github.com/prometheus/client_golang v1.24.1
+	github.com/strongswan/govici v0.9.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.2 // indirect
+	github.com/prometheus/common v0.70.1 // indirect
+	github.com/prometheus/procfs v0.21.1 // indirect
+	github.com/stretchr/testify v1.12.1 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+)
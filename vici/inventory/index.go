@@ -0,0 +1,302 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package inventory
+
+import (
+	"context"
+	"iter"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// Get returns the IKE SA with the given unique ID.
+func (w *SAWatcher) Get(id string) (*IKESA, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	sa, ok := w.sas[id]
+
+	return sa, ok
+}
+
+// ByConn returns the IKE SAs of the named connection, in the order of their
+// unique IDs.
+func (w *SAWatcher) ByConn(name string) []*IKESA {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return sorted(w.byConn[name])
+}
+
+// ByRemoteHost returns the IKE SAs with the given remote host, e.g.
+// "192.0.2.1", in the order of their unique IDs.
+func (w *SAWatcher) ByRemoteHost(host string) []*IKESA {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return sorted(w.byRemoteHost[host])
+}
+
+// All returns all IKE SAs, in the order of their unique IDs.
+func (w *SAWatcher) All() []*IKESA {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return sorted(w.sas)
+}
+
+// Child returns the CHILD SA with the given unique ID, and its IKE SA.
+func (w *SAWatcher) Child(id string) (*ChildSA, *IKESA, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	sa, ok := w.byChild[id]
+	if !ok {
+		return nil, nil, false
+	}
+
+	return sa.ChildSAs[id], sa, true
+}
+
+// sorted returns the IKE SAs of m in the order of their unique IDs.
+func sorted(m map[string]*IKESA) []*IKESA {
+	var sas []*IKESA
+
+	for _, id := range sortedIDs(m) {
+		sas = append(sas, m[id])
+	}
+
+	return sas
+}
+
+// put adds sa to the index, replacing the IKE SA with the same unique ID.
+// w.mu must be held.
+func (w *SAWatcher) put(sa *IKESA) {
+	w.remove(sa.UniqueID)
+
+	w.sas[sa.UniqueID] = sa
+	addSA(w.byConn, sa.Name, sa)
+	addSA(w.byRemoteHost, sa.RemoteHost, sa)
+
+	for cid := range sa.ChildSAs {
+		w.byChild[cid] = sa
+	}
+}
+
+// remove removes the IKE SA with the given unique ID from the index. w.mu must
+// be held.
+func (w *SAWatcher) remove(id string) {
+	sa, ok := w.sas[id]
+	if !ok {
+		return
+	}
+
+	delete(w.sas, id)
+	deleteSA(w.byConn, sa.Name, id)
+	deleteSA(w.byRemoteHost, sa.RemoteHost, id)
+
+	for cid := range sa.ChildSAs {
+		if w.byChild[cid] == sa {
+			delete(w.byChild, cid)
+		}
+	}
+}
+
+// reindex replaces the index with sas. w.mu must be held.
+func (w *SAWatcher) reindex(sas map[string]*IKESA) {
+	w.sas = make(map[string]*IKESA)
+	w.byConn = make(map[string]map[string]*IKESA)
+	w.byRemoteHost = make(map[string]map[string]*IKESA)
+	w.byChild = make(map[string]*IKESA)
+
+	for _, sa := range sas {
+		w.put(sa)
+	}
+}
+
+func addSA(idx map[string]map[string]*IKESA, key string, sa *IKESA) {
+	if idx[key] == nil {
+		idx[key] = make(map[string]*IKESA)
+	}
+
+	idx[key][sa.UniqueID] = sa
+}
+
+func deleteSA(idx map[string]map[string]*IKESA, key, id string) {
+	delete(idx[key], id)
+
+	if len(idx[key]) == 0 {
+		delete(idx, key)
+	}
+}
+
+// watch is the queue of changes of a Changes iterator. The queue holds at most
+// limit changes, so a consumer which does not keep up cannot make the SAWatcher
+// hold on to an unbounded number of changes.
+type watch struct {
+	mu      sync.Mutex
+	changes []Change
+	limit   int
+	done    bool
+
+	// Signals that changes were queued, or that the watch ended.
+	notify chan struct{}
+}
+
+// push queues c, and reports whether it fit into the queue. If it did not, the
+// watch ends.
+func (wt *watch) push(c Change) bool {
+	wt.mu.Lock()
+	full := len(wt.changes) >= wt.limit
+	if full {
+		wt.done = true
+	} else {
+		wt.changes = append(wt.changes, c)
+	}
+	wt.mu.Unlock()
+
+	wt.signal()
+
+	return !full
+}
+
+func (wt *watch) close() {
+	wt.mu.Lock()
+	wt.done = true
+	wt.mu.Unlock()
+
+	wt.signal()
+}
+
+func (wt *watch) signal() {
+	select {
+	case wt.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (wt *watch) take() ([]Change, bool) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+
+	changes := wt.changes
+	wt.changes = nil
+
+	return changes, wt.done
+}
+
+// Changes returns an iterator over the changes of the index made after the
+// call, in the order they were made. The iterator ends when ctx is done, or
+// SAWatcher.Run returns.
+//
+// Changes are queued from the call on, up to the size set by WithChangeBuffer.
+// If the consumer falls further behind, the changes that do not fit are not
+// queued, and the iterator ends after the queued changes. Consumers which need
+// every change can then call All and Changes again. Until ctx is done, the
+// queue is kept even if the iterator is never used.
+func (w *SAWatcher) Changes(ctx context.Context) iter.Seq[Change] {
+	wt := &watch{limit: w.changeBuffer, notify: make(chan struct{}, 1)}
+
+	w.mu.Lock()
+	if w.done || ctx.Err() != nil {
+		wt.done = true
+	} else {
+		w.watches[wt] = struct{}{}
+	}
+	w.mu.Unlock()
+
+	// Release the queue when ctx is done, also if the iterator is never
+	// used.
+	stop := context.AfterFunc(ctx, func() { w.unwatch(wt) })
+
+	return func(yield func(Change) bool) {
+		defer stop()
+		defer w.unwatch(wt)
+
+		for ctx.Err() == nil {
+			changes, done := wt.take()
+
+			for _, c := range changes {
+				if !yield(c) {
+					return
+				}
+			}
+
+			if done {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-wt.notify:
+			}
+		}
+	}
+}
+
+// unwatch removes wt from the watches.
+func (w *SAWatcher) unwatch(wt *watch) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.watches, wt)
+}
+
+// publish sends c to the Changes iterators. w.mu must be held.
+func (w *SAWatcher) publish(c Change) {
+	for wt := range w.watches {
+		if !wt.push(c) {
+			delete(w.watches, wt)
+
+			w.logger.LogAttrs(context.Background(), slog.LevelWarn, "change buffer full, ending Changes iterator",
+				slog.Int("buffer", wt.limit))
+		}
+	}
+}
+
+// stop ends the Changes iterators.
+func (w *SAWatcher) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.done = true
+
+	for wt := range w.watches {
+		wt.close()
+	}
+	w.watches = make(map[*watch]struct{})
+}
+
+// without returns a copy of m without key.
+func without(m *vici.Message, key string) *vici.Message {
+	c := vici.NewMessage()
+
+	for _, k := range slices.DeleteFunc(m.Keys(), func(k string) bool { return k == key }) {
+		// nolint
+		_ = c.Set(k, m.Get(k))
+	}
+
+	return c
+}
@@ -0,0 +1,802 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package inventory keeps an in-memory index of the IKE and CHILD SAs of a
+// charon daemon, which is updated from events instead of polling list-sas.
+//
+// An SAWatcher seeds the index with a single list-sas request, and then keeps
+// it up to date from the ike-updown, ike-rekey, ike-update, child-updown and
+// child-rekey events. To recover from missed events, e.g. if the event buffer
+// overflows, the index is periodically resynchronized with list-sas:
+//
+//	w := inventory.NewSAWatcher(s)
+//	go w.Run(ctx)
+//
+//	for _, sa := range w.ByConn("gw") {
+//		fmt.Println(sa.UniqueID, sa.RemoteHost, sa.State)
+//	}
+//
+//	for c := range w.Changes(ctx) {
+//		fmt.Println(c.Kind, c.IKE.Name, c.IKE.UniqueID)
+//	}
+//
+// The SAs returned by an SAWatcher are snapshots, which are replaced rather
+// than modified when the SA changes, and must not be modified by the caller.
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// IKESA is an IKE SA, as reported by list-sas and the IKE and CHILD SA events.
+type IKESA struct {
+	// Name is the name of the SA's connection.
+	Name string
+
+	UniqueID      string   `vici:"uniqueid"`
+	Version       string   `vici:"version"`
+	State         string   `vici:"state"`
+	LocalHost     string   `vici:"local-host"`
+	LocalPort     string   `vici:"local-port"`
+	LocalID       string   `vici:"local-id"`
+	RemoteHost    string   `vici:"remote-host"`
+	RemotePort    string   `vici:"remote-port"`
+	RemoteID      string   `vici:"remote-id"`
+	RemoteEAPID   string   `vici:"remote-eap-id"`
+	RemoteXAuthID string   `vici:"remote-xauth-id"`
+	Initiator     string   `vici:"initiator"`
+	InitiatorSPI  string   `vici:"initiator-spi"`
+	ResponderSPI  string   `vici:"responder-spi"`
+	LocalVIPs     []string `vici:"local-vips"`
+	RemoteVIPs    []string `vici:"remote-vips"`
+
+	// Established is when the SA was established, or the zero time if it is
+	// not established.
+	Established time.Time
+
+	// ChildSAs holds the SA's CHILD SAs, by unique ID.
+	ChildSAs map[string]*ChildSA
+
+	// Message is the SA's section of the last event or list-sa message
+	// reporting it, without the child-sas section.
+	Message *vici.Message
+}
+
+// ChildSA is a CHILD SA, as reported by list-sas and the CHILD SA events.
+type ChildSA struct {
+	Name     string   `vici:"name"`
+	UniqueID string   `vici:"uniqueid"`
+	ReqID    string   `vici:"reqid"`
+	State    string   `vici:"state"`
+	Mode     string   `vici:"mode"`
+	Protocol string   `vici:"protocol"`
+	SPIIn    string   `vici:"spi-in"`
+	SPIOut   string   `vici:"spi-out"`
+	LocalTS  []string `vici:"local-ts"`
+	RemoteTS []string `vici:"remote-ts"`
+
+	// Installed is when the SA was installed, or the zero time if it is not
+	// installed.
+	Installed time.Time
+
+	// Message is the SA's section of the last event or list-sa message
+	// reporting it.
+	Message *vici.Message
+}
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+const (
+	// IKEUp means an IKE SA was established.
+	IKEUp ChangeKind = iota + 1
+
+	// IKEDown means an IKE SA was deleted.
+	IKEDown
+
+	// IKERekey means an IKE SA was replaced by a rekeyed one.
+	IKERekey
+
+	// IKEUpdate means the endpoints of an IKE SA changed, e.g. by MOBIKE.
+	IKEUpdate
+
+	// ChildUp means a CHILD SA was installed.
+	ChildUp
+
+	// ChildDown means a CHILD SA was deleted.
+	ChildDown
+
+	// ChildRekey means a CHILD SA was replaced by a rekeyed one.
+	ChildRekey
+)
+
+// String returns the name of k, e.g. "ike-up".
+func (k ChangeKind) String() string {
+	switch k {
+	case IKEUp:
+		return "ike-up"
+	case IKEDown:
+		return "ike-down"
+	case IKERekey:
+		return "ike-rekey"
+	case IKEUpdate:
+		return "ike-update"
+	case ChildUp:
+		return "child-up"
+	case ChildDown:
+		return "child-down"
+	case ChildRekey:
+		return "child-rekey"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// Change is a change of the index.
+type Change struct {
+	Kind ChangeKind
+
+	// IKE is the IKE SA after the change, or the deleted IKE SA for IKEDown.
+	// For CHILD SA changes, it is the IKE SA of the CHILD SA.
+	IKE *IKESA
+
+	// Old is the replaced IKE SA for IKERekey and IKEUpdate.
+	Old *IKESA
+
+	// Child is the installed, deleted or new CHILD SA for CHILD SA changes.
+	Child *ChildSA
+
+	// OldChild is the replaced CHILD SA for ChildRekey.
+	OldChild *ChildSA
+
+	// Resync is true if the change was found by a resynchronization with
+	// list-sas, rather than reported by an event.
+	Resync bool
+}
+
+// Option is used to specify options to NewSAWatcher.
+type Option interface {
+	apply(*SAWatcher)
+}
+
+type funcOption struct {
+	f func(*SAWatcher)
+}
+
+func (fo *funcOption) apply(w *SAWatcher) {
+	fo.f(w)
+}
+
+// WithResyncInterval specifies how often SAWatcher.Run resynchronizes the index
+// with list-sas. If this option is not specified, the index is resynchronized
+// every 5 minutes. An interval of zero disables the periodic resynchronization.
+func WithResyncInterval(interval time.Duration) Option {
+	return &funcOption{func(w *SAWatcher) {
+		w.interval = interval
+	}}
+}
+
+// WithEventBuffer specifies the size of the buffer for received events. If
+// events are dropped because the buffer is full, the index is resynchronized
+// immediately. If this option is not specified, 1024 events are buffered.
+func WithEventBuffer(size int) Option {
+	return &funcOption{func(w *SAWatcher) {
+		w.buffer = size
+	}}
+}
+
+// WithChangeBuffer specifies how many changes are queued for each iterator
+// returned by SAWatcher.Changes. If an iterator falls further behind, it ends.
+// If this option is not specified, 1024 changes are queued.
+func WithChangeBuffer(size int) Option {
+	return &funcOption{func(w *SAWatcher) {
+		w.changeBuffer = size
+	}}
+}
+
+// WithLogger specifies the logger used to report failed resynchronizations,
+// unexpected events, and iterators ended because their change buffer is full.
+// If this option is not specified, nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return &funcOption{func(w *SAWatcher) {
+		w.logger = logger
+	}}
+}
+
+// events are the events the index is updated from.
+var events = []string{"ike-updown", "ike-rekey", "ike-update", "child-updown", "child-rekey"}
+
+// SAWatcher keeps an index of the IKE and CHILD SAs of a daemon. It is safe for
+// concurrent use.
+type SAWatcher struct {
+	s *vici.Session
+
+	interval     time.Duration
+	buffer       int
+	changeBuffer int
+	logger       *slog.Logger
+
+	// Returns the current time, for the Established and Installed times.
+	now func() time.Time
+
+	// Serializes Resync with applying events.
+	syncMu sync.Mutex
+
+	mu  sync.RWMutex
+	sas map[string]*IKESA
+
+	// Secondary indexes of sas, by connection name, by remote host, and
+	// by the unique IDs of the CHILD SAs.
+	byConn       map[string]map[string]*IKESA
+	byRemoteHost map[string]map[string]*IKESA
+	byChild      map[string]*IKESA
+
+	synced  chan struct{}
+	watches map[*watch]struct{}
+	done    bool
+}
+
+// NewSAWatcher returns an SAWatcher which watches the daemon connected to s.
+// The index is empty until SAWatcher.Run seeded it.
+func NewSAWatcher(s *vici.Session, opts ...Option) *SAWatcher {
+	w := &SAWatcher{
+		s:            s,
+		interval:     5 * time.Minute,
+		buffer:       1024,
+		changeBuffer: 1024,
+		logger:       slog.New(slog.DiscardHandler),
+		now:          time.Now,
+		sas:          make(map[string]*IKESA),
+		byConn:       make(map[string]map[string]*IKESA),
+		byRemoteHost: make(map[string]map[string]*IKESA),
+		byChild:      make(map[string]*IKESA),
+		synced:       make(chan struct{}),
+		watches:      make(map[*watch]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt.apply(w)
+	}
+
+	return w
+}
+
+// Run seeds the index, and keeps it up to date until ctx is done or the session
+// ends. Run subscribes the session to the IKE and CHILD SA events for its
+// duration. Events that the session is already subscribed to when Run starts
+// are left subscribed when it returns. The iterators returned by Changes end
+// when Run returns.
+//
+// Run must only be called once. It returns an error if the index cannot be
+// seeded, and otherwise the reason why it stopped.
+func (w *SAWatcher) Run(ctx context.Context) error {
+	defer w.stop()
+
+	ec := make(chan vici.Event, w.buffer)
+	w.s.NotifyEvents(ec)
+	defer w.s.StopEvents(ec)
+
+	// Subscribe before seeding, so no change is missed in between. Events
+	// received while seeding are applied afterwards. They may be older than
+	// the list-sas snapshot, but as long as none of them are dropped, their
+	// changes are applied in order and lead to the state after the last one.
+	// Events that the session is already subscribed to are left alone.
+	subscribed := w.s.Subscriptions()
+	subscribe := slices.DeleteFunc(slices.Clone(events), func(event string) bool {
+		return slices.Contains(subscribed, event)
+	})
+
+	if len(subscribe) > 0 {
+		if err := w.s.SubscribeContext(ctx, subscribe...); err != nil {
+			return err
+		}
+		defer func() {
+			// nolint
+			_ = w.s.UnsubscribeContext(context.WithoutCancel(ctx), subscribe...)
+		}()
+	}
+
+	if err := w.Resync(ctx); err != nil {
+		return err
+	}
+
+	var tick <-chan time.Time
+	if w.interval > 0 {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	var drops uint64
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-w.s.Done():
+			return w.s.Err()
+
+		case <-tick:
+			w.resync(ctx)
+
+		case e, ok := <-ec:
+			if !ok {
+				return w.s.Err()
+			}
+
+			w.handle(e)
+
+			// Dropped events leave the index out of date, so resync.
+			// The buffered events are older than the list-sas snapshot,
+			// and may be followed by dropped ones, e.g. an IKE SA going
+			// up before the dropped event of it going down, so they are
+			// discarded instead of being applied over the snapshot.
+			if n := w.s.Stats().SubscriberDrops[ec]; n > drops {
+				drops = n
+
+				w.logger.LogAttrs(ctx, slog.LevelWarn, "events dropped, resynchronizing SAs",
+					slog.Uint64("dropped", n))
+				drain(ec)
+				w.resync(ctx)
+			}
+		}
+	}
+}
+
+// drain discards the events buffered in ec.
+func drain(ec <-chan vici.Event) {
+	for {
+		select {
+		case _, ok := <-ec:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (w *SAWatcher) resync(ctx context.Context) {
+	if err := w.Resync(ctx); err != nil {
+		w.logger.LogAttrs(ctx, slog.LevelWarn, "failed to resynchronize SAs", slog.Any("error", err))
+	}
+}
+
+// Resync replaces the index with the SAs reported by list-sas, and sends the
+// differences as changes with Change.Resync set. Run resyncs periodically, but
+// Resync may also be called directly, e.g. after a configuration change. While
+// Resync runs, Run does not apply events, so events received in the meantime
+// are applied after the snapshot instead of being replaced by it.
+func (w *SAWatcher) Resync(ctx context.Context) error {
+	w.syncMu.Lock()
+	defer w.syncMu.Unlock()
+
+	received := w.now()
+	sas := make(map[string]*IKESA)
+
+	for m, err := range w.s.CallStreaming(ctx, "list-sas", "list-sa", nil) {
+		if err != nil {
+			return fmt.Errorf("list-sas: %w", err)
+		}
+
+		for name, sec := range sections(m) {
+			sa, err := w.parseIKESA(name, sec, received)
+			if err != nil {
+				return fmt.Errorf("list-sas: %w", err)
+			}
+
+			sas[sa.UniqueID] = sa
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	old := w.sas
+	w.reindex(sas)
+
+	for _, id := range sortedIDs(old) {
+		if _, ok := sas[id]; !ok {
+			w.publish(Change{Kind: IKEDown, IKE: old[id], Resync: true})
+		}
+	}
+
+	for _, id := range sortedIDs(sas) {
+		sa := sas[id]
+
+		prev, ok := old[id]
+		if !ok {
+			w.publish(Change{Kind: IKEUp, IKE: sa, Resync: true})
+
+			continue
+		}
+
+		for _, cid := range sortedIDs(prev.ChildSAs) {
+			if _, ok := sa.ChildSAs[cid]; !ok {
+				w.publish(Change{Kind: ChildDown, IKE: sa, Child: prev.ChildSAs[cid], Resync: true})
+			}
+		}
+
+		for _, cid := range sortedIDs(sa.ChildSAs) {
+			if _, ok := prev.ChildSAs[cid]; !ok {
+				w.publish(Change{Kind: ChildUp, IKE: sa, Child: sa.ChildSAs[cid], Resync: true})
+			}
+		}
+	}
+
+	select {
+	case <-w.synced:
+	default:
+		close(w.synced)
+	}
+
+	return nil
+}
+
+// Synced returns a channel which is closed once the index has been seeded.
+func (w *SAWatcher) Synced() <-chan struct{} {
+	return w.synced
+}
+
+// handle updates the index from an event.
+func (w *SAWatcher) handle(e vici.Event) {
+	w.syncMu.Lock()
+	defer w.syncMu.Unlock()
+
+	var err error
+
+	switch e.Name {
+	case "ike-updown":
+		err = w.ikeUpdown(e)
+	case "ike-rekey":
+		err = w.ikeRekey(e)
+	case "ike-update":
+		err = w.ikeUpdate(e)
+	case "child-updown":
+		err = w.childUpdown(e)
+	case "child-rekey":
+		err = w.childRekey(e)
+	default:
+		return
+	}
+
+	if err != nil {
+		w.logger.LogAttrs(context.Background(), slog.LevelWarn, "ignoring invalid event",
+			slog.String("event", e.Name), slog.Any("error", err))
+	}
+}
+
+func (w *SAWatcher) ikeUpdown(e vici.Event) error {
+	up := e.Message.Get("up") == "yes"
+
+	for name, sec := range sections(e.Message) {
+		sa, err := w.parseIKESA(name, sec, e.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		w.mu.Lock()
+		if up {
+			w.put(sa)
+			w.publish(Change{Kind: IKEUp, IKE: sa})
+		} else if old, ok := w.sas[sa.UniqueID]; ok {
+			w.remove(sa.UniqueID)
+			w.publish(Change{Kind: IKEDown, IKE: old})
+		}
+		w.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (w *SAWatcher) ikeRekey(e vici.Event) error {
+	for name, sec := range sections(e.Message) {
+		oldSec, _ := sec.Get("old").(*vici.Message)
+		newSec, _ := sec.Get("new").(*vici.Message)
+		if oldSec == nil || newSec == nil {
+			return fmt.Errorf("IKE SA %s lacks old or new section", name)
+		}
+
+		old, err := w.parseIKESA(name, oldSec, e.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		sa, err := w.parseIKESA(name, newSec, e.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		w.mu.Lock()
+		if prev, ok := w.sas[old.UniqueID]; ok {
+			old = prev
+			w.remove(old.UniqueID)
+		}
+		w.put(sa)
+		w.publish(Change{Kind: IKERekey, IKE: sa, Old: old})
+		w.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (w *SAWatcher) ikeUpdate(e vici.Event) error {
+	for name, sec := range sections(e.Message) {
+		sa, err := w.parseIKESA(name, sec, e.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		// The SA's section reports the SA before the update, and the
+		// new endpoints are reported at the top level of the event.
+		for _, f := range []struct {
+			key   string
+			field *string
+		}{
+			{"local-host", &sa.LocalHost},
+			{"local-port", &sa.LocalPort},
+			{"remote-host", &sa.RemoteHost},
+			{"remote-port", &sa.RemotePort},
+		} {
+			if v, ok := e.Message.Get(f.key).(string); ok {
+				*f.field = v
+			}
+		}
+
+		w.mu.Lock()
+		old := w.sas[sa.UniqueID]
+		if old != nil && len(sa.ChildSAs) == 0 {
+			sa.ChildSAs = old.ChildSAs
+		}
+		w.put(sa)
+		w.publish(Change{Kind: IKEUpdate, IKE: sa, Old: old})
+		w.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (w *SAWatcher) childUpdown(e vici.Event) error {
+	up := e.Message.Get("up") == "yes"
+
+	for name, sec := range sections(e.Message) {
+		sa, err := w.parseIKESA(name, sec, e.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		w.mu.Lock()
+		// The event only reports the affected CHILD SAs, so keep the
+		// others.
+		children := w.children(sa.UniqueID)
+		for id, child := range sa.ChildSAs {
+			if up {
+				children[id] = child
+			} else {
+				delete(children, id)
+			}
+		}
+
+		reported := sa.ChildSAs
+		sa.ChildSAs = children
+		w.put(sa)
+
+		kind := ChildDown
+		if up {
+			kind = ChildUp
+		}
+
+		for _, id := range sortedIDs(reported) {
+			w.publish(Change{Kind: kind, IKE: sa, Child: reported[id]})
+		}
+		w.mu.Unlock()
+	}
+
+	return nil
+}
+
+func (w *SAWatcher) childRekey(e vici.Event) error {
+	for name, sec := range sections(e.Message) {
+		// The child-sas section holds old and new sections instead of
+		// the CHILD SAs, so parse them separately.
+		sa, err := w.parseIKESA(name, without(sec, "child-sas"), e.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		type rekey struct{ old, new *ChildSA }
+
+		var rekeys []rekey
+
+		if cs, ok := sec.Get("child-sas").(*vici.Message); ok {
+			for _, csec := range sections(cs) {
+				oldSec, _ := csec.Get("old").(*vici.Message)
+				newSec, _ := csec.Get("new").(*vici.Message)
+				if oldSec == nil || newSec == nil {
+					return fmt.Errorf("CHILD SA of IKE SA %s lacks old or new section", name)
+				}
+
+				old, err := w.parseChildSA(oldSec, e.Timestamp)
+				if err != nil {
+					return err
+				}
+
+				child, err := w.parseChildSA(newSec, e.Timestamp)
+				if err != nil {
+					return err
+				}
+
+				rekeys = append(rekeys, rekey{old, child})
+			}
+		}
+
+		w.mu.Lock()
+		children := w.children(sa.UniqueID)
+		for i, r := range rekeys {
+			if prev, ok := children[r.old.UniqueID]; ok {
+				rekeys[i].old = prev
+				delete(children, r.old.UniqueID)
+			}
+			children[r.new.UniqueID] = r.new
+		}
+
+		sa.ChildSAs = children
+		w.put(sa)
+
+		for _, r := range rekeys {
+			w.publish(Change{Kind: ChildRekey, IKE: sa, Child: r.new, OldChild: r.old})
+		}
+		w.mu.Unlock()
+	}
+
+	return nil
+}
+
+// children returns a copy of the CHILD SAs of the indexed IKE SA with the given
+// unique ID. w.mu must be held.
+func (w *SAWatcher) children(id string) map[string]*ChildSA {
+	children := make(map[string]*ChildSA)
+
+	if sa, ok := w.sas[id]; ok {
+		for cid, child := range sa.ChildSAs {
+			children[cid] = child
+		}
+	}
+
+	return children
+}
+
+func (w *SAWatcher) parseIKESA(name string, sec *vici.Message, received time.Time) (*IKESA, error) {
+	sa := &IKESA{
+		Name:     name,
+		ChildSAs: make(map[string]*ChildSA),
+		Message:  without(sec, "child-sas"),
+	}
+
+	if err := vici.UnmarshalMessage(sec, sa); err != nil {
+		return nil, fmt.Errorf("IKE SA %s: %w", name, err)
+	}
+
+	if sa.UniqueID == "" {
+		return nil, fmt.Errorf("IKE SA %s lacks uniqueid", name)
+	}
+
+	sa.Established = since(sec, "established", received)
+
+	if cs, ok := sec.Get("child-sas").(*vici.Message); ok {
+		for _, csec := range sections(cs) {
+			child, err := w.parseChildSA(csec, received)
+			if err != nil {
+				return nil, fmt.Errorf("IKE SA %s: %w", name, err)
+			}
+
+			sa.ChildSAs[child.UniqueID] = child
+		}
+	}
+
+	return sa, nil
+}
+
+func (w *SAWatcher) parseChildSA(sec *vici.Message, received time.Time) (*ChildSA, error) {
+	child := &ChildSA{Message: sec}
+
+	if err := vici.UnmarshalMessage(sec, child); err != nil {
+		return nil, fmt.Errorf("CHILD SA: %w", err)
+	}
+
+	if child.UniqueID == "" {
+		return nil, fmt.Errorf("CHILD SA %s lacks uniqueid", child.Name)
+	}
+
+	child.Installed = since(sec, "install-time", received)
+
+	return child, nil
+}
+
+// since returns the time the number of seconds given by key before received,
+// or the zero time if key is not set.
+func since(sec *vici.Message, key string, received time.Time) time.Time {
+	v, ok := sec.Get(key).(string)
+	if !ok {
+		return time.Time{}
+	}
+
+	secs, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return received.Add(-time.Duration(secs) * time.Second)
+}
+
+// sections returns an iterator over the sections of m, by name.
+func sections(m *vici.Message) iter.Seq2[string, *vici.Message] {
+	return func(yield func(string, *vici.Message) bool) {
+		for _, k := range m.Keys() {
+			sec, ok := m.Get(k).(*vici.Message)
+			if !ok {
+				continue
+			}
+
+			if !yield(k, sec) {
+				return
+			}
+		}
+	}
+}
+
+// sortedIDs returns the keys of m, i.e. unique IDs, in numeric order.
+func sortedIDs[V any](m map[string]V) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+
+	slices.SortFunc(ids, compareIDs)
+
+	return ids
+}
+
+func compareIDs(a, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
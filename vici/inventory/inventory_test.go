@@ -0,0 +1,501 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package inventory_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/inventory"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+// daemon is a fake daemon answering list-sas with the SAs set with setSAs.
+type daemon struct {
+	srv *vicitest.Server
+
+	mu  sync.Mutex
+	sas []*vici.Message
+}
+
+func newDaemon(t *testing.T) (*daemon, *vici.Session) {
+	t.Helper()
+
+	d := &daemon{srv: vicitest.NewServer()}
+	t.Cleanup(func() { d.srv.Close() })
+
+	d.srv.Handle("list-sas", func(req *vicitest.Request) (*vici.Message, error) {
+		d.mu.Lock()
+		sas := d.sas
+		d.mu.Unlock()
+
+		for _, sa := range sas {
+			if err := req.Emit("list-sa", sa); err != nil {
+				return nil, err
+			}
+		}
+
+		return vici.NewMessage(), nil
+	})
+
+	return d, vicitest.NewSession(t, d.srv)
+}
+
+func (d *daemon) setSAs(sas ...*vici.Message) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sas = sas
+}
+
+func (d *daemon) emit(t *testing.T, event string, m *vici.Message) {
+	t.Helper()
+
+	if err := d.srv.Emit(event, m); err != nil {
+		t.Fatalf("Failed to emit %s: %v", event, err)
+	}
+}
+
+// ikeSA builds the section of an IKE SA with the given CHILD SAs.
+func ikeSA(id, remote string, children ...string) *vici.Builder {
+	b := vici.Build().
+		Set("uniqueid", id).
+		Set("version", "2").
+		Set("state", "ESTABLISHED").
+		Set("local-host", "192.0.2.1").
+		Set("remote-host", remote).
+		Set("established", "10")
+
+	if len(children) == 0 {
+		return b
+	}
+
+	b = b.Section("child-sas")
+	for _, child := range children {
+		b = childSA(b.Section("net-"+child), child).End()
+	}
+
+	return b.End()
+}
+
+func childSA(b *vici.Builder, id string) *vici.Builder {
+	return b.
+		Set("name", "net").
+		Set("uniqueid", id).
+		Set("state", "INSTALLED").
+		Set("install-time", "5").
+		List("local-ts", "10.1.0.0/16").
+		List("remote-ts", "10.2.0.0/16")
+}
+
+// sa wraps an IKE SA section in a message named after its connection.
+func sa(t *testing.T, name string, ike *vici.Builder) *vici.Message {
+	t.Helper()
+
+	sec := vicitest.MustBuild(t, ike)
+
+	return vicitest.MustBuild(t, vici.Build().Set(name, sec))
+}
+
+func startWatcher(t *testing.T, s *vici.Session, opts ...inventory.Option) (*inventory.SAWatcher, <-chan inventory.Change, func() error) {
+	t.Helper()
+
+	w := inventory.NewSAWatcher(s, opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	changes := make(chan inventory.Change, 64)
+	go func() {
+		defer close(changes)
+
+		for c := range w.Changes(ctx) {
+			changes <- c
+		}
+	}()
+
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	select {
+	case <-w.Synced():
+	case err := <-done:
+		t.Fatalf("Run failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the index to be seeded")
+	}
+
+	stop := func() error {
+		cancel()
+
+		return <-done
+	}
+
+	return w, changes, stop
+}
+
+func nextChange(t *testing.T, changes <-chan inventory.Change) inventory.Change {
+	t.Helper()
+
+	select {
+	case c, ok := <-changes:
+		if !ok {
+			t.Fatal("Changes ended unexpectedly")
+		}
+
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for change")
+	}
+
+	return inventory.Change{}
+}
+
+func expectChange(t *testing.T, changes <-chan inventory.Change, kind inventory.ChangeKind, ike, child string) inventory.Change {
+	t.Helper()
+
+	c := nextChange(t, changes)
+	if c.Kind != kind || c.IKE.UniqueID != ike {
+		t.Fatalf("Expected %v of IKE SA %s, got %v of IKE SA %s", kind, ike, c.Kind, c.IKE.UniqueID)
+	}
+
+	if child != "" && (c.Child == nil || c.Child.UniqueID != child) {
+		t.Fatalf("Expected %v of CHILD SA %s, got %+v", kind, child, c.Child)
+	}
+
+	return c
+}
+
+func TestSAWatcher(t *testing.T) {
+	d, s := newDaemon(t)
+
+	d.setSAs(sa(t, "gw", ikeSA("1", "198.51.100.1", "1")))
+
+	w, changes, stop := startWatcher(t, s)
+
+	// Seeded from list-sas.
+	expectChange(t, changes, inventory.IKEUp, "1", "")
+
+	sas := w.ByConn("gw")
+	if len(sas) != 1 || sas[0].UniqueID != "1" || sas[0].RemoteHost != "198.51.100.1" {
+		t.Fatalf("Unexpected SAs of gw: %+v", sas)
+	}
+
+	if got := sas[0].Established; time.Since(got) < 10*time.Second || time.Since(got) > time.Minute {
+		t.Errorf("Expected established about 10s ago, got %v", got)
+	}
+
+	if child, ike, ok := w.Child("1"); !ok || ike.UniqueID != "1" || child.RemoteTS[0] != "10.2.0.0/16" {
+		t.Fatalf("Expected CHILD SA 1 of IKE SA 1, got %+v %+v", child, ike)
+	}
+
+	// A new IKE SA comes up.
+	up := sa(t, "rw", ikeSA("2", "203.0.113.5"))
+	// nolint
+	_ = up.Set("up", "yes")
+	d.emit(t, "ike-updown", up)
+	expectChange(t, changes, inventory.IKEUp, "2", "")
+
+	if sas := w.ByRemoteHost("203.0.113.5"); len(sas) != 1 || sas[0].Name != "rw" {
+		t.Fatalf("Unexpected SAs of 203.0.113.5: %+v", sas)
+	}
+
+	// A CHILD SA is installed.
+	up = sa(t, "rw", ikeSA("2", "203.0.113.5", "5"))
+	// nolint
+	_ = up.Set("up", "yes")
+	d.emit(t, "child-updown", up)
+	expectChange(t, changes, inventory.ChildUp, "2", "5")
+
+	// The CHILD SA is rekeyed.
+	old := vicitest.MustBuild(t, childSA(vici.Build(), "5"))
+	next := vicitest.MustBuild(t, childSA(vici.Build(), "6"))
+	ike := ikeSA("2", "203.0.113.5").Section("child-sas").Section("net-6").Set("old", old).Set("new", next).End().End()
+	d.emit(t, "child-rekey", sa(t, "rw", ike))
+
+	c := expectChange(t, changes, inventory.ChildRekey, "2", "6")
+	if c.OldChild == nil || c.OldChild.UniqueID != "5" {
+		t.Fatalf("Expected old CHILD SA 5, got %+v", c.OldChild)
+	}
+
+	if _, _, ok := w.Child("5"); ok {
+		t.Fatal("Expected rekeyed CHILD SA 5 to be removed")
+	}
+	if sa, ok := w.Get("2"); !ok || len(sa.ChildSAs) != 1 || sa.ChildSAs["6"] == nil {
+		t.Fatalf("Expected IKE SA 2 with CHILD SA 6, got %+v", sa)
+	}
+
+	// The first IKE SA is rekeyed, and keeps its CHILD SA.
+	rekey := vicitest.MustBuild(t, vici.Build().
+		Section("gw").
+		Set("old", vicitest.MustBuild(t, ikeSA("1", "198.51.100.1", "1"))).
+		Set("new", vicitest.MustBuild(t, ikeSA("3", "198.51.100.1", "1"))).
+		End())
+	d.emit(t, "ike-rekey", rekey)
+
+	c = expectChange(t, changes, inventory.IKERekey, "3", "")
+	if c.Old == nil || c.Old.UniqueID != "1" {
+		t.Fatalf("Expected old IKE SA 1, got %+v", c.Old)
+	}
+
+	if _, ok := w.Get("1"); ok {
+		t.Fatal("Expected rekeyed IKE SA 1 to be removed")
+	}
+
+	// The rekeyed IKE SA moves to a new address. Like charon, the event
+	// reports the new endpoints next to the section of the SA, which still
+	// holds the old ones.
+	update := vicitest.MustBuild(t, vici.Build().
+		Set("local-host", "192.0.2.1").
+		Set("local-port", "4500").
+		Set("remote-host", "198.51.100.2").
+		Set("remote-port", "4500").
+		Set("gw", vicitest.MustBuild(t, ikeSA("3", "198.51.100.1"))))
+	d.emit(t, "ike-update", update)
+
+	c = expectChange(t, changes, inventory.IKEUpdate, "3", "")
+	if c.IKE.RemoteHost != "198.51.100.2" || c.Old.RemoteHost != "198.51.100.1" {
+		t.Fatalf("Expected remote host to change to 198.51.100.2, got %s (from %s)", c.IKE.RemoteHost, c.Old.RemoteHost)
+	}
+	if len(c.IKE.ChildSAs) != 1 {
+		t.Fatalf("Expected updated IKE SA to keep its CHILD SA, got %+v", c.IKE.ChildSAs)
+	}
+	if sas := w.ByRemoteHost("198.51.100.1"); len(sas) != 0 {
+		t.Fatalf("Expected no SAs with the old address, got %+v", sas)
+	}
+	if sas := w.ByRemoteHost("198.51.100.2"); len(sas) != 1 || sas[0].UniqueID != "3" {
+		t.Fatalf("Expected IKE SA 3 with the new address, got %+v", sas)
+	}
+
+	// The CHILD SA, and then the IKE SA, go down.
+	d.emit(t, "child-updown", sa(t, "rw", ikeSA("2", "203.0.113.5", "6")))
+	expectChange(t, changes, inventory.ChildDown, "2", "6")
+
+	d.emit(t, "ike-updown", sa(t, "rw", ikeSA("2", "203.0.113.5")))
+	expectChange(t, changes, inventory.IKEDown, "2", "")
+
+	if _, ok := w.Get("2"); ok {
+		t.Fatal("Expected IKE SA 2 to be removed")
+	}
+
+	// A resync finds the changes made without events.
+	d.setSAs(sa(t, "gw", ikeSA("4", "198.51.100.1")))
+
+	if err := w.Resync(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if c := expectChange(t, changes, inventory.IKEDown, "3", ""); !c.Resync {
+		t.Error("Expected change found by resync")
+	}
+	expectChange(t, changes, inventory.IKEUp, "4", "")
+
+	if sas := w.All(); len(sas) != 1 || sas[0].UniqueID != "4" {
+		t.Fatalf("Unexpected SAs after resync: %+v", sas)
+	}
+
+	if err := stop(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	// The iterator ends with Run.
+	for range changes {
+	}
+}
+
+func TestSAWatcherPeriodicResync(t *testing.T) {
+	d, s := newDaemon(t)
+
+	w, changes, _ := startWatcher(t, s, inventory.WithResyncInterval(10*time.Millisecond))
+
+	d.setSAs(sa(t, "gw", ikeSA("1", "198.51.100.1", "1")))
+
+	if c := expectChange(t, changes, inventory.IKEUp, "1", ""); !c.Resync {
+		t.Error("Expected change found by resync")
+	}
+
+	d.setSAs(sa(t, "gw", ikeSA("1", "198.51.100.1", "2")))
+
+	expectChange(t, changes, inventory.ChildDown, "1", "1")
+	expectChange(t, changes, inventory.ChildUp, "1", "2")
+
+	// The lookups reflect the resynchronized index.
+	if _, _, ok := w.Child("1"); ok {
+		t.Error("Expected CHILD SA 1 to be removed")
+	}
+	if _, ike, ok := w.Child("2"); !ok || ike.UniqueID != "1" {
+		t.Errorf("Expected CHILD SA 2 of IKE SA 1, got %v, %v", ike, ok)
+	}
+	if sas := w.ByConn("gw"); len(sas) != 1 || sas[0].ChildSAs["2"] == nil {
+		t.Errorf("Expected IKE SA of gw with CHILD SA 2, got %v", sas)
+	}
+}
+
+// blockingHandler blocks logging the first record until release is closed.
+type blockingHandler struct {
+	once    sync.Once
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *blockingHandler) Handle(context.Context, slog.Record) error {
+	h.once.Do(func() {
+		close(h.entered)
+		<-h.release
+	})
+
+	return nil
+}
+
+func (h *blockingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *blockingHandler) WithGroup(string) slog.Handler      { return h }
+
+func TestSAWatcherDiscardsEventsOnDrops(t *testing.T) {
+	d, s := newDaemon(t)
+
+	h := &blockingHandler{entered: make(chan struct{}), release: make(chan struct{})}
+	_, changes, _ := startWatcher(t, s, inventory.WithEventBuffer(1), inventory.WithLogger(slog.New(h)))
+
+	// Run is blocked logging the invalid event, so the next event is
+	// buffered, and the one after it is dropped.
+	d.emit(t, "ike-updown", sa(t, "gw", vici.Build().Set("state", "ESTABLISHED")))
+	<-h.entered
+
+	up := sa(t, "gw", ikeSA("1", "198.51.100.1"))
+	// nolint
+	_ = up.Set("up", "yes")
+	d.emit(t, "ike-updown", up)
+	d.emit(t, "ike-updown", sa(t, "gw", ikeSA("1", "198.51.100.1")))
+
+	timeout := time.After(5 * time.Second)
+	for s.Stats().EventsDropped == 0 {
+		select {
+		case <-timeout:
+			t.Fatal("Timed out waiting for an event to be dropped")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	d.setSAs(sa(t, "gw", ikeSA("3", "198.51.100.1")))
+	close(h.release)
+
+	if c := expectChange(t, changes, inventory.IKEUp, "3", ""); !c.Resync {
+		t.Fatal("Expected change found by resync")
+	}
+
+	// The buffered event is discarded, so the IKE SA which went down is not
+	// added again, and the next change is the one of a later event.
+	next := sa(t, "rw", ikeSA("2", "203.0.113.5"))
+	// nolint
+	_ = next.Set("up", "yes")
+	d.emit(t, "ike-updown", next)
+
+	expectChange(t, changes, inventory.IKEUp, "2", "")
+}
+
+func TestSAWatcherRunKeepsSubscriptions(t *testing.T) {
+	_, s := newDaemon(t)
+
+	if err := s.Subscribe("child-updown"); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+
+	_, _, stop := startWatcher(t, s)
+
+	if events := s.Subscriptions(); len(events) != 5 {
+		t.Fatalf("Expected 5 subscribed events, got %v", events)
+	}
+
+	if err := stop(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled from Run, got %v", err)
+	}
+
+	// Only the events subscribed by Run are unsubscribed again.
+	if events := s.Subscriptions(); !slices.Equal(events, []string{"child-updown"}) {
+		t.Fatalf("Expected child-updown to remain subscribed, got %v", events)
+	}
+}
+
+func TestSAWatcherChangeBuffer(t *testing.T) {
+	d, s := newDaemon(t)
+
+	w, _, _ := startWatcher(t, s, inventory.WithChangeBuffer(2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The iterator is not consumed until the changes overflowed its queue.
+	changes := w.Changes(ctx)
+
+	d.setSAs(
+		sa(t, "gw", ikeSA("1", "198.51.100.1")),
+		sa(t, "gw", ikeSA("2", "198.51.100.2")),
+		sa(t, "gw", ikeSA("3", "198.51.100.3")),
+	)
+	if err := w.Resync(context.Background()); err != nil {
+		t.Fatalf("Unexpected error from Resync: %v", err)
+	}
+
+	var ids []string
+	for c := range changes {
+		ids = append(ids, c.IKE.UniqueID)
+	}
+
+	if !slices.Equal(ids, []string{"1", "2"}) {
+		t.Fatalf("Expected the queued changes of IKE SAs 1 and 2, got %v", ids)
+	}
+
+	// An iterator for a done ctx ends immediately.
+	cancel()
+	for range w.Changes(ctx) {
+		t.Fatal("Unexpected change")
+	}
+}
+
+func TestSAWatcherSeedFailure(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	s, err := vici.NewSession(vici.WithDialContext(srv.DialContext))
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer s.Close()
+
+	w := inventory.NewSAWatcher(s)
+
+	if err := w.Run(context.Background()); !errors.Is(err, vici.ErrUnknownCommand) {
+		t.Fatalf("Expected ErrUnknownCommand, got %v", err)
+	}
+
+	// Changes ends immediately once Run returned.
+	for range w.Changes(context.Background()) {
+		t.Fatal("Unexpected change")
+	}
+}
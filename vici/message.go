@@ -23,6 +23,7 @@ package vici
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -81,6 +82,18 @@ const (
 	pktInvalid
 )
 
+// Packet type names as used in the vici README.
+var pktNames = map[uint8]string{
+	pktCmdRequest:      "CMD_REQUEST",
+	pktCmdResponse:     "CMD_RESPONSE",
+	pktCmdUnknown:      "CMD_UNKNOWN",
+	pktEventRegister:   "EVENT_REGISTER",
+	pktEventUnregister: "EVENT_UNREGISTER",
+	pktEventConfirm:    "EVENT_CONFIRM",
+	pktEventUnknown:    "EVENT_UNKNOWN",
+	pktEvent:           "EVENT",
+}
+
 var (
 	// Generic encoding/decoding and marshaling/unmarshaling errors
 	errEncoding  = errors.New("vici: error encoding message")
@@ -283,9 +296,75 @@ func (m *Message) stringIndent(prefix, indent string) string {
 }
 
 // String returns the string form of m. For readability, the output format is similar to
-// swanctl.conf configuration format.
+// swanctl.conf configuration format. Secrets are redacted using DefaultRedactor.
 func (m *Message) String() string {
-	return m.stringIndent("", "  ")
+	return DefaultRedactor.Redact(m).stringIndent("", "  ")
+}
+
+// MarshalJSON implements json.Marshaler. The message is encoded as a JSON object
+// with the message keys in order. Sections are encoded as objects, lists as arrays of
+// strings, and all other values as strings. Like String, secrets are redacted using
+// DefaultRedactor.
+func (m *Message) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+
+	var buf bytes.Buffer
+
+	if err := DefaultRedactor.Redact(m).encodeJSON(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m *Message) encodeJSON(buf *bytes.Buffer) error {
+	// Leave escaping HTML to the caller's encoder.
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+
+	encode := func(v any) error {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+
+		// Drop the newline added by Encode.
+		buf.Truncate(buf.Len() - 1)
+
+		return nil
+	}
+
+	buf.WriteByte('{')
+
+	first := true
+	for k, v := range m.elements() {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+
+		if err := encode(k); err != nil {
+			return err
+		}
+		buf.WriteByte(':')
+
+		if section, ok := v.(*Message); ok {
+			if err := section.encodeJSON(buf); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := encode(v); err != nil {
+			return err
+		}
+	}
+
+	buf.WriteByte('}')
+
+	return nil
 }
 
 // packetIsNamed returns a bool indicating the packet is a named type
@@ -525,28 +604,89 @@ func (m *Message) encode() ([]byte, error) {
 		return nil, fmt.Errorf("%v: cannot encode invalid packet", errEncoding)
 	}
 
-	if err := buf.WriteByte(m.header.ptype); err != nil {
-		return nil, fmt.Errorf("%v: %v", errEncoding, err)
+	if err := m.encodeHeader(buf); err != nil {
+		return nil, err
 	}
 
-	if m.packetIsNamed() {
-		if err := encodeKey(buf, m.header.name); err != nil {
-			return nil, err
-		}
+	if err := m.encodeElements(buf); err != nil {
+		return nil, err
 	}
 
-	if err := m.encodeElements(buf); err != nil {
+	return buf.Bytes(), nil
+}
+
+// encodePacket encodes m, and prepends the packet length as it is
+// written on the wire.
+func (m *Message) encodePacket() ([]byte, error) {
+	buf := bytes.NewBuffer([]byte{})
+
+	b, err := m.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the packet length
+	if err := safePutUint32(buf, len(b)); err != nil {
+		return nil, err
+	}
+
+	// Write the payload
+	if _, err := buf.Write(b); err != nil {
 		return nil, err
 	}
 
 	return buf.Bytes(), nil
 }
 
-func (m *Message) decode(data []byte) error {
+// readPacket reads a single length-prefixed packet from r, and decodes it.
+func readPacket(r io.Reader) (*Message, error) {
+	p := NewMessage()
+
+	buf := make([]byte, 4 /* header length */)
+	_, err := io.ReadFull(r, buf)
+	if err != nil {
+		return nil, err
+	}
+	pl := binary.BigEndian.Uint32(buf)
+
+	// Do not trust the length prefix enough to allocate it all up front,
+	// a bogus length could otherwise cost up to 4GiB. Let the buffer grow
+	// as the data actually arrives.
+	data := bytes.NewBuffer([]byte{})
+	if _, err := io.CopyN(data, r, int64(pl)); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	if err := p.decode(data.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// encodeHeader encodes the packet type, and name if applicable, to the buffer.
+func (m *Message) encodeHeader(buf *bytes.Buffer) error {
+	if err := buf.WriteByte(m.header.ptype); err != nil {
+		return fmt.Errorf("%v: %v", errEncoding, err)
+	}
+
+	if m.packetIsNamed() {
+		if err := encodeKey(buf, m.header.name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeHeader decodes the packet type, and name if applicable, from the buffer.
+func (m *Message) decodeHeader(buf *bytes.Buffer) error {
 	m.header = &header{}
-	buf := bytes.NewBuffer(data)
 
-	// Parse the message header first.
 	b, err := buf.ReadByte()
 	if err != nil {
 		return fmt.Errorf("%v: %v", errDecoding, err)
@@ -573,8 +713,24 @@ func (m *Message) decode(data []byte) error {
 		m.header.name = string(name)
 	}
 
+	return nil
+}
+
+func (m *Message) decode(data []byte) error {
+	buf := bytes.NewBuffer(data)
+
+	// Parse the message header first.
+	if err := m.decodeHeader(buf); err != nil {
+		return err
+	}
+
+	return m.decodeElements(buf)
+}
+
+// decodeElements decodes all of the message elements from the buffer.
+func (m *Message) decodeElements(buf *bytes.Buffer) error {
 	for buf.Len() > 0 {
-		b, err = buf.ReadByte()
+		b, err := buf.ReadByte()
 		if err != nil && err != io.EOF {
 			return fmt.Errorf("%v: %v", errDecoding, err)
 		}
@@ -1081,38 +1237,17 @@ func (m *Message) unmarshalToMap(rv reflect.Value) error {
 		key := reflect.ValueOf(k)
 		val := reflect.ValueOf(v)
 
-		rfv := rv.MapIndex(key)
 		mapElemType := rv.Type().Elem()
-
-		// We can't set the value of a map value directly since they are not
-		// addressable. Thus we must create a new value element and set that
-		// to the map instead.
-		switch mapElemType.Kind() {
-		case reflect.Ptr:
-			if !rfv.IsValid() {
-				rfv = reflect.New(mapElemType.Elem())
-			}
-
-		case reflect.Struct:
-			if !rfv.IsValid() {
-				rfv = reflect.Indirect(reflect.New(mapElemType))
-			}
-
-		case reflect.Slice:
-			if !rfv.IsValid() {
-				rfv = reflect.MakeSlice(mapElemType, 0, 0)
-			}
-
-		case reflect.Map:
-			if !rfv.IsValid() {
-				rfv = reflect.MakeMap(mapElemType)
-			}
-
-		case reflect.Interface:
+		if mapElemType.Kind() == reflect.Interface {
 			return fmt.Errorf("%v: map values cannot be generic interfaces", errUnmarshalTypeMismatch)
+		}
 
-		default:
-			rfv = reflect.Indirect(reflect.New(mapElemType))
+		// We can't set the value of a map value directly since they are not
+		// addressable. Thus we must create a new value element, initialized to
+		// the existing map value if there is one, and set that to the map instead.
+		rfv := reflect.New(mapElemType).Elem()
+		if existing := rv.MapIndex(key); existing.IsValid() {
+			rfv.Set(existing)
 		}
 
 		err := m.unmarshalField(rfv, val)
@@ -1181,10 +1316,16 @@ func (m *Message) unmarshalField(field reflect.Value, rv reflect.Value) error {
 		if _, ok := rv.Interface().([]string); !ok {
 			return fmt.Errorf("%v: []string and %v", errUnmarshalTypeMismatch, rv.Type())
 		}
+		if !rv.Type().AssignableTo(field.Type()) {
+			return fmt.Errorf("%v: %v and %v", errUnmarshalTypeMismatch, field.Type(), rv.Type())
+		}
 		field.Set(rv)
 
 	case reflect.Ptr:
 		if _, ok := field.Interface().(*Message); ok {
+			if _, ok := rv.Interface().(*Message); !ok {
+				return fmt.Errorf("%v: %v", errUnmarshalNonMessage, rv.Type())
+			}
 			field.Set(rv)
 
 			return nil
@@ -0,0 +1,158 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Builder constructs a Message using a chain of method calls. Errors encountered
+// while building the message are collected, and returned together by Builder.Message,
+// so that the result of each step does not need to be checked.
+//
+// A Builder is created with Build, and always operates on its current section. The
+// current section starts out as the top level of the message, Builder.Section opens
+// a new sub-section and makes it the current section, and Builder.End closes it again.
+// For example, the message for a "load-conn" command can be built with:
+//
+//	m, err := vici.Build().
+//		Section("gw").
+//		Set("version", 2).
+//		List("local_addrs", "192.0.2.1").
+//		List("remote_addrs", "198.51.100.1").
+//		Section("children").
+//		Section("net").
+//		List("local_ts", "10.1.0.0/16").
+//		End().
+//		End().
+//		End().
+//		Message()
+//
+// Unlike Message.Set, each key may only be used once in a section.
+type Builder struct {
+	root *Message
+
+	// Stack of the sections currently open, and their keys. The last
+	// element is the current section.
+	sections []*Message
+	path     []string
+
+	errs []error
+}
+
+// Build returns a Builder for a new, empty, Message.
+func Build() *Builder {
+	root := NewMessage()
+
+	return &Builder{
+		root:     root,
+		sections: []*Message{root},
+		path:     make([]string, 0),
+	}
+}
+
+// Set sets key to value in the current section. The supported types of value are the
+// same as for Message.Set. If value is a nil pointer, it is not added to the message.
+func (b *Builder) Set(key string, value any) *Builder {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return b
+	}
+
+	// Convert the value to its message element type first, and then
+	// add it to the section as a unique key.
+	tmp := NewMessage()
+	if err := tmp.marshalField(key, rv); err != nil {
+		b.fail(key, err)
+
+		return b
+	}
+	b.add(key, tmp.Get(key))
+
+	return b
+}
+
+// List sets key to the list of items in the current section.
+func (b *Builder) List(key string, items ...string) *Builder {
+	b.add(key, slices.Clone(items))
+
+	return b
+}
+
+// Section adds a new sub-section identified by key to the current section, and makes
+// it the current section.
+func (b *Builder) Section(key string) *Builder {
+	section := NewMessage()
+	b.add(key, section)
+
+	// Even if the section could not be added, keep track of it so that
+	// the following calls to End still line up with the caller's intent.
+	b.sections = append(b.sections, section)
+	b.path = append(b.path, key)
+
+	return b
+}
+
+// End closes the current section, and makes its parent the current section.
+func (b *Builder) End() *Builder {
+	if len(b.path) == 0 {
+		b.errs = append(b.errs, errors.New("vici: End called without matching Section"))
+
+		return b
+	}
+
+	b.sections = b.sections[:len(b.sections)-1]
+	b.path = b.path[:len(b.path)-1]
+
+	return b
+}
+
+// Message returns the built Message, or the errors encountered while building it.
+// Any sections that are still open are closed. Further use of the Builder modifies
+// the returned Message.
+func (b *Builder) Message() (*Message, error) {
+	b.sections = b.sections[:1]
+	b.path = b.path[:0]
+
+	if len(b.errs) > 0 {
+		return nil, errors.Join(b.errs...)
+	}
+
+	return b.root, nil
+}
+
+func (b *Builder) add(key string, value any) {
+	section := b.sections[len(b.sections)-1]
+
+	if err := section.addItemUnique(key, value); err != nil {
+		b.fail(key, err)
+	}
+}
+
+func (b *Builder) fail(key string, err error) {
+	path := strings.Join(append(slices.Clone(b.path), key), ".")
+
+	b.errs = append(b.errs, fmt.Errorf("vici: cannot build %s: %w", path, err))
+}
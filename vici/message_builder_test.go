@@ -0,0 +1,215 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	m, err := Build().
+		Set("key", "value").
+		List("list", "item1", "item2").
+		Section("section1").
+		Set("key", "key2").
+		End().
+		Section("section2").
+		List("list", "item3", "item4").
+		End().
+		Message()
+	if err != nil {
+		t.Fatalf("Unexpected error building message: %v", err)
+	}
+
+	want := &Message{
+		keys: []string{"key", "list", "section1", "section2"},
+		data: map[string]any{
+			"key":      "value",
+			"list":     []string{"item1", "item2"},
+			"section1": goldMarshaled.data["section1"],
+			"section2": goldMarshaled.data["section2"],
+		},
+	}
+
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("Built message does not equal expected message.\nExpected: %v\nReceived: %v", want, m)
+	}
+}
+
+func TestBuilderSetTypeConversion(t *testing.T) {
+	var nilInt *int
+
+	m, err := Build().
+		Set("int", 2).
+		Set("bool", true).
+		Set("nil", nilInt).
+		Set("struct", testSection{Key: "key"}).
+		Message()
+	if err != nil {
+		t.Fatalf("Unexpected error building message: %v", err)
+	}
+
+	if !reflect.DeepEqual(m.Keys(), []string{"int", "bool", "struct"}) {
+		t.Fatalf("Unexpected message keys: %v", m.Keys())
+	}
+
+	if m.Get("int") != "2" || m.Get("bool") != "yes" {
+		t.Fatalf("Unexpected message contents: %v", m)
+	}
+
+	if s, ok := m.Get("struct").(*Message); !ok || s.Get("key") != "key" {
+		t.Fatalf("Unexpected message contents: %v", m)
+	}
+}
+
+func TestBuilderUnclosedSections(t *testing.T) {
+	m, err := Build().Section("conns").Section("gw").Set("version", 2).Message()
+	if err != nil {
+		t.Fatalf("Unexpected error building message: %v", err)
+	}
+
+	conns, ok := m.Get("conns").(*Message)
+	if !ok {
+		t.Fatalf("Expected section conns in %v", m)
+	}
+
+	gw, ok := conns.Get("gw").(*Message)
+	if !ok || gw.Get("version") != "2" {
+		t.Fatalf("Expected section gw with version=2 in %v", m)
+	}
+}
+
+func TestBuilderMessageClosesSections(t *testing.T) {
+	b := Build().Section("conns").Section("gw")
+	if _, err := b.Message(); err != nil {
+		t.Fatalf("Unexpected error building message: %v", err)
+	}
+
+	// The sections were closed by Message, so further keys are added to
+	// the top level, and there is no section left to End.
+	m, err := b.Set("version", 2).Message()
+	if err != nil {
+		t.Fatalf("Unexpected error building message: %v", err)
+	}
+
+	if m.Get("version") != "2" {
+		t.Fatalf("Expected version=2 at the top level of %v", m)
+	}
+
+	if _, err := b.End().Message(); err == nil {
+		t.Fatalf("Expected error from End after Message closed all sections")
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	m, err := Build().
+		Set("key", "value").
+		Set("key", "again").
+		Section("section").
+		Set("bad", 1.5).
+		End().
+		End().
+		Message()
+	if err == nil {
+		t.Fatalf("Expected errors building message, got %v", m)
+	}
+
+	if m != nil {
+		t.Fatalf("Expected nil message on error, got %v", m)
+	}
+
+	// Each of the errors should be reported, with the path to the key.
+	for _, want := range []string{
+		"key key already exists",
+		"section.bad",
+		"End called without matching Section",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) || len(joined.Unwrap()) != 3 {
+		t.Fatalf("Expected 3 errors, got: %v", err)
+	}
+}
+
+func TestBuilderDuplicateSection(t *testing.T) {
+	_, err := Build().
+		Section("conns").
+		End().
+		Section("conns").
+		Set("key", "value").
+		End().
+		Message()
+	if err == nil {
+		t.Fatal("Expected error when adding duplicate section")
+	}
+
+	if !strings.Contains(err.Error(), "conns") {
+		t.Fatalf("Expected error to reference conns, got: %v", err)
+	}
+}
+
+func ExampleBuild() {
+	m, err := Build().
+		Section("gw").
+		Set("version", 2).
+		List("local_addrs", "192.0.2.1").
+		List("remote_addrs", "198.51.100.1").
+		Section("local").
+		Set("auth", "pubkey").
+		End().
+		Section("children").
+		Section("net").
+		List("local_ts", "10.1.0.0/16").
+		End().
+		End().
+		End().
+		Message()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Print(m)
+	// Output:
+	// {
+	//   gw {
+	//     version = 2
+	//     local_addrs = 192.0.2.1
+	//     remote_addrs = 198.51.100.1
+	//     local {
+	//       auth = pubkey
+	//     }
+	//     children {
+	//       net {
+	//         local_ts = 10.1.0.0/16
+	//       }
+	//     }
+	//   }
+	// }
+}
@@ -0,0 +1,489 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The conformance corpus in testdata/conformance contains one framed packet per
+// file, exactly as it is transmitted on the vici socket (i.e. including the 4 byte
+// length prefix). There is a file for the request and response of every command, and
+// for every event type, described in the vici README. The file names encode what the
+// packet is expected to be:
+//
+//	<command>.request	CMD_REQUEST named <command>
+//	<command>.response	CMD_RESPONSE
+//	<event>.event		EVENT named <event>
+//
+// Each packet has a corresponding .golden file holding the decoded form of the packet.
+// Run the tests with -update to re-generate the golden files after adding packets.
+//
+// The packets listed in testdata/conformance/captured.txt were captured from charon
+// by TestConformanceCapture, the others are synthetic, see
+// testdata/conformance/README.md.
+var (
+	updateGolden = flag.Bool("update", false, "Update the golden files of the conformance corpus")
+)
+
+// conformanceCorpus returns the framed packets of the conformance corpus, keyed by
+// file name without extension.
+func conformanceCorpus(tb testing.TB) map[string][]byte {
+	tb.Helper()
+
+	files, err := filepath.Glob(filepath.Join("testdata", "conformance", "*.vici"))
+	if err != nil {
+		tb.Fatalf("Failed to list conformance corpus: %v", err)
+	}
+
+	if len(files) == 0 {
+		tb.Fatal("Conformance corpus is empty")
+	}
+
+	corpus := make(map[string][]byte)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			tb.Fatalf("Failed to read %s: %v", file, err)
+		}
+
+		corpus[strings.TrimSuffix(filepath.Base(file), ".vici")] = data
+	}
+
+	return corpus
+}
+
+func describePacket(p *Message) string {
+	h := strings.TrimSpace(pktNames[p.header.ptype] + " " + p.header.name)
+
+	// Do not redact, the golden files check decoded secrets too.
+	return fmt.Sprintf("%s\n%s", h, p.stringIndent("", "  "))
+}
+
+func checkConformancePacketType(t *testing.T, name string, p *Message) {
+	base, kind, _ := strings.Cut(name, ".")
+
+	var (
+		ptype = p.header.ptype
+		ok    bool
+	)
+
+	switch kind {
+	case "request":
+		ok = ptype == pktCmdRequest && p.header.name == base
+	case "response":
+		ok = ptype == pktCmdResponse
+	case "event":
+		ok = ptype == pktEvent && p.header.name == base
+	case "register":
+		ok = ptype == pktEventRegister && p.header.name == base
+	case "unregister":
+		ok = ptype == pktEventUnregister && p.header.name == base
+	case "confirm":
+		ok = ptype == pktEventConfirm
+	case "unknown":
+		ok = ptype == pktCmdUnknown && base == "cmd" || ptype == pktEventUnknown && base == "event"
+	}
+
+	if !ok {
+		t.Fatalf("Packet type %s %q does not match file name %s", pktNames[ptype], p.header.name, name)
+	}
+}
+
+// conformanceName returns the corpus file name of a captured packet, given the
+// last command requested, e.g. "version.response".
+func conformanceName(p *Message, cmd string) string {
+	switch p.header.ptype {
+	case pktCmdRequest:
+		return p.header.name + ".request"
+	case pktCmdResponse:
+		return cmd + ".response"
+	case pktCmdUnknown:
+		return "cmd.unknown"
+	case pktEventRegister:
+		return p.header.name + ".register"
+	case pktEventUnregister:
+		return p.header.name + ".unregister"
+	case pktEventConfirm:
+		return "event.confirm"
+	case pktEventUnknown:
+		return "event.unknown"
+	default:
+		return p.header.name + ".event"
+	}
+}
+
+// conformanceCredentials returns a self-signed CA certificate in PEM form, and
+// its private key in DER form, for loading into charon.
+func conformanceCredentials(t *testing.T) (string, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "govici conformance CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), keyDER
+}
+
+// TestConformanceCapture makes the requests of the conformance corpus to charon
+// with WithCapture, and makes sure that every packet charon sends re-encodes to
+// the exact same bytes. With -update, the captured packets replace the packets of
+// the same name in the corpus, and are listed in captured.txt. Run TestConformance
+// with -update afterwards, e.g. in the same run, to update the golden files.
+//
+// The SA events, list-sa and list-policy can only be captured while charon has SAs
+// or installed policies, so they may remain synthetic.
+func TestConformanceCapture(t *testing.T) {
+	maybeSkipIntegrationTest(t)
+
+	var capture bytes.Buffer
+
+	s, err := NewSession(WithCapture(&capture))
+	if err != nil {
+		t.Fatalf("Failed to create a session: %v", err)
+	}
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cert, key := conformanceCredentials(t)
+
+	build := func(b *Builder) *Message {
+		t.Helper()
+
+		m, err := b.Message()
+		if err != nil {
+			t.Fatalf("Failed to build message: %v", err)
+		}
+
+		return m
+	}
+
+	// The outcome of the requests does not matter, failed requests get
+	// responses too.
+	call := func(cmd string, in *Message) *Message {
+		out, _ := s.Call(ctx, cmd, in)
+
+		return out
+	}
+	stream := func(cmd, event string, in *Message) {
+		for range s.CallStreaming(ctx, cmd, event, in) {
+		}
+	}
+
+	version := call("version", nil)
+	for _, cmd := range []string{"stats", "get-algorithms", "get-counters", "reset-counters", "reload-settings"} {
+		call(cmd, nil)
+	}
+
+	// The log event is registered by subscribing, and an unknown command
+	// and event get the unknown responses.
+	if err := s.Subscribe("log"); err != nil {
+		t.Fatalf("Failed to subscribe to log: %v", err)
+	}
+	call("reload-settings", nil)
+	call("conformance-unknown", nil)
+	// nolint
+	_ = s.Subscribe("conformance-unknown")
+
+	call("load-cert", build(Build().Set("type", "X509").Set("flag", "CA").Set("data", cert)))
+	stream("list-certs", "list-cert", nil)
+
+	if out := call("load-key", build(Build().Set("type", "ecdsa").Set("data", string(key)))); out != nil {
+		call("get-keys", nil)
+		call("unload-key", build(Build().Set("id", out.Get("id"))))
+	}
+
+	call("load-token", build(Build().Set("handle", "0123").Set("pin", "1234")))
+
+	call("load-shared", build(Build().
+		Set("id", "conformance").
+		Set("type", "IKE").
+		Set("data", "conformance-secret").
+		List("owners", "conformance@example.org")))
+	call("get-shared", nil)
+	call("unload-shared", build(Build().Set("id", "conformance")))
+
+	call("load-authority", build(Build().Section("conformance").Set("cacert", cert)))
+	stream("list-authorities", "list-authority", nil)
+	call("get-authorities", nil)
+	call("unload-authority", build(Build().Set("name", "conformance")))
+
+	call("load-pool", build(Build().Section("conformance").Set("addrs", "10.255.0.0/24")))
+	call("get-pools", nil)
+	call("unload-pool", build(Build().Set("name", "conformance")))
+
+	call("load-conn", build(Build().
+		Section("conformance").
+		List("local_addrs", "127.0.0.1").
+		List("remote_addrs", "127.0.0.2").
+		Section("local").Set("auth", "psk").End().
+		Section("remote").Set("auth", "psk").End().
+		Section("children").
+		Section("net").
+		List("local_ts", "10.1.0.0/16").
+		List("remote_ts", "10.2.0.0/16")))
+	stream("list-conns", "list-conn", nil)
+	call("get-conns", nil)
+
+	call("install", build(Build().Set("ike", "conformance").Set("child", "net")))
+	stream("list-policies", "list-policy", build(Build().Set("trap", "yes")))
+	call("uninstall", build(Build().Set("child", "net")))
+
+	stream("list-sas", "list-sa", nil)
+	stream("initiate", "control-log", build(Build().Set("child", "conformance-missing").Set("timeout", 1000)))
+	stream("terminate", "control-log", build(Build().Set("ike", "conformance-missing").Set("timeout", 1000)))
+	call("rekey", build(Build().Set("ike", "conformance-missing")))
+	call("redirect", build(Build().Set("ike", "conformance-missing").Set("gateway", "192.0.2.1")))
+	call("unload-conn", build(Build().Set("name", "conformance")))
+
+	call("flush-certs", nil)
+	call("clear-creds", nil)
+
+	if err := s.Unsubscribe("log"); err != nil {
+		t.Fatalf("Failed to unsubscribe from log: %v", err)
+	}
+
+	// Read the raw records of the capture, see WithCapture, so that the
+	// packets are compared with the exact bytes charon sent.
+	corpus := conformanceCorpus(t)
+	captured := make(map[string][]byte)
+
+	data := bytes.TrimPrefix(capture.Bytes(), []byte(captureMagic))
+
+	var cmd string
+
+	for len(data) >= 13 {
+		n := 13 + int(binary.BigEndian.Uint32(data[9:13]))
+		if len(data) < n {
+			break
+		}
+		raw := data[9:n]
+		data = data[n:]
+
+		p, err := readPacket(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("Failed to read captured packet: %v", err)
+		}
+
+		if p.header.ptype == pktCmdRequest {
+			cmd = p.header.name
+		}
+
+		name := conformanceName(p, cmd)
+
+		b, err := p.encodePacket()
+		if err != nil {
+			t.Fatalf("Failed to re-encode %s: %v", name, err)
+		}
+
+		if !bytes.Equal(b, raw) {
+			t.Errorf("Re-encoded %s does not equal the captured packet.\nExpected: %v\nReceived: %v", name, raw, b)
+		}
+
+		if _, ok := corpus[name]; ok && captured[name] == nil {
+			captured[name] = raw
+		}
+	}
+
+	var names []string
+	for name := range corpus {
+		if captured[name] == nil {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	t.Logf("Captured %d of %d packets of the corpus, not captured: %s", len(captured), len(corpus), strings.Join(names, " "))
+
+	if !*updateGolden {
+		return
+	}
+
+	names = names[:0]
+	for name, raw := range captured {
+		if err := os.WriteFile(filepath.Join("testdata", "conformance", name+".vici"), raw, 0o600); err != nil {
+			t.Fatalf("Failed to update %s: %v", name, err)
+		}
+
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	list := fmt.Sprintf("# Captured from %s %s by TestConformanceCapture.\n%s\n",
+		version.Get("daemon"), version.Get("version"), strings.Join(names, "\n"))
+
+	if err := os.WriteFile(filepath.Join("testdata", "conformance", "captured.txt"), []byte(list), 0o600); err != nil {
+		t.Fatalf("Failed to update captured.txt: %v", err)
+	}
+}
+
+// TestConformance decodes every packet in the conformance corpus, and makes sure
+// that they are decoded as expected, and re-encode to the exact same bytes.
+func TestConformance(t *testing.T) {
+	for name, data := range conformanceCorpus(t) {
+		t.Run(name, func(t *testing.T) {
+			r := bytes.NewReader(data)
+
+			p, err := readPacket(r)
+			if err != nil {
+				t.Fatalf("Failed to read packet: %v", err)
+			}
+
+			if r.Len() != 0 {
+				t.Fatalf("Packet length does not cover the file, %d bytes remaining", r.Len())
+			}
+
+			checkConformancePacketType(t, name, p)
+
+			b, err := p.encodePacket()
+			if err != nil {
+				t.Fatalf("Failed to re-encode packet: %v", err)
+			}
+
+			if !bytes.Equal(b, data) {
+				t.Fatalf("Re-encoded packet does not equal original.\nExpected: %v\nReceived: %v", data, b)
+			}
+
+			golden := filepath.Join("testdata", "conformance", name+".golden")
+			got := describePacket(p)
+
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(got), 0o600); err != nil {
+					t.Fatalf("Failed to update golden file: %v", err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Failed to read golden file: %v", err)
+			}
+
+			if got != string(want) {
+				t.Fatalf("Decoded packet does not match %s.\nExpected:\n%s\nReceived:\n%s", golden, want, got)
+			}
+		})
+	}
+}
+
+// FuzzDecodeHeader fuzzes the decoding of packet headers, and makes sure that
+// successfully decoded headers encode to the same bytes.
+func FuzzDecodeHeader(f *testing.F) {
+	f.Add(goldNamedPacketBytes)
+	f.Add(goldUnnamedPacketBytes)
+
+	for _, data := range conformanceCorpus(f) {
+		f.Add(data[4:])
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m := NewMessage()
+		buf := bytes.NewBuffer(data)
+
+		if err := m.decodeHeader(buf); err != nil {
+			return
+		}
+
+		if !m.packetIsValid() {
+			t.Fatalf("Decoded header is invalid: %+v", m.header)
+		}
+
+		consumed := data[:len(data)-buf.Len()]
+
+		encoded := bytes.NewBuffer([]byte{})
+		if err := m.encodeHeader(encoded); err != nil {
+			t.Fatalf("Error re-encoding the header: %v\n\nheader: %+v", err, m.header)
+		}
+
+		if !bytes.Equal(encoded.Bytes(), consumed) {
+			t.Fatalf("Re-encoded header does not match the original:\n\nencoded: %v\n\ndata: %v", encoded.Bytes(), consumed)
+		}
+	})
+}
+
+// FuzzReadPacket fuzzes the reading of length-prefixed packets off the wire.
+func FuzzReadPacket(f *testing.F) {
+	for _, data := range conformanceCorpus(f) {
+		f.Add(data)
+	}
+
+	// A length prefix that is much longer than the data.
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, pktCmdResponse})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := bytes.NewReader(data)
+
+		p, err := readPacket(r)
+		if err != nil {
+			return
+		}
+
+		consumed := data[:len(data)-r.Len()]
+
+		encoded, err := p.encodePacket()
+		if err != nil {
+			t.Fatalf("Error re-encoding the packet: %v\n\npacket: %+v", err, p)
+		}
+
+		if !bytes.Equal(encoded, consumed) {
+			t.Fatalf("Re-encoded packet does not match the original:\n\nencoded: %v\n\ndata: %v", encoded, consumed)
+		}
+	})
+}
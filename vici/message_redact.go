@@ -0,0 +1,159 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Redactor replaces the values of message elements which hold secrets with a
+// placeholder. The elements which are always redacted are:
+//
+//   - data, e.g. the private key of a load-key request or the shared secret of
+//     a load-shared request, unless the type in the same section names a
+//     certificate type, as in load-cert requests and list-cert events.
+//   - pin, e.g. of a load-token request.
+//   - secret, passphrase and password.
+//
+// These keys are redacted in any section. Additional elements are redacted by
+// giving Patterns.
+//
+// A Redactor must not be modified while it is in use.
+type Redactor struct {
+	// Patterns match the paths of additional elements to redact, using
+	// path.Match. The path of an element is its key, prefixed with the keys
+	// of the sections containing it, separated by slashes, e.g.
+	// "conn/local-1/id". A pattern without a slash matches elements with
+	// that key in any section.
+	Patterns []string
+
+	// KeepLength makes the placeholder include the length of the redacted
+	// value, e.g. "<redacted:44>", instead of only "<redacted>".
+	KeepLength bool
+}
+
+// DefaultRedactor is the Redactor used by Message.String, Message.MarshalJSON,
+// and for payload logging if a Session is not given WithRedactor. It only
+// redacts the elements that are always redacted. Patterns may be added to it
+// during program initialization.
+var DefaultRedactor = &Redactor{}
+
+// secretKeys are the keys whose values are always redacted, in any section.
+var secretKeys = map[string]struct{}{
+	"pin":        {},
+	"secret":     {},
+	"passphrase": {},
+	"password":   {},
+}
+
+// certificateTypes are the values of type for which the data in the same
+// section holds a certificate, and is not redacted.
+var certificateTypes = map[string]struct{}{
+	"x509":          {},
+	"x509_ac":       {},
+	"x509_crl":      {},
+	"ocsp_response": {},
+	"pubkey":        {},
+}
+
+const redactedValue = "<redacted>"
+
+// Redact returns a copy of m in which the values of the elements that hold
+// secrets are replaced. A redacted list is replaced by a list of placeholders
+// with the same number of items. The message m is not modified.
+func (r *Redactor) Redact(m *Message) *Message {
+	if m == nil {
+		return nil
+	}
+
+	return r.redact(m, "")
+}
+
+func (r *Redactor) redact(m *Message, prefix string) *Message {
+	out := NewMessage()
+
+	for k, v := range m.elements() {
+		p := k
+		if prefix != "" {
+			p = prefix + "/" + k
+		}
+
+		switch vv := v.(type) {
+		case *Message:
+			v = r.redact(vv, p)
+		case string:
+			if r.isSecret(m, k, p) {
+				v = r.placeholder(vv)
+			}
+		case []string:
+			if r.isSecret(m, k, p) {
+				list := make([]string, len(vv))
+				for i, item := range vv {
+					list[i] = r.placeholder(item)
+				}
+				v = list
+			}
+		}
+
+		// nolint
+		_ = out.addItem(k, v)
+	}
+
+	return out
+}
+
+// isSecret reports whether the element of section m with key k and path p
+// must be redacted.
+func (r *Redactor) isSecret(m *Message, k, p string) bool {
+	if _, ok := secretKeys[k]; ok {
+		return true
+	}
+
+	if k == "data" {
+		t, _ := m.Get("type").(string)
+		if _, ok := certificateTypes[strings.ToLower(t)]; !ok {
+			return true
+		}
+	}
+
+	for _, pattern := range r.Patterns {
+		name := p
+		if !strings.Contains(pattern, "/") {
+			name = k
+		}
+
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Redactor) placeholder(v string) string {
+	if !r.KeepLength {
+		return redactedValue
+	}
+
+	return "<redacted:" + strconv.Itoa(len(v)) + ">"
+}
@@ -0,0 +1,219 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactorRedact(t *testing.T) {
+	m, err := Build().
+		Set("type", "IKE").
+		Set("data", "very-secret").
+		Set("owners", []string{"alice", "bob"}).
+		Section("token").
+		Set("handle", "0a").
+		Set("pin", "1234").
+		Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := DefaultRedactor.Redact(m)
+
+	if got := r.Get("data"); got != redactedValue {
+		t.Fatalf("Expected data to be redacted, got %v", got)
+	}
+
+	if got := r.Get("type"); got != "IKE" {
+		t.Fatalf("Expected type to be kept, got %v", got)
+	}
+
+	token, ok := r.Get("token").(*Message)
+	if !ok {
+		t.Fatalf("Expected token section in %s", r)
+	}
+
+	if got := token.Get("pin"); got != redactedValue {
+		t.Fatalf("Expected pin to be redacted, got %v", got)
+	}
+
+	if got := token.Get("handle"); got != "0a" {
+		t.Fatalf("Expected handle to be kept, got %v", got)
+	}
+
+	if !reflect.DeepEqual(r.Keys(), m.Keys()) {
+		t.Fatalf("Expected keys %v, got %v", m.Keys(), r.Keys())
+	}
+
+	// The original message must not be modified.
+	if got := m.Get("data"); got != "very-secret" {
+		t.Fatalf("Original message was modified: %v", got)
+	}
+}
+
+func TestRedactorCertificateData(t *testing.T) {
+	for _, typ := range []string{"X509", "x509_crl", "PUBKEY"} {
+		m, err := Build().Set("type", typ).Set("data", "cert").Message()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := DefaultRedactor.Redact(m).Get("data"); got != "cert" {
+			t.Errorf("Expected data of type %s to be kept, got %v", typ, got)
+		}
+	}
+
+	for _, typ := range []string{"rsa", "EAP", ""} {
+		m, err := Build().Set("type", typ).Set("data", "key").Message()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := DefaultRedactor.Redact(m).Get("data"); got != redactedValue {
+			t.Errorf("Expected data of type %q to be redacted, got %v", typ, got)
+		}
+	}
+}
+
+func TestRedactorPatterns(t *testing.T) {
+	m, err := Build().
+		Set("id", "top").
+		Section("conn").
+		Set("id", "nested").
+		Section("local-1").
+		Set("id", "local").
+		Set("certs", []string{"a.pem", "bc.pem"}).
+		Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		patterns []string
+		keep     bool
+		path     []string
+		expected any
+	}{
+		{
+			patterns: []string{"id"},
+			path:     []string{"conn", "local-1", "id"},
+			expected: redactedValue,
+		},
+		{
+			patterns: []string{"conn/id"},
+			path:     []string{"id"},
+			expected: "top",
+		},
+		{
+			patterns: []string{"conn/id"},
+			path:     []string{"conn", "id"},
+			expected: redactedValue,
+		},
+		{
+			patterns: []string{"conn/local-*/id"},
+			path:     []string{"conn", "id"},
+			expected: "nested",
+		},
+		{
+			patterns: []string{"conn/local-*/id"},
+			path:     []string{"conn", "local-1", "id"},
+			expected: redactedValue,
+		},
+		{
+			patterns: []string{"cert?"},
+			keep:     true,
+			path:     []string{"conn", "local-1", "certs"},
+			expected: []string{"<redacted:5>", "<redacted:6>"},
+		},
+		{
+			patterns: []string{"[bad"},
+			path:     []string{"id"},
+			expected: "top",
+		},
+	}
+
+	for _, tt := range tests {
+		r := (&Redactor{Patterns: tt.patterns, KeepLength: tt.keep}).Redact(m)
+
+		for _, k := range tt.path[:len(tt.path)-1] {
+			r = r.Get(k).(*Message)
+		}
+
+		if got := r.Get(tt.path[len(tt.path)-1]); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Patterns %v: expected %s to be %v, got %v",
+				tt.patterns, strings.Join(tt.path, "/"), tt.expected, got)
+		}
+	}
+}
+
+func TestMessageStringRedacted(t *testing.T) {
+	m, err := Build().Set("type", "IKE").Set("data", "very-secret").Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := m.String()
+
+	if strings.Contains(s, "very-secret") {
+		t.Fatalf("String contains secret: %s", s)
+	}
+
+	if !strings.Contains(s, "data = "+redactedValue) {
+		t.Fatalf("Expected redacted data in %s", s)
+	}
+}
+
+func TestMessageMarshalJSON(t *testing.T) {
+	m, err := Build().
+		Set("type", "EAP").
+		Set("data", "very-secret").
+		Set("owners", []string{"alice", "bob"}).
+		Section("b").
+		Set("quote", `"x"`).
+		Set("empty", []string{}).
+		Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `{"type":"EAP","data":"\u003credacted\u003e","owners":["alice","bob"],"b":{"quote":"\"x\"","empty":[]}}`
+	if string(b) != expected {
+		t.Fatalf("Expected %s, got %s", expected, b)
+	}
+
+	b, err = json.Marshal(struct{ M *Message }{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(b) != `{"M":null}` {
+		t.Fatalf("Expected nil message to be null, got %s", b)
+	}
+}
@@ -823,6 +823,10 @@ func FuzzEncodeDecode(f *testing.F) {
 		f.Add(tc)
 	}
 
+	for _, data := range conformanceCorpus(f) {
+		f.Add(data[4:])
+	}
+
 	f.Fuzz(func(t *testing.T, data []byte) {
 		m := NewMessage()
 
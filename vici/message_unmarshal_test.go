@@ -21,6 +21,7 @@
 package vici
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -426,3 +427,78 @@ func TestUnmarshalInlineComposite(t *testing.T) {
 		t.Fatalf("Unmarshalled inlined embedded value is invalid.\nExpected: %+v\nReceived: %+v", otherValue, inlineMessage.Other)
 	}
 }
+
+type fuzzUnmarshalSection struct {
+	Name     string            `vici:"name"`
+	Uniqueid uint64            `vici:"uniqueid"`
+	Version  *int              `vici:"version"`
+	LocalTS  []string          `vici:"local-ts"`
+	Children map[string]string `vici:"child-sas"`
+}
+
+type fuzzUnmarshalMessage struct {
+	Success  bool                             `vici:"success"`
+	Errmsg   string                           `vici:"errmsg"`
+	Up       *bool                            `vici:"up"`
+	Level    int8                             `vici:"level"`
+	Port     uint16                           `vici:"local-port"`
+	Conns    []string                         `vici:"conns"`
+	Raw      *Message                         `vici:"raw"`
+	Section  fuzzUnmarshalSection             `vici:"section"`
+	SectionP *fuzzUnmarshalSection            `vici:"sectionp"`
+	Map      map[string]*fuzzUnmarshalSection `vici:"map"`
+	Inline   fuzzUnmarshalSection             `vici:",inline"`
+}
+
+// FuzzUnmarshalMessage fuzzes unmarshaling of decoded messages into a variety of
+// types. Unmarshaling is expected to fail on most inputs, but must never panic.
+func FuzzUnmarshalMessage(f *testing.F) {
+	f.Add(goldMessageBytes)
+
+	for _, data := range conformanceCorpus(f) {
+		f.Add(data[4:])
+	}
+
+	f.Fuzz(func(_ *testing.T, data []byte) {
+		m := NewMessage()
+
+		if err := m.decode(data); err != nil {
+			return
+		}
+
+		_ = UnmarshalMessage(m, &fuzzUnmarshalMessage{})
+		_ = UnmarshalMessage(m, map[string]string{})
+		_ = UnmarshalMessage(m, map[string][]string{})
+		_ = UnmarshalMessage(m, map[string]*Message{})
+		_ = UnmarshalMessage(m, map[string]fuzzUnmarshalSection{})
+		_ = UnmarshalMessage(m, map[string]map[string]string{})
+	})
+}
+
+func TestUnmarshalMapOfNonScalars(t *testing.T) {
+	m := NewMessage()
+	if err := m.Set("section", map[string]string{"key": "value"}); err != nil {
+		t.Fatalf("Unable to set message field: %v", err)
+	}
+	if err := m.Set("list", []string{"item"}); err != nil {
+		t.Fatalf("Unable to set message field: %v", err)
+	}
+
+	maps := map[string]map[string]string{}
+	if err := UnmarshalMessage(m, maps); err == nil {
+		t.Fatal("Expected error when unmarshaling list into map. None was returned.")
+	}
+	if want := map[string]string{"key": "value"}; !reflect.DeepEqual(maps["section"], want) {
+		t.Fatalf("Unexpected map value:\nwant: %v\ngot: %v", want, maps["section"])
+	}
+
+	lists := map[string][]string{}
+	if err := UnmarshalMessage(m, lists); err == nil {
+		t.Fatal("Expected error when unmarshaling section into []string. None was returned.")
+	}
+
+	messages := map[string]*Message{}
+	if err := UnmarshalMessage(m, messages); err == nil {
+		t.Fatal("Expected error when unmarshaling list into *Message. None was returned.")
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// SkipSection is used as a return value from a WalkFunc to indicate that the
+// section named in the call is to be skipped. If the value named in the call
+// is not a section, the remaining elements of the section containing it are
+// skipped. It is not returned as an error by Message.Walk.
+var SkipSection = errors.New("skip this section") // nolint
+
+// errStopWalk is used internally to end a walk early without an error.
+var errStopWalk = errors.New("stop walking")
+
+// WalkFunc is the type of the function called by Message.Walk to visit each
+// message element. The path holds the keys leading to the element, starting
+// at the top level of the message, and ending with the element's own key.
+// The value is the element's internal message representation, i.e. either
+// string, []string, or *Message.
+//
+// If the function returns the special value SkipSection, Walk skips the
+// section's contents as described by SkipSection. If the function returns
+// any other non-nil error, Walk stops and returns that error.
+type WalkFunc func(path []string, value any) error
+
+// Walk walks the message tree depth-first, calling fn for each message
+// element, including sections, in message order. A section is visited before
+// its contents.
+func (m *Message) Walk(fn WalkFunc) error {
+	err := m.walk(make([]string, 0), fn)
+	if errors.Is(err, SkipSection) {
+		return nil
+	}
+
+	return err
+}
+
+func (m *Message) walk(path []string, fn WalkFunc) error {
+	for k, v := range m.elements() {
+		// Give each call its own copy of the path, so that it is
+		// safe for the caller to retain it.
+		p := append(slices.Clone(path), k)
+
+		err := fn(p, v)
+
+		section, ok := v.(*Message)
+		switch {
+		case errors.Is(err, SkipSection) && ok:
+			continue
+		case err != nil:
+			return err
+		case ok:
+			if err := section.walk(p, fn); err != nil && !errors.Is(err, SkipSection) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// WalkOption is used to specify options to Message.All.
+type WalkOption interface {
+	apply(*walkOptions)
+}
+
+type walkOptions struct {
+	recursive bool
+	skip      func(path []string, section *Message) bool
+}
+
+type funcWalkOption struct {
+	f func(*walkOptions)
+}
+
+func (fwo *funcWalkOption) apply(o *walkOptions) {
+	fwo.f(o)
+}
+
+// WalkRecursive makes Message.All descend into sections, rather than only
+// visiting the top level of the message.
+func WalkRecursive() WalkOption {
+	return &funcWalkOption{func(o *walkOptions) {
+		o.recursive = true
+	}}
+}
+
+// WalkSkip makes Message.All skip the contents of every section for which
+// skip returns true. The section itself is still visited. Only relevant when
+// used with WalkRecursive.
+func WalkSkip(skip func(path []string, section *Message) bool) WalkOption {
+	return &funcWalkOption{func(o *walkOptions) {
+		o.skip = skip
+	}}
+}
+
+// All returns an iterator over the message elements, in message order. By
+// default, only the top level of the message is visited. Use WalkRecursive to
+// descend into sections, in which case the key of each element is the path to
+// the element joined with ".", e.g. "conns.gw.version". As keys may themselves
+// contain ".", use Message.Walk when the exact path is needed.
+//
+// Like Message.Get, each value is the element's internal message representation,
+// i.e. either string, []string, or *Message.
+func (m *Message) All(opts ...WalkOption) iter.Seq2[string, any] {
+	var o walkOptions
+
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	return func(yield func(string, any) bool) {
+		// nolint
+		_ = m.Walk(func(path []string, value any) error {
+			if !yield(strings.Join(path, "."), value) {
+				return errStopWalk
+			}
+
+			if section, ok := value.(*Message); ok {
+				if !o.recursive || (o.skip != nil && o.skip(path, section)) {
+					return SkipSection
+				}
+			}
+
+			return nil
+		})
+	}
+}
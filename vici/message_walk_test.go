@@ -0,0 +1,215 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMessageWalk(t *testing.T) {
+	var paths []string
+
+	err := goldMessage.Walk(func(path []string, _ any) error {
+		paths = append(paths, strings.Join(path, "/"))
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error walking message: %v", err)
+	}
+
+	want := []string{
+		"key1",
+		"section1",
+		"section1/sub-section",
+		"section1/sub-section/key2",
+		"section1/list1",
+	}
+
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("Unexpected walk order.\nExpected: %v\nReceived: %v", want, paths)
+	}
+}
+
+func TestMessageWalkSkipSection(t *testing.T) {
+	var paths []string
+
+	err := goldMessage.Walk(func(path []string, _ any) error {
+		paths = append(paths, strings.Join(path, "/"))
+
+		if path[len(path)-1] == "sub-section" {
+			return SkipSection
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error walking message: %v", err)
+	}
+
+	want := []string{"key1", "section1", "section1/sub-section", "section1/list1"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("Unexpected walk order.\nExpected: %v\nReceived: %v", want, paths)
+	}
+
+	// Returning SkipSection for a value skips the rest of its section.
+	paths = paths[:0]
+
+	err = goldMessage.Walk(func(path []string, _ any) error {
+		paths = append(paths, strings.Join(path, "/"))
+
+		if path[len(path)-1] == "key2" {
+			return SkipSection
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error walking message: %v", err)
+	}
+
+	want = []string{"key1", "section1", "section1/sub-section", "section1/sub-section/key2", "section1/list1"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("Unexpected walk order.\nExpected: %v\nReceived: %v", want, paths)
+	}
+}
+
+func TestMessageWalkError(t *testing.T) {
+	errTest := errors.New("test error")
+
+	n := 0
+	err := goldMessage.Walk(func(_ []string, _ any) error {
+		n++
+		if n == 3 {
+			return errTest
+		}
+
+		return nil
+	})
+	if !errors.Is(err, errTest) {
+		t.Fatalf("Expected %v, got %v", errTest, err)
+	}
+
+	if n != 3 {
+		t.Fatalf("Expected walk to stop after 3 elements, visited %d", n)
+	}
+}
+
+func TestMessageWalkRetainPath(t *testing.T) {
+	var paths [][]string
+
+	err := goldMessage.Walk(func(path []string, _ any) error {
+		paths = append(paths, path)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error walking message: %v", err)
+	}
+
+	if got := strings.Join(paths[2], "/"); got != "section1/sub-section" {
+		t.Fatalf("Retained path was modified by walk: %v", got)
+	}
+}
+
+func TestMessageAll(t *testing.T) {
+	var keys []string
+
+	for k := range goldMessage.All() {
+		keys = append(keys, k)
+	}
+
+	if !reflect.DeepEqual(keys, goldMessage.Keys()) {
+		t.Fatalf("Expected only top-level keys %v, got %v", goldMessage.Keys(), keys)
+	}
+
+	keys = keys[:0]
+	for k := range goldMessage.All(WalkRecursive()) {
+		keys = append(keys, k)
+	}
+
+	want := []string{"key1", "section1", "section1.sub-section", "section1.sub-section.key2", "section1.list1"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("Unexpected keys.\nExpected: %v\nReceived: %v", want, keys)
+	}
+
+	skip := func(path []string, _ *Message) bool {
+		return path[len(path)-1] == "sub-section"
+	}
+
+	keys = keys[:0]
+	for k := range goldMessage.All(WalkRecursive(), WalkSkip(skip)) {
+		keys = append(keys, k)
+	}
+
+	want = []string{"key1", "section1", "section1.sub-section", "section1.list1"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("Unexpected keys.\nExpected: %v\nReceived: %v", want, keys)
+	}
+}
+
+func TestMessageAllBreak(t *testing.T) {
+	n := 0
+	for range goldMessage.All(WalkRecursive()) {
+		n++
+		if n == 2 {
+			break
+		}
+	}
+
+	if n != 2 {
+		t.Fatalf("Expected to visit 2 elements, visited %d", n)
+	}
+}
+
+func ExampleMessage_Walk() {
+	m, err := Build().
+		Section("rw").
+		Set("uniqueid", 1).
+		Section("child-sas").
+		Section("rw-1").
+		Set("bytes-in", 1024).
+		Set("bytes-out", 2048).
+		Message()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	err = m.Walk(func(path []string, value any) error {
+		if v, ok := value.(string); ok {
+			fmt.Printf("%s = %s\n", strings.Join(path, "."), v)
+		}
+
+		return nil
+	})
+	if err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// rw.uniqueid = 1
+	// rw.child-sas.rw-1.bytes-in = 1024
+	// rw.child-sas.rw-1.bytes-out = 2048
+}
@@ -0,0 +1,27 @@
+module github.com/strongswan/govici/vici/otelvici
+
+go 1.25.0
+
+// The replace directives build this module against the code in this
+// repository. They are ignored when the module is used as a dependency, which
+// then uses the required releases. The modules of this repository are tagged
+// together, e.g. v0.9.0 and vici/otelvici/v0.9.0.
+replace github.com/strongswan/govici => ../..
+
+require (
+	github.com/strongswan/govici v0.9.0
+	go.opentelemetry.io/otel v1.46.0
+	go.opentelemetry.io/otel/metric v1.46.0
+	go.opentelemetry.io/otel/sdk v1.46.0
+	go.opentelemetry.io/otel/sdk/metric v1.46.0
+	go.opentelemetry.io/otel/trace v1.46.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/go-logr/logr v1.4.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.2.1 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+)
@@ -0,0 +1,326 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package otelvici provides OpenTelemetry tracing and metrics for vici sessions.
+//
+// The interceptors returned by UnaryInterceptor and StreamInterceptor create a client
+// span for each command request, and record its duration and message sizes. They are
+// installed with the vici.WithUnaryInterceptor and vici.WithStreamInterceptor session
+// options:
+//
+//	s, err := vici.NewSession(
+//		vici.WithUnaryInterceptor(otelvici.UnaryInterceptor()),
+//		vici.WithStreamInterceptor(otelvici.StreamInterceptor()),
+//	)
+//
+// Events received for subscriptions are counted with ObserveEvents.
+//
+// By default, the global tracer and meter providers are used.
+package otelvici
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name used for the tracer and meter.
+const ScopeName = "github.com/strongswan/govici/vici/otelvici"
+
+// Attribute keys set on spans and metrics.
+const (
+	// CommandKey is the name of the vici command.
+	CommandKey = attribute.Key("vici.command")
+
+	// EventKey is the name of a vici event, e.g. the event streamed by a
+	// streaming command.
+	EventKey = attribute.Key("vici.event")
+
+	// SuccessKey reports whether the command succeeded.
+	SuccessKey = attribute.Key("vici.success")
+
+	// ErrmsgKey is the errmsg of a failed command.
+	ErrmsgKey = attribute.Key("vici.errmsg")
+
+	// EventCountKey is the number of events received by a streaming command.
+	EventCountKey = attribute.Key("vici.event.count")
+
+	// DirectionKey is either "sent" or "received", for message sizes.
+	DirectionKey = attribute.Key("vici.message.direction")
+)
+
+// Metric names.
+const (
+	// DurationMetric is a histogram of command request durations, in seconds.
+	DurationMetric = "vici.client.duration"
+
+	// MessageSizeMetric is a histogram of encoded message sizes, in bytes.
+	MessageSizeMetric = "vici.client.message.size"
+
+	// EventsMetric counts the events received, per event name.
+	EventsMetric = "vici.client.events"
+)
+
+// Option is used to specify options to the interceptors and ObserveEvents.
+type Option interface {
+	apply(*config)
+}
+
+type funcOption struct {
+	f func(*config)
+}
+
+func (fo *funcOption) apply(c *config) {
+	fo.f(c)
+}
+
+// WithTracerProvider specifies the tracer provider to use. If this option is not
+// specified, the global tracer provider is used.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return &funcOption{func(c *config) {
+		c.tracerProvider = tp
+	}}
+}
+
+// WithMeterProvider specifies the meter provider to use. If this option is not
+// specified, the global meter provider is used.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return &funcOption{func(c *config) {
+		c.meterProvider = mp
+	}}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+
+	tracer trace.Tracer
+
+	duration    metric.Float64Histogram
+	messageSize metric.Int64Histogram
+	events      metric.Int64Counter
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+
+	c.tracer = c.tracerProvider.Tracer(ScopeName)
+	meter := c.meterProvider.Meter(ScopeName)
+
+	var err error
+
+	// On error, the instruments are still usable no-op instruments, so just
+	// report the error.
+	c.duration, err = meter.Float64Histogram(DurationMetric,
+		metric.WithDescription("Duration of vici command requests."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	c.messageSize, err = meter.Int64Histogram(MessageSizeMetric,
+		metric.WithDescription("Size of encoded vici messages."),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	c.events, err = meter.Int64Counter(EventsMetric,
+		metric.WithDescription("Number of vici events received."),
+		metric.WithUnit("{event}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return c
+}
+
+// UnaryInterceptor returns an interceptor that traces and measures command requests
+// made with vici.Session.Call.
+func UnaryInterceptor(opts ...Option) vici.UnaryInterceptor {
+	c := newConfig(opts)
+
+	return func(ctx context.Context, cmd string, in *vici.Message, invoker vici.UnaryInvoker) (*vici.Message, error) {
+		ctx, span := c.tracer.Start(ctx, "vici "+cmd,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(CommandKey.String(cmd)),
+		)
+		defer span.End()
+
+		start := time.Now()
+		c.recordSize(ctx, cmd, "sent", in)
+
+		out, err := invoker(ctx, cmd, in)
+
+		c.recordSize(ctx, cmd, "received", out)
+		c.end(ctx, span, cmd, start, out, err)
+
+		return out, err
+	}
+}
+
+// StreamInterceptor returns an interceptor that traces and measures streamed command
+// requests made with vici.Session.CallStreaming. The span starts when the caller starts
+// iterating, and ends when the iteration is complete. Each streamed event is counted
+// like the events observed by ObserveEvents.
+func StreamInterceptor(opts ...Option) vici.StreamInterceptor {
+	c := newConfig(opts)
+
+	return func(ctx context.Context, cmd string, event string, in *vici.Message, invoker vici.StreamInvoker) iter.Seq2[*vici.Message, error] {
+		return func(yield func(*vici.Message, error) bool) {
+			ctx, span := c.tracer.Start(ctx, "vici "+cmd,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					CommandKey.String(cmd),
+					EventKey.String(event),
+				),
+			)
+			defer span.End()
+
+			var (
+				start = time.Now()
+				n     int64
+				last  *vici.Message
+				serr  error
+			)
+
+			c.recordSize(ctx, cmd, "sent", in)
+
+			for m, err := range invoker(ctx, cmd, event, in) {
+				if err != nil {
+					// The message, if any, is the failed command
+					// response.
+					last, serr = m, err
+				} else {
+					n++
+					c.events.Add(ctx, 1, metric.WithAttributes(EventKey.String(event)))
+					c.recordSize(ctx, cmd, "received", m)
+				}
+
+				if !yield(m, err) {
+					break
+				}
+			}
+
+			span.SetAttributes(EventCountKey.Int64(n))
+			c.end(ctx, span, cmd, start, last, serr)
+		}
+	}
+}
+
+// ObserveEvents counts the events received by s for its subscriptions, per event
+// name. The events are observed through a channel registered with NotifyEvents.
+// Like any such channel, events are discarded rather than counted if they arrive
+// faster than they can be counted.
+//
+// Counting stops when the returned function is called, or when s is closed.
+func ObserveEvents(s *vici.Session, opts ...Option) (stop func()) {
+	c := newConfig(opts)
+
+	ec := make(chan vici.Event, 128)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	s.NotifyEvents(ec)
+
+	go func() {
+		defer close(stopped)
+
+		for {
+			select {
+			case <-done:
+				return
+			case ev, ok := <-ec:
+				if !ok {
+					return
+				}
+
+				c.events.Add(context.Background(), 1, metric.WithAttributes(EventKey.String(ev.Name)))
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			s.StopEvents(ec)
+			close(done)
+		})
+		<-stopped
+	}
+}
+
+// end records the outcome of a command request on the span, and its duration.
+func (c *config) end(ctx context.Context, span trace.Span, cmd string, start time.Time, out *vici.Message, err error) {
+	success := err == nil
+
+	span.SetAttributes(SuccessKey.Bool(success))
+
+	if out != nil {
+		if errmsg, ok := out.Get("errmsg").(string); ok && errmsg != "" {
+			span.SetAttributes(ErrmsgKey.String(errmsg))
+		}
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	c.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+		CommandKey.String(cmd),
+		SuccessKey.Bool(success),
+	))
+}
+
+func (c *config) recordSize(ctx context.Context, cmd string, direction string, m *vici.Message) {
+	if m == nil {
+		return
+	}
+
+	b, err := m.MarshalBinary()
+	if err != nil {
+		return
+	}
+
+	c.messageSize.Record(ctx, int64(len(b)), metric.WithAttributes(
+		CommandKey.String(cmd),
+		DirectionKey.String(direction),
+	))
+}
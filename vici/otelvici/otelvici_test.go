@@ -0,0 +1,281 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package otelvici_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/otelvici"
+	"github.com/strongswan/govici/vici/vicitest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type testEnv struct {
+	spans  *tracetest.SpanRecorder
+	reader *sdkmetric.ManualReader
+	opts   []otelvici.Option
+	srv    *vicitest.Server
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+
+	env := &testEnv{
+		spans:  spans,
+		reader: reader,
+		opts: []otelvici.Option{
+			otelvici.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+			otelvici.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		},
+		srv: vicitest.NewServer(),
+	}
+	t.Cleanup(func() { env.srv.Close() })
+
+	env.srv.Handle("version", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().Set("daemon", "charon").Message()
+	})
+	env.srv.Handle("initiate", func(_ *vicitest.Request) (*vici.Message, error) {
+		return nil, errors.New("CHILD_SA config 'net' not found")
+	})
+	env.srv.Handle("list-sas", func(req *vicitest.Request) (*vici.Message, error) {
+		for _, name := range []string{"gw-1", "gw-2"} {
+			m, err := vici.Build().Section(name).Set("state", "ESTABLISHED").Message()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := req.Emit("list-sa", m); err != nil {
+				return nil, err
+			}
+		}
+
+		return vici.NewMessage(), nil
+	})
+
+	return env
+}
+
+func (env *testEnv) session(t *testing.T) *vici.Session {
+	t.Helper()
+
+	s, err := vici.NewSession(
+		vici.WithDialContext(env.srv.DialContext),
+		vici.WithUnaryInterceptor(otelvici.UnaryInterceptor(env.opts...)),
+		vici.WithStreamInterceptor(otelvici.StreamInterceptor(env.opts...)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func (env *testEnv) metrics(t *testing.T) map[string]metricdata.Aggregation {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := env.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Failed to collect metrics: %v", err)
+	}
+
+	metrics := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+
+	return metrics
+}
+
+func attrValue(attrs []attribute.KeyValue, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	env := newTestEnv(t)
+	s := env.session(t)
+
+	if _, err := s.Call(context.Background(), "version", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	in, err := vici.Build().Set("child", "net").Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Call(context.Background(), "initiate", in); err == nil {
+		t.Fatal("Expected error from initiate")
+	}
+
+	spans := env.spans.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	ok, failed := spans[0], spans[1]
+
+	if ok.Name() != "vici version" {
+		t.Fatalf("Unexpected span name: %s", ok.Name())
+	}
+
+	if v, _ := attrValue(ok.Attributes(), otelvici.SuccessKey); !v.AsBool() {
+		t.Fatalf("Expected successful span, got %v", ok.Attributes())
+	}
+
+	if v, _ := attrValue(failed.Attributes(), otelvici.CommandKey); v.AsString() != "initiate" {
+		t.Fatalf("Unexpected command attribute: %v", failed.Attributes())
+	}
+
+	if v, _ := attrValue(failed.Attributes(), otelvici.ErrmsgKey); v.AsString() != "CHILD_SA config 'net' not found" {
+		t.Fatalf("Unexpected errmsg attribute: %v", failed.Attributes())
+	}
+
+	if failed.Status().Code != codes.Error {
+		t.Fatalf("Expected error status, got %v", failed.Status())
+	}
+
+	metrics := env.metrics(t)
+
+	duration, ok2 := metrics[otelvici.DurationMetric].(metricdata.Histogram[float64])
+	if !ok2 {
+		t.Fatalf("Expected %s histogram, got %T", otelvici.DurationMetric, metrics[otelvici.DurationMetric])
+	}
+
+	var count uint64
+	for _, dp := range duration.DataPoints {
+		count += dp.Count
+	}
+
+	if count != 2 {
+		t.Fatalf("Expected 2 duration measurements, got %d", count)
+	}
+
+	size, ok2 := metrics[otelvici.MessageSizeMetric].(metricdata.Histogram[int64])
+	if !ok2 {
+		t.Fatalf("Expected %s histogram, got %T", otelvici.MessageSizeMetric, metrics[otelvici.MessageSizeMetric])
+	}
+
+	// One request with arguments, and two responses.
+	count = 0
+	for _, dp := range size.DataPoints {
+		count += dp.Count
+	}
+
+	if count != 3 {
+		t.Fatalf("Expected 3 message size measurements, got %d", count)
+	}
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	env := newTestEnv(t)
+	s := env.session(t)
+
+	n := 0
+	for _, err := range s.CallStreaming(context.Background(), "list-sas", "list-sa", nil) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		n++
+	}
+
+	if n != 2 {
+		t.Fatalf("Expected 2 events, got %d", n)
+	}
+
+	spans := env.spans.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+
+	if v, _ := attrValue(spans[0].Attributes(), otelvici.EventCountKey); v.AsInt64() != 2 {
+		t.Fatalf("Expected event count of 2, got %v", spans[0].Attributes())
+	}
+
+	if v, _ := attrValue(spans[0].Attributes(), otelvici.EventKey); v.AsString() != "list-sa" {
+		t.Fatalf("Unexpected event attribute: %v", spans[0].Attributes())
+	}
+
+	events, ok := env.metrics(t)[otelvici.EventsMetric].(metricdata.Sum[int64])
+	if !ok || len(events.DataPoints) != 1 || events.DataPoints[0].Value != 2 {
+		t.Fatalf("Expected 2 list-sa events to be counted, got %+v", events)
+	}
+}
+
+func TestObserveEvents(t *testing.T) {
+	env := newTestEnv(t)
+	s := env.session(t)
+
+	stop := otelvici.ObserveEvents(s, env.opts...)
+	defer stop()
+
+	if err := s.Subscribe("ike-updown"); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+
+	for range 3 {
+		if err := env.srv.Emit("ike-updown", vici.NewMessage()); err != nil {
+			t.Fatalf("Unexpected error emitting event: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		events, ok := env.metrics(t)[otelvici.EventsMetric].(metricdata.Sum[int64])
+		if ok && len(events.DataPoints) == 1 && events.DataPoints[0].Value == 3 {
+			if v, _ := events.DataPoints[0].Attributes.Value(otelvici.EventKey); v.AsString() != "ike-updown" {
+				t.Fatalf("Unexpected event attribute: %v", events.DataPoints[0].Attributes)
+			}
+
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for events to be counted, got %+v", events)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stop()
+
+	// Stopping more than once is fine.
+	stop()
+}
@@ -0,0 +1,148 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// PacketType is the type of a vici packet, as described in the vici README.
+type PacketType uint8
+
+// Packet types. See the vici README for details.
+const (
+	PacketCmdRequest      = PacketType(pktCmdRequest)
+	PacketCmdResponse     = PacketType(pktCmdResponse)
+	PacketCmdUnknown      = PacketType(pktCmdUnknown)
+	PacketEventRegister   = PacketType(pktEventRegister)
+	PacketEventUnregister = PacketType(pktEventUnregister)
+	PacketEventConfirm    = PacketType(pktEventConfirm)
+	PacketEventUnknown    = PacketType(pktEventUnknown)
+	PacketEvent           = PacketType(pktEvent)
+)
+
+// String returns the name of the packet type as used in the vici README, e.g.
+// "CMD_REQUEST".
+func (t PacketType) String() string {
+	if name, ok := pktNames[uint8(t)]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("PacketType(%d)", uint8(t))
+}
+
+// Packet is a single vici packet, as transmitted between a client and the daemon.
+//
+// Most programs do not need to deal with packets, as a Session takes care of the
+// protocol. Packets are useful for programs which implement the other side of the
+// protocol, e.g. proxies or test fakes of the daemon.
+type Packet struct {
+	// Type is the packet type.
+	Type PacketType
+
+	// Name is the command or event name. It is only set for the named packet
+	// types: CMD_REQUEST, EVENT_REGISTER, EVENT_UNREGISTER and EVENT.
+	Name string
+
+	// Message is the packet payload. It is never nil for a Packet returned by
+	// ReadPacket, and may be nil when writing a packet without payload.
+	Message *Message
+}
+
+// ReadPacket reads a single packet, including its length prefix, from r.
+func ReadPacket(r io.Reader) (*Packet, error) {
+	m, err := readPacket(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Packet{
+		Type:    PacketType(m.header.ptype),
+		Name:    m.header.name,
+		Message: m,
+	}
+	m.header = nil
+
+	return p, nil
+}
+
+// WritePacket writes the packet p, including its length prefix, to w. An error is
+// returned if the packet is not valid, e.g. if a named packet type has no name.
+func WritePacket(w io.Writer, p *Packet) error {
+	b, err := p.encode()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+
+	return err
+}
+
+// encode returns the packet as it is written on the wire.
+func (p *Packet) encode() ([]byte, error) {
+	m := p.Message
+	if m == nil {
+		m = NewMessage()
+	}
+
+	// Do not modify the caller's message by setting the header on it.
+	pm := &Message{
+		header: &header{
+			ptype: uint8(p.Type),
+			name:  p.Name,
+		},
+		keys: m.keys,
+		data: m.data,
+	}
+
+	return pm.encodePacket()
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. It returns the message
+// encoded as a vici packet payload, i.e. without packet header.
+func (m *Message) MarshalBinary() ([]byte, error) {
+	buf := bytes.NewBuffer([]byte{})
+
+	if err := m.encodeElements(buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. It decodes data, a vici
+// packet payload without packet header, into m. Any existing contents of m are
+// replaced.
+func (m *Message) UnmarshalBinary(data []byte) error {
+	dm := NewMessage()
+
+	if err := dm.decodeElements(bytes.NewBuffer(data)); err != nil {
+		return err
+	}
+
+	m.keys = dm.keys
+	m.data = dm.data
+
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	for name, data := range conformanceCorpus(t) {
+		p, err := ReadPacket(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: unexpected error reading packet: %v", name, err)
+		}
+
+		var buf bytes.Buffer
+		if err := WritePacket(&buf, p); err != nil {
+			t.Fatalf("%s: unexpected error writing packet: %v", name, err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Fatalf("%s: written packet does not match corpus.\nExpected: %v\nReceived: %v", name, data, buf.Bytes())
+		}
+	}
+}
+
+func TestReadPacket(t *testing.T) {
+	data, err := goldMessage.encodePacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := ReadPacket(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Unexpected error reading packet: %v", err)
+	}
+
+	if p.Type != PacketCmdResponse || p.Name != "" {
+		t.Fatalf("Unexpected packet header: %v %q", p.Type, p.Name)
+	}
+
+	if !reflect.DeepEqual(p.Message.data, goldMessage.data) {
+		t.Fatalf("Unexpected packet payload: %s", p.Message)
+	}
+}
+
+func TestWritePacketInvalid(t *testing.T) {
+	var buf bytes.Buffer
+
+	// Named packet types need a name.
+	if err := WritePacket(&buf, &Packet{Type: PacketCmdRequest}); err == nil {
+		t.Fatal("Expected error writing unnamed command request")
+	}
+
+	if err := WritePacket(&buf, &Packet{Type: PacketType(pktInvalid)}); err == nil {
+		t.Fatal("Expected error writing invalid packet type")
+	}
+
+	if buf.Len() != 0 {
+		t.Fatalf("Expected nothing to be written, got %v", buf.Bytes())
+	}
+
+	// A nil message is written as an empty payload.
+	if err := WritePacket(&buf, &Packet{Type: PacketEventConfirm}); err != nil {
+		t.Fatalf("Unexpected error writing packet: %v", err)
+	}
+
+	if want := []byte{0, 0, 0, 1, pktEventConfirm}; !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("Expected %v, got %v", want, buf.Bytes())
+	}
+}
+
+func TestPacketTypeString(t *testing.T) {
+	if s := PacketCmdRequest.String(); s != "CMD_REQUEST" {
+		t.Fatalf("Expected CMD_REQUEST, got %s", s)
+	}
+
+	if s := PacketType(42).String(); s != "PacketType(42)" {
+		t.Fatalf("Expected PacketType(42), got %s", s)
+	}
+}
+
+func TestMessageMarshalBinary(t *testing.T) {
+	b, err := goldMessage.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error marshaling message: %v", err)
+	}
+
+	// The payload is the gold packet without its header.
+	if !bytes.Equal(b, goldMessageBytes[1:]) {
+		t.Fatalf("Unexpected payload.\nExpected: %v\nReceived: %v", goldMessageBytes[1:], b)
+	}
+
+	m := NewMessage()
+	if err := m.Set("replaced", "yes"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.UnmarshalBinary(b); err != nil {
+		t.Fatalf("Unexpected error unmarshaling message: %v", err)
+	}
+
+	if !reflect.DeepEqual(m.data, goldMessage.data) || !reflect.DeepEqual(m.keys, goldMessage.keys) {
+		t.Fatalf("Unmarshaled message does not equal gold message: %s", m)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrPeerRejected is returned by NewSession and NewSessionContext when the
+// credentials of the process listening on the vici socket are rejected by the
+// check given to WithPeerCredentials or WithPeerUID.
+var ErrPeerRejected = errors.New("vici: peer credentials rejected")
+
+// Credentials are the credentials of the process at the other end of a UNIX
+// socket connection, as recorded by the kernel when the connection was made.
+type Credentials struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// PeerCredentials returns the credentials of the process at the other end of
+// conn, which must be a *net.UnixConn. On platforms where this is not supported,
+// an error wrapping errors.ErrUnsupported is returned.
+//
+// Servers can use PeerCredentials to make authorization decisions based on the
+// client's identity.
+func PeerCredentials(conn net.Conn) (*Credentials, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, fmt.Errorf("vici: cannot get peer credentials of %T, not a unix socket", conn)
+	}
+
+	return peerCredentials(uc)
+}
+
+// WithPeerCredentials makes the Session verify the credentials of the process
+// listening on the vici socket before using the connection. If verify returns an
+// error, the connection is closed, and the Session is not created. The socket
+// must be a UNIX socket, as for WithSocketPath.
+//
+// This protects against another process, e.g. one that replaced the socket file,
+// impersonating the daemon.
+func WithPeerCredentials(verify func(*Credentials) error) SessionOption {
+	return newFuncSessionOption(func(so *Session) {
+		so.verifyPeer = verify
+	})
+}
+
+// WithPeerUID makes the Session verify that the process listening on the vici
+// socket runs as the user with the given uid, typically 0, as charon runs as
+// root. See WithPeerCredentials.
+func WithPeerUID(uid uint32) SessionOption {
+	return WithPeerCredentials(func(c *Credentials) error {
+		if c.UID != uid {
+			return fmt.Errorf("peer runs as uid %d, expected uid %d", c.UID, uid)
+		}
+
+		return nil
+	})
+}
+
+// checkPeer verifies the credentials of the peer of conn with s.verifyPeer.
+func (s *Session) checkPeer(conn net.Conn) error {
+	creds, err := PeerCredentials(conn)
+	if err != nil {
+		return err
+	}
+
+	if err := s.verifyPeer(creds); err != nil {
+		return fmt.Errorf("%w: %w", ErrPeerRejected, err)
+	}
+
+	return nil
+}
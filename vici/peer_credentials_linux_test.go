@@ -0,0 +1,142 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestUnixSocket serves a testServer on a UNIX socket, and returns its path.
+func newTestUnixSocket(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "charon.vici")
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go newTestServer(conn).serve()
+		}
+	}()
+
+	return path
+}
+
+func TestPeerCredentials(t *testing.T) {
+	path := newTestUnixSocket(t)
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	creds, err := PeerCredentials(conn)
+	if err != nil {
+		t.Fatalf("Unexpected error getting peer credentials: %v", err)
+	}
+
+	// The server is this process.
+	expected := Credentials{
+		PID: int32(os.Getpid()),
+		UID: uint32(os.Getuid()),
+		GID: uint32(os.Getgid()),
+	}
+
+	if *creds != expected {
+		t.Fatalf("Unexpected peer credentials: got %+v, expected %+v", *creds, expected)
+	}
+}
+
+func TestPeerCredentialsNotUnix(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	if _, err := PeerCredentials(client); err == nil {
+		t.Fatal("Expected error getting peer credentials of a pipe")
+	}
+}
+
+func TestSessionPeerUID(t *testing.T) {
+	path := newTestUnixSocket(t)
+
+	s, err := NewSession(WithSocketPath(path), WithPeerUID(uint32(os.Getuid())))
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Call(context.Background(), "cmd-ok", nil); err != nil {
+		t.Fatalf("Unexpected error calling cmd-ok: %v", err)
+	}
+}
+
+func TestSessionPeerRejected(t *testing.T) {
+	path := newTestUnixSocket(t)
+
+	_, err := NewSession(WithSocketPath(path), WithPeerUID(uint32(os.Getuid())+1))
+	if !errors.Is(err, ErrPeerRejected) {
+		t.Fatalf("Expected ErrPeerRejected, got %v", err)
+	}
+
+	custom := errors.New("pid not allowed")
+
+	_, err = NewSession(WithSocketPath(path), WithPeerCredentials(func(c *Credentials) error {
+		if c.PID == int32(os.Getpid()) {
+			return custom
+		}
+
+		return nil
+	}))
+	if !errors.Is(err, ErrPeerRejected) || !errors.Is(err, custom) {
+		t.Fatalf("Expected error wrapping ErrPeerRejected and the check's error, got %v", err)
+	}
+}
+
+func TestSessionPeerCredentialsNotUnix(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	dialer := func(_ context.Context, _, _ string) (net.Conn, error) {
+		return client, nil
+	}
+
+	_, err := NewSession(WithDialContext(dialer), WithPeerUID(0))
+	if err == nil {
+		t.Fatal("Expected error verifying peer credentials of a pipe")
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"path"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// Denial specifies how a denied command request is answered.
+type Denial int
+
+const (
+	// DenyUnknown answers denied command requests with CMD_UNKNOWN, as if the
+	// daemon did not know the command.
+	DenyUnknown Denial = iota
+
+	// DenyFailure answers denied command requests with a command response
+	// indicating failure, with an errmsg saying that the command is not
+	// permitted.
+	DenyFailure
+)
+
+// Policy specifies the commands and events a client may use.
+type Policy struct {
+	// Commands lists the commands the client may request, as patterns
+	// in the syntax of path.Match, e.g. "list-*".
+	Commands []string
+
+	// Events lists the events the client may register for, as patterns
+	// like Commands. If Events is nil, the client may register for all
+	// events.
+	Events []string
+
+	// Denial specifies how requests for other commands are answered.
+	Denial Denial
+}
+
+// ReadOnly is a Policy allowing the commands which only query the daemon's
+// state, and the events which report IKE and CHILD SA state changes.
+var ReadOnly = &Policy{
+	Commands: []string{"version", "stats", "list-*", "get-*"},
+	Events:   []string{"list-*", "ike-*", "child-*"},
+}
+
+// Unrestricted is a Policy allowing all commands and events.
+var Unrestricted = &Policy{
+	Commands: []string{"*"},
+}
+
+// AllowsCommand returns whether the policy allows requests for cmd.
+func (p *Policy) AllowsCommand(cmd string) bool {
+	return matchAny(p.Commands, cmd)
+}
+
+// AllowsEvent returns whether the policy allows registrations for event.
+func (p *Policy) AllowsEvent(event string) bool {
+	if p.Events == nil {
+		return true
+	}
+
+	return matchAny(p.Events, event)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Client describes a client connected to a Proxy, and is used to choose the
+// client's Policy.
+type Client struct {
+	// Credentials holds the credentials of the client process, if it is
+	// connected over a UNIX socket and the platform supports it. Otherwise,
+	// Credentials is nil.
+	Credentials *vici.Credentials
+
+	// RemoteAddr is the address of the client.
+	RemoteAddr net.Addr
+}
+
+// String returns a description of the client, i.e. its credentials if they are
+// available, or else its address.
+func (c *Client) String() string {
+	if creds := c.Credentials; creds != nil {
+		return fmt.Sprintf("uid=%d gid=%d pid=%d", creds.UID, creds.GID, creds.PID)
+	}
+
+	if c.RemoteAddr != nil {
+		return c.RemoteAddr.String()
+	}
+
+	return ""
+}
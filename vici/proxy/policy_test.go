@@ -0,0 +1,106 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package proxy_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/proxy"
+)
+
+func TestPolicyAllowsCommand(t *testing.T) {
+	tests := []struct {
+		policy  *proxy.Policy
+		cmd     string
+		allowed bool
+	}{
+		{proxy.ReadOnly, "version", true},
+		{proxy.ReadOnly, "list-sas", true},
+		{proxy.ReadOnly, "get-counters", true},
+		{proxy.ReadOnly, "load-conn", false},
+		{proxy.ReadOnly, "initiate", false},
+		{proxy.Unrestricted, "load-conn", true},
+		{&proxy.Policy{}, "version", false},
+		{&proxy.Policy{Commands: []string{"initiate", "terminate"}}, "terminate", true},
+		{&proxy.Policy{Commands: []string{"initiate", "terminate"}}, "rekey", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.policy.AllowsCommand(tt.cmd); got != tt.allowed {
+			t.Errorf("AllowsCommand(%q) with %+v: expected %v, got %v", tt.cmd, *tt.policy, tt.allowed, got)
+		}
+	}
+}
+
+func TestPolicyAllowsEvent(t *testing.T) {
+	tests := []struct {
+		policy  *proxy.Policy
+		event   string
+		allowed bool
+	}{
+		{proxy.ReadOnly, "ike-updown", true},
+		{proxy.ReadOnly, "child-rekey", true},
+		{proxy.ReadOnly, "list-sa", true},
+		{proxy.ReadOnly, "log", false},
+		{proxy.ReadOnly, "control-log", false},
+		{proxy.Unrestricted, "log", true},
+		{&proxy.Policy{Events: []string{}}, "ike-updown", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.policy.AllowsEvent(tt.event); got != tt.allowed {
+			t.Errorf("AllowsEvent(%q) with %+v: expected %v, got %v", tt.event, *tt.policy, tt.allowed, got)
+		}
+	}
+}
+
+func TestClientString(t *testing.T) {
+	tests := []struct {
+		client   *proxy.Client
+		expected string
+	}{
+		{
+			client: &proxy.Client{
+				Credentials: &vici.Credentials{PID: 42, UID: 1000, GID: 100},
+				RemoteAddr:  &net.UnixAddr{Net: "unix"},
+			},
+			expected: "uid=1000 gid=100 pid=42",
+		},
+		{
+			client: &proxy.Client{
+				RemoteAddr: &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 4502},
+			},
+			expected: "192.0.2.1:4502",
+		},
+		{
+			client:   &proxy.Client{},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.client.String(); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
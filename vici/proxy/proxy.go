@@ -0,0 +1,626 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package proxy provides a vici proxy, which shares a single Session to the
+// charon daemon with many clients, and restricts the commands each client may
+// use.
+//
+// A Proxy speaks the vici protocol to its clients, so that any vici client,
+// including swanctl and Session, can connect to it. Each command request is
+// forwarded over the upstream Session, if the client's Policy allows it. Events
+// are forwarded to each client according to its own registrations:
+//
+//	p := proxy.New(s, proxy.WithPolicy(func(c *proxy.Client) *proxy.Policy {
+//		if c.Credentials != nil && c.Credentials.UID == 0 {
+//			return proxy.Unrestricted
+//		}
+//
+//		return proxy.ReadOnly
+//	}))
+//	defer p.Close()
+//
+//	l, err := net.Listen("unix", "/run/vici-proxy.vici")
+//	if err != nil {
+//		return err
+//	}
+//
+//	return p.Serve(l)
+//
+// Requests are forwarded with the client's identity, see audit.WithIdentity, so
+// that the requests made by each client are attributed to it when the audit
+// interceptors are installed on the upstream Session.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/audit"
+)
+
+// streamedEvents maps the commands which stream events to the requesting
+// client to the streamed event. Clients register for the event before making
+// the request. The daemon only sends these events to the client making the
+// request, so the proxy does the same.
+var streamedEvents = map[string]string{
+	"list-sas":         "list-sa",
+	"list-policies":    "list-policy",
+	"list-conns":       "list-conn",
+	"list-certs":       "list-cert",
+	"list-authorities": "list-authority",
+	"initiate":         "control-log",
+	"terminate":        "control-log",
+}
+
+// writeTimeout is how long the proxy waits for a client to read a packet.
+const writeTimeout = 10 * time.Second
+
+func isStreamedEvent(event string) bool {
+	for _, e := range streamedEvents {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Option is used to specify options to New.
+type Option interface {
+	apply(*Proxy)
+}
+
+type funcOption struct {
+	f func(*Proxy)
+}
+
+func (fo *funcOption) apply(p *Proxy) {
+	fo.f(p)
+}
+
+// WithPolicy specifies the func used to choose the Policy of each client when
+// it connects. If this option is not specified, all clients use ReadOnly.
+func WithPolicy(policy func(*Client) *Policy) Option {
+	return &funcOption{func(p *Proxy) {
+		p.policy = policy
+	}}
+}
+
+// WithLogger specifies the logger used to record clients and denied requests.
+// If this option is not specified, nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return &funcOption{func(p *Proxy) {
+		p.logger = logger
+	}}
+}
+
+// Proxy is a vici proxy.
+type Proxy struct {
+	upstream *vici.Session
+	policy   func(*Client) *Policy
+	logger   *slog.Logger
+
+	// Cancelled when the proxy is closed, to abandon upstream requests.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	conns     map[*conn]struct{}
+	listeners map[net.Listener]struct{}
+	closed    bool
+
+	// Number of clients registered for each event, which the upstream
+	// session is subscribed to. Held while subscribing, so that changes
+	// are made in order.
+	subMu sync.Mutex
+	refs  map[string]int
+
+	events chan vici.Event
+
+	wg sync.WaitGroup
+}
+
+// New returns a new Proxy forwarding requests over upstream. The Proxy takes
+// care of subscribing upstream to the events clients register for, which must
+// not be changed by other users of upstream.
+func New(upstream *vici.Session, opts ...Option) *Proxy {
+	p := &Proxy{
+		upstream: upstream,
+		policy: func(*Client) *Policy {
+			return ReadOnly
+		},
+		logger:    slog.New(slog.DiscardHandler),
+		conns:     make(map[*conn]struct{}),
+		listeners: make(map[net.Listener]struct{}),
+		refs:      make(map[string]int),
+		events:    make(chan vici.Event, 128),
+	}
+
+	for _, opt := range opts {
+		opt.apply(p)
+	}
+
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	upstream.NotifyEvents(p.events)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		p.dispatch()
+	}()
+
+	return p
+}
+
+// Serve accepts client connections on l, and serves each of them, until l is
+// closed or the Proxy is closed. When the Proxy is closed, Serve returns nil.
+func (p *Proxy) Serve(l net.Listener) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+
+		return net.ErrClosed
+	}
+	p.listeners[l] = struct{}{}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.listeners, l)
+		p.mu.Unlock()
+	}()
+
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			p.mu.Lock()
+			closed := p.closed
+			p.mu.Unlock()
+
+			if closed {
+				return nil
+			}
+
+			return err
+		}
+
+		if err := p.serve(nc); err != nil {
+			return nil
+		}
+	}
+}
+
+// Close closes all listeners given to Serve and all client connections, and waits
+// until the connections are done. The upstream Session is not closed.
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+
+		return nil
+	}
+	p.closed = true
+
+	for l := range p.listeners {
+		l.Close()
+	}
+
+	for c := range p.conns {
+		c.nc.Close()
+	}
+	p.mu.Unlock()
+
+	p.cancel()
+	p.upstream.StopEvents(p.events)
+
+	p.wg.Wait()
+
+	return nil
+}
+
+// serve starts serving a client connection.
+func (p *Proxy) serve(nc net.Conn) error {
+	client := &Client{
+		RemoteAddr: nc.RemoteAddr(),
+	}
+
+	if creds, err := vici.PeerCredentials(nc); err == nil {
+		client.Credentials = creds
+	}
+
+	c := &conn{
+		p:      p,
+		nc:     nc,
+		client: client,
+		policy: p.policy(client),
+		out:    make(chan *vici.Packet, 128),
+		done:   make(chan struct{}),
+		events: make(map[string]struct{}),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		nc.Close()
+
+		return net.ErrClosed
+	}
+	p.conns[c] = struct{}{}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		c.serve()
+
+		p.mu.Lock()
+		delete(p.conns, c)
+		p.mu.Unlock()
+	}()
+
+	return nil
+}
+
+// dispatch forwards events received by the upstream session to the clients
+// registered for them, until the proxy is closed or the session ends.
+func (p *Proxy) dispatch() {
+	for {
+		var (
+			e  vici.Event
+			ok bool
+		)
+
+		select {
+		case e, ok = <-p.events:
+			if !ok {
+				return
+			}
+		case <-p.ctx.Done():
+			return
+		}
+
+		// Streamed events are sent directly to the client making the
+		// request, see conn.call.
+		if isStreamedEvent(e.Name) {
+			continue
+		}
+
+		p.mu.Lock()
+		for c := range p.conns {
+			if c.registered(e.Name) {
+				c.sendEvent(e.Name, e.Message)
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// ref subscribes the upstream session to event, if no client is registered for
+// it yet.
+func (p *Proxy) ref(event string) error {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+
+	if p.refs[event] == 0 {
+		if err := p.upstream.SubscribeContext(p.ctx, event); err != nil {
+			return err
+		}
+	}
+	p.refs[event]++
+
+	return nil
+}
+
+// unref unsubscribes the upstream session from event, if no other client is
+// registered for it.
+func (p *Proxy) unref(event string) {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+
+	p.refs[event]--
+	if p.refs[event] > 0 {
+		return
+	}
+	delete(p.refs, event)
+
+	if err := p.upstream.UnsubscribeContext(context.WithoutCancel(p.ctx), event); err != nil {
+		p.logger.LogAttrs(p.ctx, slog.LevelWarn, "failed to unsubscribe upstream session",
+			slog.String("event", event),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// conn is a client connection to a Proxy.
+type conn struct {
+	p      *Proxy
+	nc     net.Conn
+	client *Client
+	policy *Policy
+
+	// Packets to write to the client. Once the client is done, either
+	// because it disconnected, or because a write failed, done is closed.
+	out       chan *vici.Packet
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu     sync.Mutex
+	events map[string]struct{}
+}
+
+// serve reads and answers requests from the client, until the connection is
+// closed. Requests are handled one at a time, in order, as by the daemon.
+func (c *conn) serve() {
+	attrs := c.logAttrs()
+
+	c.p.logger.LogAttrs(c.p.ctx, slog.LevelInfo, "client connected", attrs...)
+	defer c.p.logger.LogAttrs(c.p.ctx, slog.LevelInfo, "client disconnected", attrs...)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		c.writeLoop()
+	}()
+
+	defer func() {
+		c.close()
+		wg.Wait()
+
+		c.mu.Lock()
+		events := c.events
+		c.events = nil
+		c.mu.Unlock()
+
+		for event := range events {
+			if !isStreamedEvent(event) {
+				c.p.unref(event)
+			}
+		}
+	}()
+
+	for {
+		req, err := vici.ReadPacket(c.nc)
+		if err != nil {
+			return
+		}
+
+		var resp *vici.Packet
+
+		switch req.Type {
+		case vici.PacketCmdRequest:
+			resp = c.call(req)
+
+		case vici.PacketEventRegister:
+			resp = c.register(req.Name)
+
+		case vici.PacketEventUnregister:
+			resp = c.unregister(req.Name)
+
+		default:
+			// Not a request, ignore.
+			continue
+		}
+
+		if resp == nil || !c.send(resp) {
+			return
+		}
+	}
+}
+
+// close ends the client connection.
+func (c *conn) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.nc.Close()
+	})
+}
+
+// writeLoop writes packets to the client until the client is done. A client
+// that does not read what it is sent within writeTimeout is disconnected, so
+// that it cannot hold up the upstream session during a streamed request.
+func (c *conn) writeLoop() {
+	for {
+		select {
+		case pkt := <-c.out:
+			// nolint
+			c.nc.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+			if err := vici.WritePacket(c.nc, pkt); err != nil {
+				c.close()
+
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// send queues a packet for the client, and returns false if the client is done.
+func (c *conn) send(pkt *vici.Packet) bool {
+	select {
+	case c.out <- pkt:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+// sendEvent queues an event for the client without blocking. Like the daemon,
+// the proxy does not wait for slow clients, and drops the event instead.
+func (c *conn) sendEvent(event string, m *vici.Message) {
+	select {
+	case c.out <- &vici.Packet{Type: vici.PacketEvent, Name: event, Message: m}:
+	default:
+		c.p.logger.LogAttrs(c.p.ctx, slog.LevelWarn, "dropped event for slow client",
+			append(c.logAttrs(), slog.String("event", event))...,
+		)
+	}
+}
+
+func (c *conn) registered(event string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.events[event]
+
+	return ok
+}
+
+// call forwards a command request upstream, if it is allowed by the policy,
+// and returns the response for the client. If the upstream session has ended,
+// nil is returned.
+func (c *conn) call(req *vici.Packet) *vici.Packet {
+	if !c.policy.AllowsCommand(req.Name) {
+		c.p.logger.LogAttrs(c.p.ctx, slog.LevelWarn, "denied command request",
+			append(c.logAttrs(), slog.String("command", req.Name))...,
+		)
+
+		return c.deny()
+	}
+
+	var (
+		out *vici.Message
+		err error
+	)
+
+	// Let the upstream session's interceptors know on whose behalf the
+	// request is made, e.g. for the audit interceptors.
+	ctx := audit.WithIdentity(c.p.ctx, c.client.String())
+
+	if event, ok := streamedEvents[req.Name]; ok && c.registered(event) {
+		out, err = c.p.upstream.CallStreamingFunc(ctx, req.Name, event, req.Message, func(m *vici.Message) error {
+			if !c.send(&vici.Packet{Type: vici.PacketEvent, Name: event, Message: m}) {
+				return net.ErrClosed
+			}
+
+			return nil
+		})
+	} else {
+		out, err = c.p.upstream.Call(ctx, req.Name, req.Message)
+	}
+
+	switch {
+	case out != nil:
+		// The response of a failed command is forwarded as is.
+		return &vici.Packet{Type: vici.PacketCmdResponse, Message: out}
+
+	case errors.Is(err, vici.ErrUnknownCommand):
+		return &vici.Packet{Type: vici.PacketCmdUnknown}
+
+	case c.p.upstream.Err() != nil, c.p.ctx.Err() != nil, errors.Is(err, net.ErrClosed):
+		return nil
+
+	default:
+		return failure(err.Error())
+	}
+}
+
+// register registers the client for event, if it is allowed by the policy.
+func (c *conn) register(event string) *vici.Packet {
+	if !c.policy.AllowsEvent(event) {
+		c.p.logger.LogAttrs(c.p.ctx, slog.LevelWarn, "denied event registration",
+			append(c.logAttrs(), slog.String("event", event))...,
+		)
+
+		return &vici.Packet{Type: vici.PacketEventUnknown}
+	}
+
+	if c.registered(event) {
+		return &vici.Packet{Type: vici.PacketEventConfirm}
+	}
+
+	// Streamed events are only sent during the client's own requests, the
+	// upstream session registers for them while streaming.
+	if !isStreamedEvent(event) {
+		if err := c.p.ref(event); err != nil {
+			return &vici.Packet{Type: vici.PacketEventUnknown}
+		}
+	}
+
+	c.mu.Lock()
+	c.events[event] = struct{}{}
+	c.mu.Unlock()
+
+	return &vici.Packet{Type: vici.PacketEventConfirm}
+}
+
+// unregister unregisters the client from event.
+func (c *conn) unregister(event string) *vici.Packet {
+	c.mu.Lock()
+	_, ok := c.events[event]
+	delete(c.events, event)
+	c.mu.Unlock()
+
+	if ok && !isStreamedEvent(event) {
+		c.p.unref(event)
+	}
+
+	return &vici.Packet{Type: vici.PacketEventConfirm}
+}
+
+// deny returns the response to a denied command request.
+func (c *conn) deny() *vici.Packet {
+	if c.policy.Denial == DenyFailure {
+		return failure("command not permitted")
+	}
+
+	return &vici.Packet{Type: vici.PacketCmdUnknown}
+}
+
+func failure(errmsg string) *vici.Packet {
+	m := vici.NewMessage()
+
+	// nolint
+	_ = m.Set("success", "no")
+	// nolint
+	_ = m.Set("errmsg", errmsg)
+
+	return &vici.Packet{Type: vici.PacketCmdResponse, Message: m}
+}
+
+func (c *conn) logAttrs() []slog.Attr {
+	var attrs []slog.Attr
+
+	if addr := c.client.RemoteAddr; addr != nil {
+		attrs = append(attrs, slog.String("remote", addr.String()))
+	}
+
+	if creds := c.client.Credentials; creds != nil {
+		attrs = append(attrs,
+			slog.Int("pid", int(creds.PID)),
+			slog.Uint64("uid", uint64(creds.UID)),
+			slog.Uint64("gid", uint64(creds.GID)),
+		)
+	}
+
+	return attrs
+}
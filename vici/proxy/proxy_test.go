@@ -0,0 +1,342 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package proxy_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/audit"
+	"github.com/strongswan/govici/vici/proxy"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+// newTestProxy starts a Proxy in front of a fake daemon, and returns the daemon
+// and the path of the proxy's socket.
+func newTestProxy(t *testing.T, opts ...proxy.Option) (*vicitest.Server, string) {
+	t.Helper()
+
+	srv, upstream := newTestUpstream(t)
+
+	return srv, serveTestProxy(t, upstream, opts...)
+}
+
+// newTestUpstream returns a fake daemon, and a session connected to it.
+func newTestUpstream(t *testing.T, opts ...vici.SessionOption) (*vicitest.Server, *vici.Session) {
+	t.Helper()
+
+	srv := vicitest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	srv.Handle("version", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().Set("daemon", "charon").Message()
+	})
+	srv.Handle("load-conn", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().Set("success", "yes").Message()
+	})
+	srv.Handle("list-sas", func(req *vicitest.Request) (*vici.Message, error) {
+		for _, name := range []string{"gw-1", "gw-2"} {
+			m, err := vici.Build().Section(name).Set("state", "ESTABLISHED").Message()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := req.Emit("list-sa", m); err != nil {
+				return nil, err
+			}
+		}
+
+		return vici.NewMessage(), nil
+	})
+
+	upstream, err := vici.NewSession(append(opts, vici.WithDialContext(srv.DialContext))...)
+	if err != nil {
+		t.Fatalf("Failed to create upstream session: %v", err)
+	}
+	t.Cleanup(func() { upstream.Close() })
+
+	return srv, upstream
+}
+
+// serveTestProxy starts a Proxy for upstream, and returns the path of its socket.
+func serveTestProxy(t *testing.T, upstream *vici.Session, opts ...proxy.Option) string {
+	t.Helper()
+
+	p := proxy.New(upstream, opts...)
+	t.Cleanup(func() { p.Close() })
+
+	path := filepath.Join(t.TempDir(), "proxy.vici")
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	go func() {
+		// nolint
+		p.Serve(l)
+	}()
+
+	return path
+}
+
+func newTestClient(t *testing.T, path string) *vici.Session {
+	t.Helper()
+
+	s, err := vici.NewSession(vici.WithSocketPath(path))
+	if err != nil {
+		t.Fatalf("Failed to create client session: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestProxyCall(t *testing.T) {
+	_, path := newTestProxy(t)
+	s := newTestClient(t, path)
+
+	m, err := s.Call(context.Background(), "version", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error calling version: %v", err)
+	}
+
+	if got := m.Get("daemon"); got != "charon" {
+		t.Fatalf("Expected daemon=charon, got %v", got)
+	}
+}
+
+func TestProxyDenyUnknown(t *testing.T) {
+	_, path := newTestProxy(t)
+	s := newTestClient(t, path)
+
+	if _, err := s.Call(context.Background(), "load-conn", nil); !errors.Is(err, vici.ErrUnknownCommand) {
+		t.Fatalf("Expected ErrUnknownCommand for denied command, got %v", err)
+	}
+}
+
+func TestProxyDenyFailure(t *testing.T) {
+	_, path := newTestProxy(t, proxy.WithPolicy(func(*proxy.Client) *proxy.Policy {
+		return &proxy.Policy{
+			Commands: []string{"version"},
+			Denial:   proxy.DenyFailure,
+		}
+	}))
+	s := newTestClient(t, path)
+
+	m, err := s.Call(context.Background(), "load-conn", nil)
+	if err == nil {
+		t.Fatal("Expected error for denied command")
+	}
+
+	if got := m.Get("errmsg"); got != "command not permitted" {
+		t.Fatalf("Expected errmsg for denied command, got %v", got)
+	}
+}
+
+func TestProxyUnknownCommand(t *testing.T) {
+	_, path := newTestProxy(t, proxy.WithPolicy(func(*proxy.Client) *proxy.Policy {
+		return proxy.Unrestricted
+	}))
+	s := newTestClient(t, path)
+
+	if _, err := s.Call(context.Background(), "load-conn", nil); err != nil {
+		t.Fatalf("Unexpected error calling allowed command: %v", err)
+	}
+
+	// The daemon does not know this command.
+	if _, err := s.Call(context.Background(), "reload-settings", nil); !errors.Is(err, vici.ErrUnknownCommand) {
+		t.Fatalf("Expected ErrUnknownCommand from daemon, got %v", err)
+	}
+}
+
+func TestProxyStream(t *testing.T) {
+	_, path := newTestProxy(t)
+
+	s := newTestClient(t, path)
+	other := newTestClient(t, path)
+
+	// The other client is registered for the streamed event, but must not
+	// receive the events streamed to s.
+	ec := make(chan vici.Event, 16)
+	other.NotifyEvents(ec)
+
+	if err := other.Subscribe("list-sa"); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+
+	var names []string
+
+	for m, err := range s.CallStreaming(context.Background(), "list-sas", "list-sa", nil) {
+		if err != nil {
+			t.Fatalf("Unexpected error streaming list-sas: %v", err)
+		}
+
+		names = append(names, m.Keys()...)
+	}
+
+	if len(names) != 2 || names[0] != "gw-1" || names[1] != "gw-2" {
+		t.Fatalf("Unexpected list-sa events: %v", names)
+	}
+
+	select {
+	case e := <-ec:
+		t.Fatalf("Unexpected event for other client: %s", e.Name)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestProxyEvents(t *testing.T) {
+	srv, path := newTestProxy(t)
+
+	a := newTestClient(t, path)
+	b := newTestClient(t, path)
+
+	aec := make(chan vici.Event, 16)
+	a.NotifyEvents(aec)
+
+	bec := make(chan vici.Event, 16)
+	b.NotifyEvents(bec)
+
+	if err := a.Subscribe("ike-updown"); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+	if err := b.Subscribe("child-updown"); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+
+	m, err := vici.Build().Set("up", "yes").Message()
+	if err != nil {
+		t.Fatalf("Unexpected error building event: %v", err)
+	}
+
+	if err := srv.Emit("ike-updown", m); err != nil {
+		t.Fatalf("Unexpected error emitting event: %v", err)
+	}
+
+	select {
+	case e := <-aec:
+		if e.Name != "ike-updown" || e.Message.Get("up") != "yes" {
+			t.Fatalf("Unexpected event: %s %s", e.Name, e.Message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	select {
+	case e := <-bec:
+		t.Fatalf("Unexpected event for client not registered: %s", e.Name)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Once a is unregistered, it must no longer receive the event.
+	if err := a.Unsubscribe("ike-updown"); err != nil {
+		t.Fatalf("Unexpected error unsubscribing: %v", err)
+	}
+
+	if err := srv.Emit("ike-updown", m); err != nil {
+		t.Fatalf("Unexpected error emitting event: %v", err)
+	}
+
+	select {
+	case e := <-aec:
+		t.Fatalf("Unexpected event after unsubscribing: %s", e.Name)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestProxyDenyEvent(t *testing.T) {
+	_, path := newTestProxy(t)
+	s := newTestClient(t, path)
+
+	if err := s.Subscribe("log"); err == nil {
+		t.Fatal("Expected error subscribing to denied event")
+	}
+}
+
+func TestProxyPolicyByCredentials(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Peer credentials are only supported on linux")
+	}
+
+	_, path := newTestProxy(t, proxy.WithPolicy(func(c *proxy.Client) *proxy.Policy {
+		if c.Credentials != nil && c.Credentials.UID == uint32(os.Getuid()) {
+			return proxy.Unrestricted
+		}
+
+		return proxy.ReadOnly
+	}))
+	s := newTestClient(t, path)
+
+	if _, err := s.Call(context.Background(), "load-conn", nil); err != nil {
+		t.Fatalf("Unexpected error calling command allowed for uid: %v", err)
+	}
+}
+
+func TestProxyAuditIdentity(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		entries []*audit.Entry
+	)
+
+	sink := audit.SinkFunc(func(e *audit.Entry) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		entries = append(entries, e)
+
+		return nil
+	})
+
+	_, upstream := newTestUpstream(t, vici.WithUnaryInterceptor(audit.UnaryInterceptor(sink)))
+	path := serveTestProxy(t, upstream, proxy.WithPolicy(func(*proxy.Client) *proxy.Policy {
+		return proxy.Unrestricted
+	}))
+	s := newTestClient(t, path)
+
+	if _, err := s.Call(context.Background(), "load-conn", nil); err != nil {
+		t.Fatalf("Unexpected error calling load-conn: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 audit entry, got %d", len(entries))
+	}
+
+	if entries[0].Identity == "" {
+		t.Fatal("Expected the client's identity in the audit entry")
+	}
+
+	if runtime.GOOS == "linux" && !strings.HasPrefix(entries[0].Identity, "uid=") {
+		t.Fatalf("Expected the client's credentials as identity, got %q", entries[0].Identity)
+	}
+}
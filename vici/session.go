@@ -22,7 +22,10 @@ package vici
 
 import (
 	"context"
+	"crypto/tls"
+	"io"
 	"iter"
+	"log/slog"
 	"net"
 	"time"
 )
@@ -38,10 +41,46 @@ type Session struct {
 
 	// The context dial func to use when dialing the charon socket.
 	dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// TLS configuration, if the connection uses TLS.
+	tlsConfig *tls.Config
+
+	// Check of the credentials of the process listening on the socket.
+	verifyPeer func(*Credentials) error
+
+	// Where to record the packets of the connection, if anywhere.
+	capture io.Writer
+
+	// Logger for the session, whether to log packet payloads, and the
+	// redactor for logged payloads.
+	logger      *slog.Logger
+	logPayloads bool
+	redactor    *Redactor
+
+	// Interceptors given as options, and the resulting invokers used by
+	// Call and CallStreaming.
+	unaryInterceptors  []UnaryInterceptor
+	streamInterceptors []StreamInterceptor
+	invokeUnary        UnaryInvoker
+	invokeStream       StreamInvoker
+
+	// Interval and timeout of the health check, which is disabled if
+	// the interval is zero.
+	healthInterval time.Duration
+	healthTimeout  time.Duration
 }
 
 // NewSession returns a new vici session.
+//
+// NewSession is equivalent to NewSessionContext with context.Background().
 func NewSession(opts ...SessionOption) (*Session, error) {
+	return NewSessionContext(context.Background(), opts...)
+}
+
+// NewSessionContext returns a new vici session. The provided context must be
+// non-nil, and is used when dialing the charon socket. Once the session is
+// established, cancelling the context has no effect on it.
+func NewSessionContext(ctx context.Context, opts ...SessionOption) (*Session, error) {
 	s := &Session{
 		// Set default session opts before applying
 		// the opts passed by the caller.
@@ -55,19 +94,95 @@ func NewSession(opts ...SessionOption) (*Session, error) {
 		opt.apply(s)
 	}
 
+	if s.logger == nil {
+		s.logger = discardLogger
+	}
+
+	if s.redactor == nil {
+		s.redactor = DefaultRedactor
+	}
+
+	s.invokeUnary = chainUnaryInterceptors(s.unaryInterceptors, s.call)
+	s.invokeStream = chainStreamInterceptors(s.streamInterceptors, s.stream)
+
 	if s.cc != nil {
 		// Testing only. A net.Conn was given.
+		s.cc.logger = s.logger
+		s.cc.logPayloads = s.logPayloads
+		s.cc.redactor = s.redactor
+
 		return s, nil
 	}
 
-	conn, err := s.dialer(context.Background(), s.network, s.addr)
+	conn, err := s.dialer(ctx, s.network, s.addr)
 	if err != nil {
+		s.logger.LogAttrs(ctx, slog.LevelWarn, "failed to connect to daemon",
+			slog.String("network", s.network),
+			slog.String("addr", s.addr),
+			slog.Any("error", err),
+		)
+
 		return nil, err
 	}
 
+	if s.verifyPeer != nil {
+		if err := s.checkPeer(conn); err != nil {
+			s.logger.LogAttrs(ctx, slog.LevelWarn, "refusing to use connection to daemon",
+				slog.String("network", s.network),
+				slog.String("addr", s.addr),
+				slog.Any("error", err),
+			)
+
+			// nolint
+			conn.Close()
+
+			return nil, err
+		}
+	}
+
+	if s.tlsConfig != nil {
+		conn, err = s.tlsClient(ctx, conn)
+		if err != nil {
+			s.logger.LogAttrs(ctx, slog.LevelWarn, "failed TLS handshake with daemon",
+				slog.String("network", s.network),
+				slog.String("addr", s.addr),
+				slog.Any("error", err),
+			)
+
+			return nil, err
+		}
+	}
+
+	if s.capture != nil {
+		cc, err := newCaptureConn(conn, s.capture, s.logger)
+		if err != nil {
+			s.logger.LogAttrs(ctx, slog.LevelWarn, "failed to start capturing packets",
+				slog.Any("error", err),
+			)
+
+			// nolint
+			conn.Close()
+
+			return nil, err
+		}
+		conn = cc
+	}
+
+	s.logger.LogAttrs(ctx, slog.LevelInfo, "connected to daemon",
+		slog.String("network", s.network),
+		slog.String("addr", s.addr),
+	)
+
 	s.cc = newClientConn(conn)
+	s.cc.logger = s.logger
+	s.cc.logPayloads = s.logPayloads
+	s.cc.redactor = s.redactor
 	go s.cc.listen()
 
+	if s.healthInterval > 0 {
+		go s.healthCheck(s.healthInterval, s.healthTimeout)
+	}
+
 	return s, nil
 }
 
@@ -76,6 +191,53 @@ func (s *Session) Close() error {
 	return s.cc.Close()
 }
 
+// Done returns a channel that is closed when the session's connection to the
+// daemon ends, either because the session was closed with Close, or because
+// of an error, e.g. the daemon stopped or restarted. Use Err to find out why
+// the connection ended.
+func (s *Session) Done() <-chan struct{} {
+	return s.cc.done
+}
+
+// Err returns nil if Done is not yet closed. Otherwise, Err returns the reason
+// the session's connection ended: ErrSessionClosed if the session was closed
+// with Close, an error wrapping io.EOF if the daemon closed the connection, or
+// otherwise the error that ended the connection, e.g. a malformed packet.
+// Once Done is closed, Err always returns the same error.
+func (s *Session) Err() error {
+	return s.cc.Err()
+}
+
+// Stats holds counters describing the traffic on a Session's connection to
+// the daemon. It is returned by Session.Stats.
+type Stats struct {
+	// PacketsRead and PacketsWritten count the packets read from, and
+	// written to, the daemon.
+	PacketsRead    uint64
+	PacketsWritten uint64
+
+	// EventsDispatched counts the events received from the daemon.
+	EventsDispatched uint64
+
+	// EventsDropped counts the events that were not delivered because the
+	// receiver was not ready, summed over all receivers. SubscriberDrops
+	// holds the number of dropped events for each channel currently
+	// registered with Session.NotifyEvents.
+	EventsDropped   uint64
+	SubscriberDrops map[chan<- Event]uint64
+
+	// ResponsesDropped counts the responses that were dropped because they
+	// were received faster than they were consumed. The request waiting for
+	// a dropped response returns ErrResponseDropped.
+	ResponsesDropped uint64
+}
+
+// Stats returns a snapshot of the counters describing the traffic on the
+// session's connection to the daemon.
+func (s *Session) Stats() Stats {
+	return s.cc.statistics()
+}
+
 // SessionOption is used to specify additional options
 // to a Session.
 type SessionOption interface {
@@ -110,7 +272,8 @@ func WithSocketPath(path string) SessionOption {
 //
 // As the protocol itself currently does not provide any security or
 // authentication properties, it is recommended to run it over a UNIX
-// socket with appropriate permissions.
+// socket with appropriate permissions, or to use WithTLSConfig for
+// TCP sockets.
 func WithAddr(network, addr string) SessionOption {
 	return newFuncSessionOption(func(so *Session) {
 		so.network = network
@@ -153,7 +316,14 @@ func (s *Session) CommandRequest(cmd string, msg *Message) (*Message, error) {
 // The provided context must be non-nil, and can be used to interrupt blocking network I/O
 // involved in the command request.
 func (s *Session) Call(ctx context.Context, cmd string, in *Message) (*Message, error) {
-	s.cc.Lock()
+	return s.invokeUnary(ctx, cmd, in)
+}
+
+// call is the UnaryInvoker at the end of the interceptor chain.
+func (s *Session) call(ctx context.Context, cmd string, in *Message) (*Message, error) {
+	if err := s.cc.LockContext(ctx); err != nil {
+		return nil, err
+	}
 	defer s.cc.Unlock()
 
 	return s.cc.call(ctx, cmd, in)
@@ -190,11 +360,80 @@ func (s *Session) StreamedCommandRequest(cmd string, event string, msg *Message)
 //
 // The provided context must be non-nil, and can be used to interrupt blocking network I/O
 // involved in the command request.
+//
+// The command request is made when the caller starts iterating, and the session is
+// exclusively held by the iterator until the iteration is complete. Other requests made
+// on the session in the meantime, e.g. by other goroutines, wait until then, or until
+// their context is done. In particular, a request made by the loop body on the same
+// session can never be served: it waits until its context is done and returns the
+// context's error, or blocks forever if its context is never done. Collect the required
+// information during the loop instead, or use another Session.
+//
+// The session may also be subscribed to event. In that case, events received during
+// the call are delivered both to the iterator and to the channels registered with
+// NotifyEvents, and the subscription remains in place once the call is complete.
 func (s *Session) CallStreaming(ctx context.Context, cmd string, event string, in *Message) iter.Seq2[*Message, error] {
-	s.cc.Lock()
-	defer s.cc.Unlock()
+	return s.invokeStream(ctx, cmd, event, in)
+}
+
+// stream is the StreamInvoker at the end of the interceptor chain.
+func (s *Session) stream(ctx context.Context, cmd string, event string, in *Message) iter.Seq2[*Message, error] {
+	stream := s.cc.stream(ctx, cmd, event, in)
+
+	return func(yield func(*Message, error) bool) {
+		if err := s.cc.LockContext(ctx); err != nil {
+			yield(nil, err)
+			return
+		}
+		defer s.cc.Unlock()
+
+		stream(yield)
+	}
+}
+
+// CallStreamingFunc makes a command request which involves streaming a given event type, like
+// CallStreaming, but passes each streamed event message to fn, and returns the command response
+// like Call. If fn returns an error, the request is abandoned, and the error is returned.
+//
+// Like CallStreaming, the request goes through the interceptors given with WithStreamInterceptor.
+//
+// As with CallStreaming, the session is exclusively held while the request is made, and requests
+// made by fn on the same session wait until their context is done.
+func (s *Session) CallStreamingFunc(ctx context.Context, cmd string, event string, in *Message, fn func(*Message) error) (*Message, error) {
+	var out *Message
+
+	// Unlike stream, keep the command response, which the iterator only
+	// yields if the command failed.
+	invoker := func(ctx context.Context, cmd string, event string, in *Message) iter.Seq2[*Message, error] {
+		return func(yield func(*Message, error) bool) {
+			if err := s.cc.LockContext(ctx); err != nil {
+				yield(nil, err)
+				return
+			}
+			defer s.cc.Unlock()
+
+			var err error
+
+			out, err = s.cc.streamFunc(ctx, cmd, event, in, func(m *Message) bool {
+				return yield(m, m.Err())
+			})
+			if err != nil {
+				yield(out, err)
+			}
+		}
+	}
+
+	for m, err := range chainStreamInterceptors(s.streamInterceptors, invoker)(ctx, cmd, event, in) {
+		if err != nil {
+			return m, err
+		}
+
+		if err := fn(m); err != nil {
+			return nil, err
+		}
+	}
 
-	return s.cc.stream(ctx, cmd, event, in)
+	return out, nil
 }
 
 // Event represents an event received by a Session sent from the
@@ -216,30 +455,77 @@ type Event struct {
 // Subscribe registers the session to listen for all events given. To receive
 // events that are registered here, use NotifyEvents. An error is returned if
 // Subscribe is not able to register the given events with the charon daemon.
+//
+// Subscribe is equivalent to SubscribeContext with context.Background().
 func (s *Session) Subscribe(events ...string) error {
-	s.cc.Lock()
+	return s.SubscribeContext(context.Background(), events...)
+}
+
+// SubscribeContext registers the session to listen for all events given, like
+// Subscribe. The provided context must be non-nil, and can be used to interrupt
+// blocking network I/O involved in the event registrations.
+//
+// Events are registered one at a time. If an error is returned, the events
+// registered before the error occurred remain registered.
+func (s *Session) SubscribeContext(ctx context.Context, events ...string) error {
+	if err := s.cc.LockContext(ctx); err != nil {
+		return err
+	}
 	defer s.cc.Unlock()
 
-	return s.cc.subscribe(context.Background(), events...)
+	return s.cc.subscribe(ctx, events...)
 }
 
 // Unsubscribe unregisters the given events, so the session will no longer
 // receive events of the given type. If a given event is not valid, an error
 // is retured.
+//
+// Unsubscribe is equivalent to UnsubscribeContext with context.Background().
 func (s *Session) Unsubscribe(events ...string) error {
-	s.cc.Lock()
+	return s.UnsubscribeContext(context.Background(), events...)
+}
+
+// UnsubscribeContext unregisters the given events, like Unsubscribe. The provided
+// context must be non-nil, and can be used to interrupt blocking network I/O
+// involved in the event unregistrations.
+//
+// Events are unregistered one at a time. If an error is returned, the events
+// unregistered before the error occurred remain unregistered. The event whose
+// unregistration failed is no longer delivered by the session either.
+func (s *Session) UnsubscribeContext(ctx context.Context, events ...string) error {
+	if err := s.cc.LockContext(ctx); err != nil {
+		return err
+	}
 	defer s.cc.Unlock()
 
-	return s.cc.unsubscribe(context.Background(), events...)
+	return s.cc.unsubscribe(ctx, events...)
 }
 
 // UnsubscribeAll unregisters all events that the session is currently
 // subscribed to.
+//
+// UnsubscribeAll is equivalent to UnsubscribeAllContext with context.Background().
 func (s *Session) UnsubscribeAll() error {
-	s.cc.Lock()
+	return s.UnsubscribeAllContext(context.Background())
+}
+
+// UnsubscribeAllContext unregisters all events that the session is currently
+// subscribed to, like UnsubscribeAll. The provided context must be non-nil, and
+// can be used to interrupt blocking network I/O involved in the event
+// unregistrations.
+func (s *Session) UnsubscribeAllContext(ctx context.Context) error {
+	if err := s.cc.LockContext(ctx); err != nil {
+		return err
+	}
 	defer s.cc.Unlock()
 
-	return s.cc.unsubscribe(context.Background())
+	return s.cc.unsubscribe(ctx)
+}
+
+// Subscriptions returns the events that the session is currently subscribed to,
+// in the order they were subscribed.
+func (s *Session) Subscriptions() []string {
+	return s.cc.subscriptions()
 }
 
 // NotifyEvents registers c for writing received events. The Session must first
@@ -247,7 +533,8 @@ func (s *Session) UnsubscribeAll() error {
 //
 // Writes to c will not block: the caller must ensure that c has sufficient
 // buffer space to keep up with the expected event rate. If the write to c
-// would block, the event is discarded.
+// would block, the event is discarded, and counted in the Stats returned by
+// Session.Stats.
 //
 // NotifyEvents may be called multiple times with different channels: each
 // channel will indepedently receive a copy of each event received by the
@@ -255,7 +542,7 @@ func (s *Session) UnsubscribeAll() error {
 //
 // When the Session is Close()'d, or the event listener otherwise exits, e.g.
 // due to the daemon stopping or restarting, c will be closed to indicate
-// that no more events will be passed to it.
+// that no more events will be passed to it. Session.Err reports the reason.
 func (s *Session) NotifyEvents(c chan<- Event) {
 	s.cc.notify(c)
 }
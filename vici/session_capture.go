@@ -0,0 +1,245 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// captureMagic starts every capture, and identifies the capture format version.
+const captureMagic = "VICICAP1"
+
+// ErrInvalidCapture is returned by NewCaptureReader and CaptureReader.Next when
+// the data is not a capture written by WithCapture.
+var ErrInvalidCapture = errors.New("vici: invalid capture")
+
+// WithCapture makes the Session record every packet sent to, and received from,
+// the daemon to w, together with its direction and the time it was sent or
+// received. The capture can be read with NewCaptureReader, and replayed with
+// the vicitest package.
+//
+// The capture holds the packets as they are transmitted, so it is not redacted,
+// and may contain secrets. Packets are written to w as they are transmitted,
+// until the session's connection is closed. If writing to w fails, capturing
+// stops, and the error is logged. The Session does not close w.
+func WithCapture(w io.Writer) SessionOption {
+	return newFuncSessionOption(func(so *Session) {
+		so.capture = w
+	})
+}
+
+// CaptureDirection is the direction of a captured packet.
+type CaptureDirection uint8
+
+const (
+	// CaptureSent is the direction of packets sent to the daemon.
+	CaptureSent CaptureDirection = iota + 1
+
+	// CaptureReceived is the direction of packets received from the daemon.
+	CaptureReceived
+)
+
+// String returns "sent" or "received".
+func (d CaptureDirection) String() string {
+	switch d {
+	case CaptureSent:
+		return "sent"
+	case CaptureReceived:
+		return "received"
+	default:
+		return fmt.Sprintf("CaptureDirection(%d)", uint8(d))
+	}
+}
+
+// CaptureRecord is a packet recorded by WithCapture.
+type CaptureRecord struct {
+	// Time is when the packet was sent or received.
+	Time time.Time
+
+	// Direction is the direction of the packet.
+	Direction CaptureDirection
+
+	// Packet is the captured packet.
+	Packet *Packet
+}
+
+// CaptureReader reads the records of a capture written by WithCapture.
+type CaptureReader struct {
+	r *bufio.Reader
+}
+
+// NewCaptureReader returns a CaptureReader reading from r. An error wrapping
+// ErrInvalidCapture is returned if r does not start with a capture header.
+func NewCaptureReader(r io.Reader) (*CaptureReader, error) {
+	cr := &CaptureReader{r: bufio.NewReader(r)}
+
+	magic := make([]byte, len(captureMagic))
+	if _, err := io.ReadFull(cr.r, magic); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: missing header", ErrInvalidCapture)
+		}
+
+		return nil, err
+	}
+
+	if string(magic) != captureMagic {
+		return nil, fmt.Errorf("%w: bad header %q", ErrInvalidCapture, magic)
+	}
+
+	return cr, nil
+}
+
+// Next returns the next record of the capture. At the end of the capture, Next
+// returns io.EOF. If the capture ends within a record, e.g. because the Session
+// is still writing it, io.ErrUnexpectedEOF is returned.
+func (cr *CaptureReader) Next() (*CaptureRecord, error) {
+	var hdr [9]byte
+
+	if _, err := io.ReadFull(cr.r, hdr[:]); err != nil {
+		return nil, err
+	}
+
+	dir := CaptureDirection(hdr[0])
+	if dir != CaptureSent && dir != CaptureReceived {
+		return nil, fmt.Errorf("%w: unknown direction %d", ErrInvalidCapture, hdr[0])
+	}
+
+	p, err := ReadPacket(cr.r)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	return &CaptureRecord{
+		Time:      time.Unix(0, int64(binary.BigEndian.Uint64(hdr[1:]))),
+		Direction: dir,
+		Packet:    p,
+	}, nil
+}
+
+// captureConn is a net.Conn which records the packets transmitted over it.
+type captureConn struct {
+	net.Conn
+
+	logger *slog.Logger
+
+	// The packet data read and written, until a whole packet has been
+	// transmitted.
+	rbuf bytes.Buffer
+	wbuf bytes.Buffer
+
+	// Guards w, and the closed and failed flags. Once either is set, nothing
+	// more is recorded.
+	mu     sync.Mutex
+	w      io.Writer
+	closed bool
+	failed bool
+}
+
+// newCaptureConn returns conn wrapped to record its packets to w. The capture
+// header is written immediately.
+func newCaptureConn(conn net.Conn, w io.Writer, logger *slog.Logger) (*captureConn, error) {
+	if _, err := io.WriteString(w, captureMagic); err != nil {
+		return nil, fmt.Errorf("failed to write capture header: %w", err)
+	}
+
+	return &captureConn{Conn: conn, w: w, logger: logger}, nil
+}
+
+func (c *captureConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.record(CaptureReceived, &c.rbuf, b[:n])
+	}
+
+	return n, err
+}
+
+func (c *captureConn) Write(b []byte) (int, error) {
+	// Record before writing, as the response to a request may otherwise be
+	// received, and recorded, before the write of the request returns. If the
+	// write fails, the connection is broken, and nothing else is recorded.
+	c.record(CaptureSent, &c.wbuf, b)
+
+	n, err := c.Conn.Write(b)
+	if err != nil {
+		c.mu.Lock()
+		c.failed = true
+		c.mu.Unlock()
+	}
+
+	return n, err
+}
+
+func (c *captureConn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	return c.Conn.Close()
+}
+
+// record appends the transmitted data to buf, and writes a record for every
+// packet it completes.
+func (c *captureConn) record(dir CaptureDirection, buf *bytes.Buffer, b []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed || c.failed {
+		return
+	}
+
+	buf.Write(b)
+
+	for buf.Len() >= 4 {
+		pl := int(binary.BigEndian.Uint32(buf.Bytes()[:4]))
+		if buf.Len() < 4+pl {
+			return
+		}
+
+		rec := make([]byte, 9, 9+4+pl)
+		rec[0] = byte(dir)
+		binary.BigEndian.PutUint64(rec[1:], uint64(time.Now().UnixNano()))
+		rec = append(rec, buf.Next(4+pl)...)
+
+		if _, err := c.w.Write(rec); err != nil {
+			c.failed = true
+			c.logger.LogAttrs(context.Background(), slog.LevelWarn, "stopped capturing packets",
+				slog.Any("error", err),
+			)
+
+			return
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestWithCapture(t *testing.T) {
+	var buf bytes.Buffer
+
+	s, ts := newTestSession(t, WithCapture(&buf))
+	defer ts.conn.Close()
+
+	if err := s.Subscribe("event-confirm"); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+
+	in, err := Build().Set("a", "test").Set("b", "123").Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Call(context.Background(), "cmd-strcat", in); err != nil {
+		t.Fatalf("Unexpected error from call: %v", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Unexpected error closing session: %v", err)
+	}
+
+	cr, err := NewCaptureReader(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error reading capture: %v", err)
+	}
+
+	expected := []struct {
+		dir   CaptureDirection
+		ptype PacketType
+		name  string
+	}{
+		{CaptureSent, PacketEventRegister, "event-confirm"},
+		{CaptureReceived, PacketEventConfirm, ""},
+		{CaptureSent, PacketCmdRequest, "cmd-strcat"},
+		{CaptureReceived, PacketCmdResponse, ""},
+	}
+
+	var records []*CaptureRecord
+	for {
+		rec, err := cr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error reading capture: %v", err)
+		}
+
+		records = append(records, rec)
+	}
+
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %d", len(expected), len(records))
+	}
+
+	for i, rec := range records {
+		e := expected[i]
+
+		if rec.Direction != e.dir || rec.Packet.Type != e.ptype || rec.Packet.Name != e.name {
+			t.Errorf("Expected record %d to be %v %v %q, got %v %v %q",
+				i, e.dir, e.ptype, e.name, rec.Direction, rec.Packet.Type, rec.Packet.Name)
+		}
+
+		if i > 0 && rec.Time.Before(records[i-1].Time) {
+			t.Errorf("Record %d is older than its predecessor", i)
+		}
+	}
+
+	if got := records[2].Packet.Message.Get("b"); got != "123" {
+		t.Errorf("Expected captured request to hold b=123, got %v", got)
+	}
+
+	if got := records[3].Packet.Message.Get("c"); got != "test123" {
+		t.Errorf("Expected captured response to hold c=test123, got %v", got)
+	}
+}
+
+// failingWriter accepts n writes, and then fails.
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errors.New("disk full")
+	}
+	w.n--
+
+	return len(b), nil
+}
+
+func TestWithCaptureWriteError(t *testing.T) {
+	if _, err := NewSession(WithCapture(&failingWriter{}), WithDialContext(
+		func(_ context.Context, _, _ string) (net.Conn, error) {
+			client, _ := net.Pipe()
+
+			return client, nil
+		},
+	)); err == nil {
+		t.Fatal("Expected error when the capture header cannot be written")
+	}
+
+	// Once capturing fails, the session keeps working.
+	s, ts := newTestSession(t, WithCapture(&failingWriter{n: 2}))
+	defer ts.conn.Close()
+	defer s.Close()
+
+	in, err := Build().Set("a", "test").Set("b", "123").Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		if _, err := s.Call(context.Background(), "cmd-strcat", in); err != nil {
+			t.Fatalf("Unexpected error from call: %v", err)
+		}
+	}
+}
+
+func TestCaptureReaderInvalid(t *testing.T) {
+	p, err := (&Packet{Type: PacketCmdRequest, Name: "version"}).encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected error
+	}{
+		{
+			name:     "empty",
+			data:     "",
+			expected: ErrInvalidCapture,
+		},
+		{
+			name:     "bad header",
+			data:     "VICICAP9",
+			expected: ErrInvalidCapture,
+		},
+		{
+			name:     "bad direction",
+			data:     captureMagic + "\x03" + strings.Repeat("\x00", 8) + string(p),
+			expected: ErrInvalidCapture,
+		},
+		{
+			name:     "truncated record",
+			data:     captureMagic + "\x01" + strings.Repeat("\x00", 8) + string(p[:len(p)-1]),
+			expected: io.ErrUnexpectedEOF,
+		},
+		{
+			name:     "truncated time",
+			data:     captureMagic + "\x01\x00",
+			expected: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr, err := NewCaptureReader(strings.NewReader(tt.data))
+			if err == nil {
+				_, err = cr.Next()
+			}
+
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
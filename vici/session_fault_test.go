@@ -0,0 +1,295 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+// newFaultySession returns a session connected to a vicitest.Server through a
+// vicitest.FaultInjector with faults. The server has an echo command, which
+// responds with its arguments, and a count command, which streams a count-item
+// event for each number up to n.
+func newFaultySession(t *testing.T, faults ...vicitest.Fault) *vici.Session {
+	t.Helper()
+
+	srv := vicitest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	srv.Handle("echo", func(req *vicitest.Request) (*vici.Message, error) {
+		return req.Message, nil
+	})
+	srv.Handle("count", func(req *vicitest.Request) (*vici.Message, error) {
+		n, err := strconv.Atoi(req.Message.Get("n").(string))
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range n {
+			m, err := vici.Build().Set("i", strconv.Itoa(i)).Message()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := req.Emit("count-item", m); err != nil {
+				return nil, err
+			}
+		}
+
+		return vici.NewMessage(), nil
+	})
+
+	fi := vicitest.NewFaultInjector(srv.DialContext, faults...)
+	t.Cleanup(func() { fi.Close() })
+
+	s, err := vici.NewSession(vici.WithDialContext(fi.DialContext))
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+// echo makes an echo request with the given value of k, and checks that the
+// response matches it.
+func echo(ctx context.Context, s *vici.Session, k string) error {
+	in, err := vici.Build().Set("k", k).Message()
+	if err != nil {
+		return err
+	}
+
+	out, err := s.Call(ctx, "echo", in)
+	if err != nil {
+		return err
+	}
+
+	if got := out.Get("k"); got != k {
+		return errors.New("got response k=" + got.(string) + ", expected k=" + k)
+	}
+
+	return nil
+}
+
+func TestSessionFaultSplitPackets(t *testing.T) {
+	s := newFaultySession(t,
+		// Split the request and the response within, and right after,
+		// the length header.
+		vicitest.Fault{
+			Direction: vici.CaptureSent,
+			Kind:      vicitest.FaultSplit,
+			Offsets:   []int{1, 4, 7},
+			Delay:     5 * time.Millisecond,
+		},
+		vicitest.Fault{
+			Direction: vici.CaptureReceived,
+			Kind:      vicitest.FaultSplit,
+			Offsets:   []int{2, 4, 5},
+			Delay:     5 * time.Millisecond,
+		},
+	)
+
+	for _, k := range []string{"a", "b"} {
+		if err := echo(context.Background(), s, k); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+}
+
+func TestSessionFaultLateResponse(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := newFaultySession(t, vicitest.Fault{
+			Direction: vici.CaptureReceived,
+			Kind:      vicitest.FaultDelay,
+			Delay:     time.Second,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		if err := echo(ctx, s, "a"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+		}
+
+		// The late response to the abandoned request must be skipped.
+		if err := echo(context.Background(), s, "b"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func TestSessionFaultDuplicateResponse(t *testing.T) {
+	s := newFaultySession(t, vicitest.Fault{
+		Direction: vici.CaptureReceived,
+		Kind:      vicitest.FaultDuplicate,
+	})
+
+	if err := echo(context.Background(), s, "a"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A duplicate received after the next request is sent cannot be told
+	// apart from its response, so wait until the duplicate is read.
+	for s.Stats().PacketsRead < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The duplicate of the first response must not be taken as the
+	// response to the following requests.
+	for _, k := range []string{"b", "c"} {
+		if err := echo(context.Background(), s, k); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+}
+
+func TestSessionFaultDroppedResponse(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s := newFaultySession(t, vicitest.Fault{
+			Direction: vici.CaptureReceived,
+			Kind:      vicitest.FaultDrop,
+		})
+
+		// Without a deadline, the request waits until nothing is
+		// received from the daemon for too long.
+		err := echo(context.Background(), s, "a")
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatalf("Expected %v, got %v", os.ErrDeadlineExceeded, err)
+		}
+
+		<-s.Done()
+		if !errors.Is(s.Err(), os.ErrDeadlineExceeded) {
+			t.Fatalf("Expected session to end with %v, got %v", os.ErrDeadlineExceeded, s.Err())
+		}
+	})
+}
+
+func TestSessionFaultBrokenResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		fault    vicitest.Fault
+		expected error
+	}{
+		{
+			name: "truncated",
+			fault: vicitest.Fault{
+				Direction: vici.CaptureReceived,
+				Kind:      vicitest.FaultTruncate,
+				Length:    6,
+			},
+			expected: io.ErrUnexpectedEOF,
+		},
+		{
+			name: "truncated header",
+			fault: vicitest.Fault{
+				Direction: vici.CaptureReceived,
+				Kind:      vicitest.FaultTruncate,
+				Length:    2,
+			},
+			expected: io.ErrUnexpectedEOF,
+		},
+		{
+			name: "disconnect",
+			fault: vicitest.Fault{
+				Direction: vici.CaptureReceived,
+				Kind:      vicitest.FaultDisconnect,
+			},
+			expected: io.EOF,
+		},
+		{
+			name: "garbage",
+			fault: vicitest.Fault{
+				Direction: vici.CaptureReceived,
+				Kind:      vicitest.FaultGarbage,
+				// A packet of an unknown type.
+				Data: []byte{0, 0, 0, 1, 42},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFaultySession(t, tt.fault)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := echo(ctx, s, "a"); err == nil {
+				t.Fatal("Expected error from broken response")
+			}
+
+			select {
+			case <-s.Done():
+			case <-ctx.Done():
+				t.Fatal("Expected session to end")
+			}
+
+			if tt.expected != nil && !errors.Is(s.Err(), tt.expected) {
+				t.Fatalf("Expected session to end with %v, got %v", tt.expected, s.Err())
+			}
+		})
+	}
+}
+
+func TestSessionFaultStreamDisconnect(t *testing.T) {
+	s := newFaultySession(t, vicitest.Fault{
+		// The event registration is confirmed by packet 0, and the
+		// third event is packet 3.
+		Direction: vici.CaptureReceived,
+		Packet:    3,
+		Kind:      vicitest.FaultDisconnect,
+	})
+
+	in, err := vici.Build().Set("n", "5").Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	for _, err := range s.CallStreaming(context.Background(), "count", "count-item", in) {
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("Expected %v, got %v", io.EOF, err)
+			}
+
+			break
+		}
+		n++
+	}
+
+	if n != 2 {
+		t.Fatalf("Expected 2 events before the disconnect, got %d", n)
+	}
+
+	if err := echo(context.Background(), s, "a"); err == nil {
+		t.Fatal("Expected error from request after disconnect")
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ErrHealthCheckFailed is returned by Session.Err, and by requests made on the
+// Session, after the daemon failed to respond to a health check enabled with
+// WithHealthCheck.
+var ErrHealthCheckFailed = errors.New("vici: health check failed")
+
+// WithHealthCheck enables a background health check of the session's connection.
+// Whenever nothing was received from the daemon for interval, the session is
+// pinged as with Session.Ping. If the daemon does not respond within timeout, the
+// connection is closed, and Session.Err returns an error wrapping
+// ErrHealthCheckFailed. Use Session.Done to be notified, e.g. to create a new
+// Session. If timeout is not positive, interval is used as the timeout.
+//
+// The check is skipped if another request is in progress at the time, as that
+// request notices an unresponsive daemon by itself.
+func WithHealthCheck(interval, timeout time.Duration) SessionOption {
+	return newFuncSessionOption(func(so *Session) {
+		so.healthInterval = interval
+		so.healthTimeout = timeout
+	})
+}
+
+// Ping checks that the daemon responds to requests, by sending the version
+// command. Unlike requests made with Call, Ping does not go through the
+// session's interceptors.
+//
+// The provided context must be non-nil, and can be used to limit how long to
+// wait for the daemon's response.
+func (s *Session) Ping(ctx context.Context) error {
+	if err := s.cc.LockContext(ctx); err != nil {
+		return err
+	}
+	defer s.cc.Unlock()
+
+	return s.cc.ping(ctx)
+}
+
+// healthCheck pings the daemon when the session is idle, until the connection
+// ends, and ends the connection if a ping fails.
+func (s *Session) healthCheck(interval, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = interval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := s.cc.stats.packetsRead.Load()
+
+	for {
+		select {
+		case <-s.cc.done:
+			return
+		case <-ticker.C:
+		}
+
+		// Anything received since the last check shows that the daemon
+		// is still there.
+		if n := s.cc.stats.packetsRead.Load(); n != last {
+			last = n
+
+			continue
+		}
+
+		if !s.cc.TryLock() {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := s.cc.ping(ctx)
+		cancel()
+
+		s.cc.Unlock()
+
+		if err != nil && s.cc.Err() == nil {
+			s.logger.LogAttrs(context.Background(), slog.LevelWarn, "health check failed",
+				slog.Duration("timeout", timeout),
+				slog.Any("error", err),
+			)
+
+			s.cc.fail(fmt.Errorf("%w: %w", ErrHealthCheckFailed, err))
+
+			return
+		}
+
+		last = s.cc.stats.packetsRead.Load()
+	}
+}
+
+func (cc *clientConn) ping(ctx context.Context) error {
+	_, err := cc.call(ctx, "version", nil)
+
+	return err
+}
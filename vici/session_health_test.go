@@ -0,0 +1,131 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// newUnresponsiveSession returns a session connected to a daemon that reads
+// requests, but never responds.
+func newUnresponsiveSession(t *testing.T, opts ...SessionOption) *Session {
+	t.Helper()
+
+	client, server := net.Pipe()
+	t.Cleanup(func() { server.Close() })
+
+	go func() {
+		// nolint
+		io.Copy(io.Discard, server)
+	}()
+
+	dialer := func(_ context.Context, _, _ string) (net.Conn, error) {
+		return client, nil
+	}
+
+	s, err := NewSession(append(opts, WithDialContext(dialer))...)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	return s
+}
+
+func TestSessionPing(t *testing.T) {
+	s, _ := newTestSession(t)
+	defer s.Close()
+
+	if err := s.Ping(context.Background()); err != nil {
+		t.Fatalf("Unexpected error from Ping: %v", err)
+	}
+}
+
+func TestSessionPingTimeout(t *testing.T) {
+	s := newUnresponsiveSession(t)
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := s.Ping(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded from Ping, got %v", err)
+	}
+}
+
+func TestSessionHealthCheck(t *testing.T) {
+	s, _ := newTestSession(t, WithHealthCheck(10*time.Millisecond, time.Second))
+	defer s.Close()
+
+	// Wait for a few health checks to be done.
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Stats().PacketsWritten < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for health checks")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := s.Err(); err != nil {
+		t.Fatalf("Unexpected session error after successful health checks: %v", err)
+	}
+}
+
+func TestSessionHealthCheckFailed(t *testing.T) {
+	s := newUnresponsiveSession(t, WithHealthCheck(10*time.Millisecond, 50*time.Millisecond))
+	defer s.Close()
+
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for health check to fail")
+	}
+
+	if err := s.Err(); !errors.Is(err, ErrHealthCheckFailed) {
+		t.Fatalf("Expected ErrHealthCheckFailed, got %v", err)
+	}
+
+	if _, err := s.Call(context.Background(), "version", nil); !errors.Is(err, ErrHealthCheckFailed) {
+		t.Fatalf("Expected ErrHealthCheckFailed from Call, got %v", err)
+	}
+}
+
+func TestSessionHealthCheckSkippedWhileBusy(t *testing.T) {
+	s, _ := newTestSession(t, WithHealthCheck(10*time.Millisecond, time.Second))
+	defer s.Close()
+
+	// Hold the session, as a request in progress would. No health
+	// checks must be made in the meantime.
+	s.cc.Lock()
+	written := s.Stats().PacketsWritten
+	time.Sleep(100 * time.Millisecond)
+
+	if n := s.Stats().PacketsWritten; n != written {
+		s.cc.Unlock()
+		t.Fatalf("Expected no health checks while busy, %d packets were written", n-written)
+	}
+	s.cc.Unlock()
+}
@@ -0,0 +1,102 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"context"
+	"iter"
+)
+
+// UnaryInvoker is called by a UnaryInterceptor to continue a command request made
+// with Session.Call, either by calling the next interceptor in the chain, or by making
+// the actual request.
+type UnaryInvoker func(ctx context.Context, cmd string, in *Message) (*Message, error)
+
+// UnaryInterceptor intercepts command requests made with Session.Call. The interceptor
+// is responsible for calling invoker to continue the request, and may inspect or modify
+// the command arguments before, and the response after, doing so. The interceptor may
+// also call invoker more than once, e.g. to retry the request, or not at all.
+//
+// The session is not held while an interceptor runs, only during each call to the
+// invoker at the end of the chain. An interceptor may therefore make other requests
+// on the same session.
+type UnaryInterceptor func(ctx context.Context, cmd string, in *Message, invoker UnaryInvoker) (*Message, error)
+
+// StreamInvoker is called by a StreamInterceptor to continue a streamed command request
+// made with Session.CallStreaming or Session.CallStreamingFunc, either by calling the
+// next interceptor in the chain, or by making the actual request.
+type StreamInvoker func(ctx context.Context, cmd string, event string, in *Message) iter.Seq2[*Message, error]
+
+// StreamInterceptor intercepts streamed command requests made with Session.CallStreaming
+// and Session.CallStreamingFunc. The interceptor is responsible for calling invoker to
+// continue the request, and returns the iterator given to the caller. To inspect or
+// modify the streamed messages, the interceptor can return an iterator that wraps the
+// one returned by invoker.
+//
+// As with Session.CallStreaming, the request is only made once the caller starts
+// iterating, and the session is held until the iteration is complete. The wrapping
+// iterator must not make requests on the same session while ranging over the wrapped
+// iterator.
+type StreamInterceptor func(ctx context.Context, cmd string, event string, in *Message, invoker StreamInvoker) iter.Seq2[*Message, error]
+
+// WithUnaryInterceptor adds interceptors for command requests made with Session.Call,
+// and the deprecated Session.CommandRequest. When this option is given more than once,
+// or with several interceptors, the interceptors are chained in the order given: the
+// first interceptor is the outermost, and the last one calls the actual request.
+func WithUnaryInterceptor(interceptors ...UnaryInterceptor) SessionOption {
+	return newFuncSessionOption(func(so *Session) {
+		so.unaryInterceptors = append(so.unaryInterceptors, interceptors...)
+	})
+}
+
+// WithStreamInterceptor adds interceptors for streamed command requests made with
+// Session.CallStreaming, Session.CallStreamingFunc, and the deprecated
+// Session.StreamedCommandRequest. The interceptors are chained in the same way as with
+// WithUnaryInterceptor.
+func WithStreamInterceptor(interceptors ...StreamInterceptor) SessionOption {
+	return newFuncSessionOption(func(so *Session) {
+		so.streamInterceptors = append(so.streamInterceptors, interceptors...)
+	})
+}
+
+func chainUnaryInterceptors(interceptors []UnaryInterceptor, invoker UnaryInvoker) UnaryInvoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+
+		invoker = func(ctx context.Context, cmd string, in *Message) (*Message, error) {
+			return interceptor(ctx, cmd, in, next)
+		}
+	}
+
+	return invoker
+}
+
+func chainStreamInterceptors(interceptors []StreamInterceptor, invoker StreamInvoker) StreamInvoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+
+		invoker = func(ctx context.Context, cmd string, event string, in *Message) iter.Seq2[*Message, error] {
+			return interceptor(ctx, cmd, event, in, next)
+		}
+	}
+
+	return invoker
+}
@@ -0,0 +1,195 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestUnaryInterceptorChain(t *testing.T) {
+	var order []string
+
+	record := func(name string) UnaryInterceptor {
+		return func(ctx context.Context, cmd string, in *Message, invoker UnaryInvoker) (*Message, error) {
+			order = append(order, name+":before")
+			defer func() { order = append(order, name+":after") }()
+
+			return invoker(ctx, cmd, in)
+		}
+	}
+
+	modify := func(ctx context.Context, cmd string, in *Message, invoker UnaryInvoker) (*Message, error) {
+		if cmd != "cmd-strcat" {
+			return invoker(ctx, cmd, in)
+		}
+
+		if err := in.Set("b", "-intercepted"); err != nil {
+			return nil, err
+		}
+
+		out, err := invoker(ctx, cmd, in)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := out.Set("intercepted", "yes"); err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	}
+
+	s, ts := newTestSession(t,
+		WithUnaryInterceptor(record("first"), record("second")),
+		WithUnaryInterceptor(modify),
+	)
+	defer s.Close()
+	defer ts.conn.Close()
+
+	in, err := Build().Set("a", "call").Set("b", "").Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := s.Call(context.Background(), "cmd-strcat", in)
+	if err != nil {
+		t.Fatalf("Unexpected error from call: %v", err)
+	}
+
+	if c := out.Get("c"); c != "call-intercepted" {
+		t.Fatalf("Expected modified request, got c=%v", c)
+	}
+
+	if v := out.Get("intercepted"); v != "yes" {
+		t.Fatalf("Expected modified response, got %s", out)
+	}
+
+	want := []string{"first:before", "second:before", "second:after", "first:after"}
+	if !slices.Equal(order, want) {
+		t.Fatalf("Unexpected interceptor order.\nExpected: %v\nReceived: %v", want, order)
+	}
+
+	// Deprecated methods are intercepted too.
+	order = order[:0]
+
+	if _, err := s.CommandRequest("cmd-ok", nil); err != nil {
+		t.Fatalf("Unexpected error from command request: %v", err)
+	}
+
+	if !slices.Equal(order, want) {
+		t.Fatalf("Unexpected interceptor order.\nExpected: %v\nReceived: %v", want, order)
+	}
+}
+
+func TestUnaryInterceptorReentrant(t *testing.T) {
+	var s *Session
+
+	calls := 0
+
+	// Retry failed commands once, after making another request on the
+	// same session.
+	retry := func(ctx context.Context, cmd string, in *Message, invoker UnaryInvoker) (*Message, error) {
+		calls++
+
+		out, err := invoker(ctx, cmd, in)
+		if err == nil {
+			return out, nil
+		}
+
+		if _, err := s.Call(ctx, "cmd-ok", nil); err != nil {
+			return nil, err
+		}
+
+		return invoker(ctx, cmd, in)
+	}
+
+	s, ts := newTestSession(t, WithUnaryInterceptor(retry))
+	defer s.Close()
+	defer ts.conn.Close()
+
+	if _, err := s.Call(context.Background(), "cmd-err", nil); err == nil {
+		t.Fatal("Expected error from cmd-err")
+	}
+
+	// The original call, and the one made by the interceptor.
+	if calls != 2 {
+		t.Fatalf("Expected interceptor to be called twice, got %d", calls)
+	}
+}
+
+func TestStreamInterceptor(t *testing.T) {
+	var (
+		cmds   []string
+		events int
+	)
+
+	count := func(ctx context.Context, cmd string, event string, in *Message, invoker StreamInvoker) iter.Seq2[*Message, error] {
+		cmds = append(cmds, cmd)
+
+		return func(yield func(*Message, error) bool) {
+			for m, err := range invoker(ctx, cmd, event, in) {
+				if err == nil {
+					events++
+
+					if err := m.Set("intercepted", "yes"); err != nil {
+						yield(nil, err)
+						return
+					}
+				}
+
+				if !yield(m, err) {
+					return
+				}
+			}
+		}
+	}
+
+	s, ts := newTestSession(t, WithStreamInterceptor(count))
+	defer s.Close()
+	defer ts.conn.Close()
+
+	n := 0
+	for m, err := range s.CallStreaming(context.Background(), "cmd-stream", "event-stream", nil) {
+		if err != nil {
+			t.Fatalf("Unexpected error from stream: %v", err)
+		}
+
+		if v := m.Get("intercepted"); v != "yes" {
+			t.Fatalf("Expected modified event, got %s", m)
+		}
+		n++
+	}
+
+	if n != 3 || events != 3 {
+		t.Fatalf("Expected 3 events, got %d (interceptor saw %d)", n, events)
+	}
+
+	if _, err := s.StreamedCommandRequest("cmd-stream", "event-stream", nil); err != nil {
+		t.Fatalf("Unexpected error from streamed command request: %v", err)
+	}
+
+	if !slices.Equal(cmds, []string{"cmd-stream", "cmd-stream"}) {
+		t.Fatalf("Expected both streamed calls to be intercepted, got %v", cmds)
+	}
+}
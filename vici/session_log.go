@@ -0,0 +1,82 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// WithLogger specifies the logger used by the Session. The Session logs its
+// connection lifecycle, event registrations and dropped packets at info level
+// and above, and each command request at debug level. If this option is not
+// specified, or logger is nil, nothing is logged.
+func WithLogger(logger *slog.Logger) SessionOption {
+	return newFuncSessionOption(func(so *Session) {
+		so.logger = logger
+	})
+}
+
+// WithPayloadLogging makes the Session log the contents of every packet sent
+// to, and received from, the daemon at debug level. Values which hold secrets,
+// such as private keys, shared secrets and PINs, are redacted from the logged
+// messages, see WithRedactor. This option has no effect without WithLogger.
+func WithPayloadLogging() SessionOption {
+	return newFuncSessionOption(func(so *Session) {
+		so.logPayloads = true
+	})
+}
+
+// WithRedactor specifies the Redactor used to redact secrets from the payloads
+// logged because of WithPayloadLogging. If this option is not specified, or r
+// is nil, DefaultRedactor is used.
+func WithRedactor(r *Redactor) SessionOption {
+	return newFuncSessionOption(func(so *Session) {
+		so.redactor = r
+	})
+}
+
+// discardLogger is used when no logger is given to the Session.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// payloadValue lazily formats a redacted packet payload for logging, so that
+// it is only done when the log record is actually handled.
+type payloadValue struct {
+	m *Message
+	r *Redactor
+}
+
+func (v payloadValue) LogValue() slog.Value {
+	return slog.StringValue(v.r.Redact(v.m).stringIndent("", "  "))
+}
+
+// terminationLevel returns the level used to log the end of a connection.
+func terminationLevel(err error) slog.Level {
+	switch {
+	case errors.Is(err, ErrSessionClosed):
+		return slog.LevelInfo
+	case errors.Is(err, io.EOF):
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
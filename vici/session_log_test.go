@@ -0,0 +1,123 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	s, ts := newTestSession(t, WithLogger(logger), WithPayloadLogging())
+	defer ts.conn.Close()
+
+	if err := s.Subscribe("event-confirm"); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+
+	in, err := Build().Set("a", "x").Set("b", "y").Set("pin", "1234").Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Call(context.Background(), "cmd-strcat", in); err != nil {
+		t.Fatalf("Unexpected error from call: %v", err)
+	}
+
+	if _, err := s.Call(context.Background(), "cmd-unknown", nil); err == nil {
+		t.Fatal("Expected error from unknown command")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Unexpected error closing session: %v", err)
+	}
+	<-s.Done()
+
+	out := buf.String()
+
+	for _, want := range []string{
+		`level=INFO msg="connected to daemon"`,
+		`level=DEBUG msg="event registration" event=event-confirm`,
+		`level=DEBUG msg="command request" command=cmd-strcat`,
+		`level=WARN msg="command request" command=cmd-unknown`,
+		`level=DEBUG msg="sent packet" type=CMD_REQUEST name=cmd-strcat`,
+		`level=DEBUG msg="received packet" type=CMD_RESPONSE`,
+		redactedValue,
+		`level=INFO msg="connection ended"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected log to contain %q", want)
+		}
+	}
+
+	if strings.Contains(out, "1234") {
+		t.Error("Log contains unredacted payload")
+	}
+
+	if t.Failed() {
+		t.Logf("Log:\n%s", out)
+	}
+}
+
+func TestWithRedactor(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	r := &Redactor{Patterns: []string{"a"}, KeepLength: true}
+
+	s, ts := newTestSession(t, WithLogger(logger), WithPayloadLogging(), WithRedactor(r))
+	defer ts.conn.Close()
+
+	in, err := Build().Set("a", "token").Set("b", "y").Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Call(context.Background(), "cmd-strcat", in); err != nil {
+		t.Fatalf("Unexpected error from call: %v", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Unexpected error closing session: %v", err)
+	}
+	<-s.Done()
+
+	out := buf.String()
+
+	if !strings.Contains(out, "a = <redacted:5>") {
+		t.Errorf("Expected log to contain redacted value with length")
+	}
+
+	if strings.Contains(out, "a = token") {
+		t.Error("Log contains unredacted payload")
+	}
+
+	if t.Failed() {
+		t.Logf("Log:\n%s", out)
+	}
+}
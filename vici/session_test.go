@@ -22,12 +22,19 @@ package vici
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"iter"
 	"net"
 	"os/exec"
 	"reflect"
+	"slices"
+	"strconv"
+	"sync"
 	"testing"
+	"testing/synctest"
 	"time"
 )
 
@@ -80,6 +87,447 @@ func TestCommandRequestAfterClose(t *testing.T) {
 	}
 }
 
+// newTestSession returns a Session connected to a testServer, using opts in addition
+// to a dialer. The session is established through the dialer, so that it is set up
+// as it would be with charon.
+func newTestSession(t *testing.T, opts ...SessionOption) (*Session, *testServer) {
+	t.Helper()
+
+	client, server := net.Pipe()
+
+	ts := newTestServer(server)
+	go ts.serve()
+
+	dialer := func(_ context.Context, _, _ string) (net.Conn, error) {
+		return client, nil
+	}
+
+	s, err := NewSession(append(opts, WithDialContext(dialer))...)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	return s, ts
+}
+
+func TestNewSessionContext(t *testing.T) {
+	dialer := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := NewSessionContext(ctx, WithDialContext(dialer)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected %v, but got %v", context.Canceled, err)
+	}
+}
+
+func TestSubscribeContext(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s, ts := newTestSession(t)
+		defer s.Close()
+		defer ts.conn.Close()
+
+		if err := s.SubscribeContext(context.Background(), "event-confirm"); err != nil {
+			t.Fatalf("Unexpected error subscribing: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := s.UnsubscribeAllContext(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected %v, but got %v", context.Canceled, err)
+		}
+
+		if err := s.UnsubscribeAllContext(context.Background()); err != nil {
+			t.Fatalf("Unexpected error unsubscribing: %v", err)
+		}
+
+		if len(s.cc.events.list) != 0 {
+			t.Fatalf("Expected no events to be registered, got %v", s.cc.events.list)
+		}
+
+		// Leave the session unusable last: the server never responds to this
+		// registration, so later responses would not line up with requests.
+		ctx, cancel = context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+
+		err := s.SubscribeContext(ctx, "event-confirm", "event-no-response")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected %v, but got %v", context.DeadlineExceeded, err)
+		}
+
+		// The event registered before the deadline remains registered.
+		if !slices.Equal(s.cc.events.list, []string{"event-confirm"}) {
+			t.Fatalf("Expected only event-confirm to be registered, got %v", s.cc.events.list)
+		}
+	})
+}
+
+func TestSessionSubscriptions(t *testing.T) {
+	s, ts := newTestSession(t)
+	defer s.Close()
+	defer ts.conn.Close()
+
+	if events := s.Subscriptions(); len(events) != 0 {
+		t.Fatalf("Expected no subscriptions, got %v", events)
+	}
+
+	if err := s.Subscribe("event-confirm"); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+
+	if events := s.Subscriptions(); !slices.Equal(events, []string{"event-confirm"}) {
+		t.Fatalf("Expected event-confirm subscription, got %v", events)
+	}
+
+	if err := s.Unsubscribe("event-confirm"); err != nil {
+		t.Fatalf("Unexpected error unsubscribing: %v", err)
+	}
+
+	if events := s.Subscriptions(); len(events) != 0 {
+		t.Fatalf("Expected no subscriptions after unsubscribing, got %v", events)
+	}
+}
+
+func TestSubscribeContextReceiveEvents(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s, ts := newTestSession(t)
+		defer s.Close()
+		defer ts.conn.Close()
+
+		ec := make(chan Event, 3)
+		s.NotifyEvents(ec)
+
+		if err := s.Subscribe("event-simple"); err != nil {
+			t.Fatalf("Unexpected error subscribing: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
+		defer cancel()
+
+		// Events must still be delivered while an event registration
+		// is waiting for its response.
+		errs := make(chan error, 1)
+		go func() {
+			errs <- s.SubscribeContext(ctx, "event-no-response")
+		}()
+
+		start := time.Now()
+		for range 3 {
+			<-ec
+		}
+
+		if d := time.Since(start); d >= 4*time.Second {
+			t.Fatalf("Events were not delivered until the registration ended (after %v)", d)
+		}
+
+		if err := <-errs; !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected %v, but got %v", context.DeadlineExceeded, err)
+		}
+	})
+}
+
+func TestSessionDone(t *testing.T) {
+	s, ts := newTestSession(t)
+	defer ts.conn.Close()
+
+	if err := s.Err(); err != nil {
+		t.Fatalf("Expected nil error before session is done, got %v", err)
+	}
+
+	select {
+	case <-s.Done():
+		t.Fatal("Done closed before session ended")
+	default:
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Unexpected error closing session: %v", err)
+	}
+
+	<-s.Done()
+
+	if err := s.Err(); !errors.Is(err, ErrSessionClosed) {
+		t.Fatalf("Expected %v, got %v", ErrSessionClosed, err)
+	}
+
+	if _, err := s.Call(context.Background(), "cmd-ok", nil); !errors.Is(err, ErrSessionClosed) {
+		t.Fatalf("Expected %v from call on closed session, got %v", ErrSessionClosed, err)
+	}
+}
+
+func TestSessionDoneEOF(t *testing.T) {
+	s, ts := newTestSession(t)
+	defer s.Close()
+
+	ec := make(chan Event, 1)
+	s.NotifyEvents(ec)
+
+	if err := ts.conn.Close(); err != nil {
+		t.Fatalf("Unexpected error closing server conn: %v", err)
+	}
+
+	<-s.Done()
+
+	if _, ok := <-ec; ok {
+		t.Fatal("Expected event channel to be closed")
+	}
+
+	if err := s.Err(); !errors.Is(err, io.EOF) {
+		t.Fatalf("Expected error wrapping %v, got %v", io.EOF, err)
+	}
+
+	// Closing the session does not change the reason.
+	if err := s.Close(); err != nil {
+		t.Fatalf("Unexpected error closing session: %v", err)
+	}
+
+	if err := s.Err(); !errors.Is(err, io.EOF) {
+		t.Fatalf("Expected error wrapping %v after Close, got %v", io.EOF, err)
+	}
+}
+
+func TestSessionDoneMalformedPacket(t *testing.T) {
+	s, ts := newTestSession(t)
+	defer s.Close()
+	defer ts.conn.Close()
+
+	// A command response containing an invalid element type.
+	if _, err := ts.conn.Write([]byte{0, 0, 0, 2, pktCmdResponse, 0xff}); err != nil {
+		t.Fatalf("Unexpected error writing packet: %v", err)
+	}
+
+	<-s.Done()
+
+	err := s.Err()
+	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, ErrSessionClosed) {
+		t.Fatalf("Expected decoding error, got %v", err)
+	}
+}
+
+func TestSessionConcurrentCallStreaming(t *testing.T) {
+	s, ts := newTestSession(t)
+	defer s.Close()
+	defer ts.conn.Close()
+
+	var wg sync.WaitGroup
+
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			in := NewMessage()
+			if err := in.Set("a", "call"); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := in.Set("b", strconv.Itoa(i)); err != nil {
+				t.Error(err)
+				return
+			}
+
+			out, err := s.Call(context.Background(), "cmd-strcat", in)
+			if err != nil {
+				t.Errorf("Unexpected error from call %d: %v", i, err)
+				return
+			}
+
+			if c := out.Get("c"); c != "call"+strconv.Itoa(i) {
+				t.Errorf("Received response for the wrong call: expected call%d, got %v", i, c)
+			}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			n := 0
+			for m, err := range s.CallStreaming(context.Background(), "cmd-stream", "event-stream", nil) {
+				if err != nil {
+					t.Errorf("Unexpected error from stream %d: %v", i, err)
+					return
+				}
+
+				if v := m.Get("index"); v != strconv.Itoa(n) {
+					t.Errorf("Unexpected event in stream %d: %s", i, m)
+					return
+				}
+				n++
+			}
+
+			if n != 3 {
+				t.Errorf("Expected 3 events in stream %d, got %d", i, n)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestSessionCallStreamingBreak(t *testing.T) {
+	s, ts := newTestSession(t)
+	defer s.Close()
+	defer ts.conn.Close()
+
+	for range s.CallStreaming(context.Background(), "cmd-stream", "event-stream", nil) {
+		break
+	}
+
+	// The session must be released once the iteration has ended.
+	if _, err := s.Call(context.Background(), "cmd-ok", nil); err != nil {
+		t.Fatalf("Unexpected error from call after stream: %v", err)
+	}
+}
+
+func TestSessionCallStreamingNested(t *testing.T) {
+	s, ts := newTestSession(t)
+	defer s.Close()
+	defer ts.conn.Close()
+
+	n := 0
+	for _, err := range s.CallStreaming(context.Background(), "cmd-stream", "event-stream", nil) {
+		if err != nil {
+			t.Fatalf("Unexpected error from stream: %v", err)
+		}
+		n++
+
+		// The session is held by the stream, so a request made here
+		// cannot be served, and must give up once its context is done.
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_, err := s.Call(ctx, "cmd-ok", nil)
+		cancel()
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected %v from nested call, got %v", context.DeadlineExceeded, err)
+		}
+	}
+
+	if n != 3 {
+		t.Fatalf("Expected 3 events, got %d", n)
+	}
+
+	// The session must be released once the iteration has ended.
+	if _, err := s.Call(context.Background(), "cmd-ok", nil); err != nil {
+		t.Fatalf("Unexpected error from call after stream: %v", err)
+	}
+}
+
+func TestSessionCallStreamingFunc(t *testing.T) {
+	var intercepted []string
+
+	s, ts := newTestSession(t, WithStreamInterceptor(func(ctx context.Context, cmd string, event string, in *Message, invoker StreamInvoker) iter.Seq2[*Message, error] {
+		return func(yield func(*Message, error) bool) {
+			for m, err := range invoker(ctx, cmd, event, in) {
+				intercepted = append(intercepted, event)
+
+				if !yield(m, err) {
+					return
+				}
+			}
+		}
+	}))
+	defer s.Close()
+	defer ts.conn.Close()
+
+	n := 0
+	out, err := s.CallStreamingFunc(context.Background(), "cmd-stream", "event-stream", nil, func(m *Message) error {
+		if v := m.Get("index"); v != strconv.Itoa(n) {
+			return fmt.Errorf("unexpected event: %s", m)
+		}
+		n++
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error from CallStreamingFunc: %v", err)
+	}
+
+	if n != 3 {
+		t.Fatalf("Expected 3 events, got %d", n)
+	}
+
+	if v := out.Get("done"); v != "yes" {
+		t.Fatalf("Expected command response, got %s", out)
+	}
+
+	if !slices.Equal(intercepted, []string{"event-stream", "event-stream", "event-stream"}) {
+		t.Fatalf("Expected streamed events to be intercepted, got %v", intercepted)
+	}
+}
+
+func TestSessionCallUnknownCommand(t *testing.T) {
+	s, ts := newTestSession(t)
+	defer s.Close()
+	defer ts.conn.Close()
+
+	if _, err := s.Call(context.Background(), "cmd-unknown", nil); !errors.Is(err, ErrUnknownCommand) {
+		t.Fatalf("Expected %v, but got %v", ErrUnknownCommand, err)
+	}
+}
+
+func TestSessionCallStreamingFuncError(t *testing.T) {
+	s, ts := newTestSession(t)
+	defer s.Close()
+	defer ts.conn.Close()
+
+	stop := errors.New("stop")
+
+	_, err := s.CallStreamingFunc(context.Background(), "cmd-stream", "event-stream", nil, func(_ *Message) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("Expected error returned by fn, got %v", err)
+	}
+
+	// The session must be released once the request has ended.
+	if _, err := s.Call(context.Background(), "cmd-ok", nil); err != nil {
+		t.Fatalf("Unexpected error from call after stream: %v", err)
+	}
+}
+
+func TestSessionStats(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		s, ts := newTestSession(t)
+		defer s.Close()
+		defer ts.conn.Close()
+
+		// Only room for one of the three events sent by the server.
+		ec := make(chan Event, 1)
+		s.NotifyEvents(ec)
+
+		if err := s.Subscribe("event-simple"); err != nil {
+			t.Fatalf("Unexpected error subscribing: %v", err)
+		}
+
+		time.Sleep(5 * time.Second)
+		synctest.Wait()
+
+		if _, err := s.Call(context.Background(), "cmd-ok", nil); err != nil {
+			t.Fatalf("Unexpected error from call: %v", err)
+		}
+
+		st := s.Stats()
+
+		want := Stats{
+			PacketsRead:      5,
+			PacketsWritten:   2,
+			EventsDispatched: 3,
+			EventsDropped:    2,
+			SubscriberDrops:  map[chan<- Event]uint64{ec: 2},
+			ResponsesDropped: 0,
+		}
+
+		if !reflect.DeepEqual(st, want) {
+			t.Fatalf("Unexpected stats.\nExpected: %+v\nReceived: %+v", want, st)
+		}
+	})
+}
+
 // These tests are considered 'integration' tests because they require charon
 // to be running, and make actual client-issued commands. Note that these are
 // only meant to test the package API, and the specific commands used are out
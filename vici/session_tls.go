@@ -0,0 +1,67 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+// WithTLSConfig makes the Session use TLS on its connection to the daemon,
+// with the given configuration. This is intended for daemons whose vici
+// socket is reachable over TCP (see WithAddr), e.g. through a TLS terminator
+// such as the one provided by the tlsterm package. To authenticate to the
+// terminator, set the client certificate in config.Certificates.
+//
+// If config.ServerName is empty, the host of the address given to WithAddr
+// is used to verify the server's certificate.
+func WithTLSConfig(config *tls.Config) SessionOption {
+	return newFuncSessionOption(func(so *Session) {
+		so.tlsConfig = config
+	})
+}
+
+// tlsClient performs a TLS handshake over conn, and returns the resulting
+// TLS connection.
+func (s *Session) tlsClient(ctx context.Context, conn net.Conn) (net.Conn, error) {
+	config := s.tlsConfig
+
+	if config.ServerName == "" && !config.InsecureSkipVerify {
+		host, _, err := net.SplitHostPort(s.addr)
+		if err != nil {
+			host = s.addr
+		}
+
+		config = config.Clone()
+		config.ServerName = host
+	}
+
+	tc := tls.Client(conn, config)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		// nolint
+		tc.Close()
+
+		return nil, err
+	}
+
+	return tc, nil
+}
@@ -0,0 +1,155 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vici
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// newTestTLSConfigs returns a server configuration with a self-signed
+// certificate for host, and a client configuration trusting it.
+func newTestTLSConfigs(t *testing.T, host string) (server, client *tls.Config) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: host},
+		DNSNames:              []string{host},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	server = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+	client = &tls.Config{
+		RootCAs: pool,
+	}
+
+	return server, client
+}
+
+// newTestTLSDialer returns a dialer to a testServer behind TLS. It uses a TCP
+// connection rather than net.Pipe, as a failing handshake needs both sides to
+// be able to write at the same time.
+func newTestTLSDialer(t *testing.T, config *tls.Config) func(context.Context, string, string) (net.Conn, error) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				tc := tls.Server(conn, config)
+				if err := tc.Handshake(); err != nil {
+					tc.Close()
+
+					return
+				}
+
+				newTestServer(tc).serve()
+			}()
+		}
+	}()
+
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "tcp", l.Addr().String())
+	}
+}
+
+func TestSessionTLS(t *testing.T) {
+	serverConfig, clientConfig := newTestTLSConfigs(t, "gw.example.com")
+
+	// The server name is taken from the address.
+	s, err := NewSession(
+		WithAddr("tcp", "gw.example.com:4502"),
+		WithDialContext(newTestTLSDialer(t, serverConfig)),
+		WithTLSConfig(clientConfig),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Call(context.Background(), "cmd-ok", nil); err != nil {
+		t.Fatalf("Unexpected error calling cmd-ok: %v", err)
+	}
+}
+
+func TestSessionTLSWrongServerName(t *testing.T) {
+	serverConfig, clientConfig := newTestTLSConfigs(t, "gw.example.com")
+
+	_, err := NewSession(
+		WithAddr("tcp", "other.example.com:4502"),
+		WithDialContext(newTestTLSDialer(t, serverConfig)),
+		WithTLSConfig(clientConfig),
+	)
+	if err == nil {
+		t.Fatalf("Expected error connecting to server with certificate for another name")
+	}
+
+	var verr *tls.CertificateVerificationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected certificate verification error, got %v", err)
+	}
+}
@@ -0,0 +1,588 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package sync converges the configuration loaded into a daemon to a desired
+// state, like swanctl --load-all, but with separate planning and applying.
+//
+// The desired connections, shared secrets, private keys and pools are given
+// as a State, holding the payloads of the load-conn, load-shared, load-key
+// and load-pool commands. Syncer.Plan compares them with what get-conns,
+// get-shared, get-keys and get-pools report, and returns the Plan of loads
+// and unloads required to converge. Plans can be printed for a dry run, and
+// are carried out with Syncer.Apply:
+//
+//	desired := &sync.State{
+//		Conns: map[string]*vici.Message{"gw": gw},
+//		Pools: map[string]*vici.Message{"rw": rw},
+//	}
+//
+//	syncer := sync.NewSyncer(s)
+//
+//	plan, err := syncer.Plan(ctx, desired)
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Print(plan)
+//
+//	if err := syncer.Apply(ctx, plan); err != nil {
+//		return err
+//	}
+//
+// The daemon only reports the names of loaded connections, shared secrets and
+// pools, not their contents. A Syncer therefore remembers the payloads it
+// loaded, and only reloads an object when its payload changed, or when it
+// was loaded by someone else. Private keys are identified by the ID derived
+// from the key itself, see KeyID, so a loaded key never needs reloading.
+package sync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"sort"
+	gosync "sync"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// Kind is the kind of a configuration object.
+type Kind int
+
+const (
+	// KindKey is a private key, loaded with load-key.
+	KindKey Kind = iota + 1
+
+	// KindShared is a shared secret, loaded with load-shared.
+	KindShared
+
+	// KindPool is a virtual IP address pool, loaded with load-pool.
+	KindPool
+
+	// KindConn is a connection, loaded with load-conn.
+	KindConn
+)
+
+// String returns the name of k, e.g. "connection".
+func (k Kind) String() string {
+	switch k {
+	case KindKey:
+		return "key"
+	case KindShared:
+		return "shared secret"
+	case KindPool:
+		return "pool"
+	case KindConn:
+		return "connection"
+	default:
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+}
+
+// kinds are all kinds, in the order they are loaded, i.e. dependencies
+// first. They are unloaded in the reverse order.
+var kinds = []Kind{KindKey, KindShared, KindPool, KindConn}
+
+// State is a desired configuration state. Each map holds the payloads of the
+// objects of one kind, by name.
+type State struct {
+	// Conns holds the connections, by name. The values are the contents of
+	// the connection sections of load-conn, e.g. with local_addrs and
+	// children.
+	Conns map[string]*vici.Message
+
+	// Shared holds the shared secrets, by their unique identifier. The values
+	// are the load-shared arguments, e.g. with type, data and owners. The id
+	// argument is set from the map key.
+	Shared map[string]*vici.Message
+
+	// Keys holds the private keys, by key ID, see KeyID and State.AddKey. The
+	// values are the load-key arguments, i.e. type and data.
+	Keys map[string]*vici.Message
+
+	// Pools holds the pools, by name. The values are the contents of the pool
+	// sections of load-pool, e.g. with addrs and dns.
+	Pools map[string]*vici.Message
+}
+
+// AddKey adds the private key given in PEM or DER encoding to s. The key
+// type is as expected by load-key, e.g. "rsa", "ecdsa" or "any".
+func (s *State) AddKey(typ string, data []byte) error {
+	id, err := KeyID(data)
+	if err != nil {
+		return err
+	}
+
+	m, err := vici.Build().Set("type", typ).Set("data", string(data)).Message()
+	if err != nil {
+		return err
+	}
+
+	if s.Keys == nil {
+		s.Keys = make(map[string]*vici.Message)
+	}
+	s.Keys[id] = m
+
+	return nil
+}
+
+func (s *State) objects(k Kind) map[string]*vici.Message {
+	switch k {
+	case KindKey:
+		return s.Keys
+	case KindShared:
+		return s.Shared
+	case KindPool:
+		return s.Pools
+	case KindConn:
+		return s.Conns
+	default:
+		return nil
+	}
+}
+
+// Action is the action of a Change.
+type Action int
+
+const (
+	// ActionLoad loads, or reloads, an object.
+	ActionLoad Action = iota + 1
+
+	// ActionUnload unloads an object.
+	ActionUnload
+)
+
+// String returns the name of a, i.e. "load" or "unload".
+func (a Action) String() string {
+	switch a {
+	case ActionLoad:
+		return "load"
+	case ActionUnload:
+		return "unload"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+// Reason is the reason of a Change.
+type Reason string
+
+const (
+	// ReasonMissing means the object is desired, but not loaded.
+	ReasonMissing Reason = "not loaded"
+
+	// ReasonChanged means the desired payload differs from the one last
+	// loaded by the Syncer.
+	ReasonChanged Reason = "changed"
+
+	// ReasonUnknown means the object is loaded, but not by the Syncer, so
+	// its contents are unknown.
+	ReasonUnknown Reason = "not loaded by this syncer"
+
+	// ReasonUndesired means the object is loaded, but not desired.
+	ReasonUndesired Reason = "not desired"
+)
+
+// Change is a single load or unload in a Plan.
+type Change struct {
+	Kind   Kind
+	Action Action
+	Name   string
+	Reason Reason
+
+	// Payload is the payload of a load, as given in the State.
+	Payload *vici.Message
+
+	fingerprint string
+}
+
+// String describes c, e.g. "load connection 'gw' (not loaded)".
+func (c *Change) String() string {
+	return fmt.Sprintf("%s %s '%s' (%s)", c.Action, c.Kind, c.Name, c.Reason)
+}
+
+// Plan holds the changes required to converge the daemon to a desired State.
+type Plan struct {
+	// Changes holds the changes, in the order they are applied: loads
+	// before unloads, keys and secrets before pools, and pools before
+	// connections, and the reverse for unloads.
+	Changes []*Change
+
+	// Unchanged is the number of desired objects which are already loaded
+	// as desired.
+	Unchanged int
+}
+
+// Empty reports whether the plan has no changes.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String formats the plan for a dry run, with a line per change, prefixed with
+// "+" for loads of missing objects, "~" for reloads, and "-" for unloads, and
+// a summary line.
+func (p *Plan) String() string {
+	var (
+		buf            bytes.Buffer
+		loads, unloads int
+	)
+
+	for _, c := range p.Changes {
+		sign := "~"
+
+		switch {
+		case c.Action == ActionUnload:
+			sign = "-"
+			unloads++
+		case c.Reason == ReasonMissing:
+			sign = "+"
+			loads++
+		default:
+			loads++
+		}
+
+		fmt.Fprintf(&buf, "%s %s\n", sign, c)
+	}
+
+	fmt.Fprintf(&buf, "%d to load, %d to unload, %d unchanged\n", loads, unloads, p.Unchanged)
+
+	return buf.String()
+}
+
+// Option is used to specify options to NewSyncer.
+type Option interface {
+	apply(*Syncer)
+}
+
+type funcOption struct {
+	f func(*Syncer)
+}
+
+func (fo *funcOption) apply(s *Syncer) {
+	fo.f(s)
+}
+
+// WithManaged specifies the func which decides whether the Syncer manages a
+// loaded object, i.e. unloads it if it is not desired. It can be used to share
+// a daemon with other configuration sources, e.g. by the names' prefix. If this
+// option is not specified, all objects are managed.
+func WithManaged(managed func(kind Kind, name string) bool) Option {
+	return &funcOption{func(s *Syncer) {
+		s.managed = managed
+	}}
+}
+
+// WithKinds specifies the kinds of objects to synchronize. Objects of other
+// kinds are neither loaded nor unloaded. If this option is not specified, all
+// kinds are synchronized.
+func WithKinds(ks ...Kind) Option {
+	return &funcOption{func(s *Syncer) {
+		s.kinds = slices.Clone(ks)
+	}}
+}
+
+// Syncer converges the configuration loaded into a daemon to a desired State.
+// A Syncer remembers the payloads it loaded, to avoid reloading unchanged
+// objects, so the same Syncer should be used for each synchronization with a
+// daemon. It is safe for concurrent use, but concurrent Apply calls may undo
+// each other's changes.
+type Syncer struct {
+	s       *vici.Session
+	managed func(Kind, string) bool
+	kinds   []Kind
+
+	mu gosync.Mutex
+	// The fingerprints of the payloads loaded by the Syncer.
+	loaded map[object]string
+}
+
+// object identifies a configuration object.
+type object struct {
+	kind Kind
+	name string
+}
+
+// NewSyncer returns a Syncer for the daemon connected to s.
+func NewSyncer(s *vici.Session, opts ...Option) *Syncer {
+	sy := &Syncer{
+		s:       s,
+		managed: func(Kind, string) bool { return true },
+		kinds:   kinds,
+		loaded:  make(map[object]string),
+	}
+
+	for _, opt := range opts {
+		opt.apply(sy)
+	}
+
+	return sy
+}
+
+// Plan returns the changes required to converge the daemon to desired.
+func (sy *Syncer) Plan(ctx context.Context, desired *State) (*Plan, error) {
+	var (
+		plan    Plan
+		unloads []*Change
+	)
+
+	for _, k := range kinds {
+		if !slices.Contains(sy.kinds, k) {
+			continue
+		}
+
+		current, err := sy.current(ctx, k)
+		if err != nil {
+			return nil, err
+		}
+
+		want := desired.objects(k)
+
+		for _, name := range sortedKeys(want) {
+			fp, err := fingerprint(want[name])
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s '%s': %w", k, name, err)
+			}
+
+			c := &Change{
+				Kind:        k,
+				Action:      ActionLoad,
+				Name:        name,
+				Payload:     want[name],
+				fingerprint: fp,
+			}
+
+			sy.mu.Lock()
+			last, ok := sy.loaded[object{k, name}]
+			sy.mu.Unlock()
+
+			switch {
+			case !slices.Contains(current, name):
+				c.Reason = ReasonMissing
+			case k == KindKey:
+				// Key IDs are derived from the keys, so the loaded key is
+				// the desired one.
+				plan.Unchanged++
+
+				continue
+			case !ok:
+				c.Reason = ReasonUnknown
+			case last != fp:
+				c.Reason = ReasonChanged
+			default:
+				plan.Unchanged++
+
+				continue
+			}
+
+			plan.Changes = append(plan.Changes, c)
+		}
+
+		var undesired []*Change
+
+		for _, name := range current {
+			if _, ok := want[name]; ok || !sy.managed(k, name) {
+				continue
+			}
+
+			undesired = append(undesired, &Change{
+				Kind:   k,
+				Action: ActionUnload,
+				Name:   name,
+				Reason: ReasonUndesired,
+			})
+		}
+
+		// Unload dependents first, i.e. in the reverse order of loading.
+		unloads = append(undesired, unloads...)
+	}
+
+	plan.Changes = append(plan.Changes, unloads...)
+
+	return &plan, nil
+}
+
+// Apply carries out the changes of p, in order. It stops at the first failed
+// change, and returns an error describing it. The changes made before remain
+// in place.
+func (sy *Syncer) Apply(ctx context.Context, p *Plan) error {
+	for _, c := range p.Changes {
+		cmd, in, err := request(c)
+		if err != nil {
+			return fmt.Errorf("%s failed: %w", c, err)
+		}
+
+		out, err := sy.s.Call(ctx, cmd, in)
+		if err != nil {
+			if msg, ok := errmsg(out); ok {
+				return fmt.Errorf("%s failed: %s", c, msg)
+			}
+
+			return fmt.Errorf("%s failed: %w", c, err)
+		}
+
+		sy.mu.Lock()
+		if c.Action == ActionLoad {
+			sy.loaded[object{c.Kind, c.Name}] = c.fingerprint
+		} else {
+			delete(sy.loaded, object{c.Kind, c.Name})
+		}
+		sy.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Sync plans and applies the changes required to converge the daemon to
+// desired, and returns the applied plan.
+func (sy *Syncer) Sync(ctx context.Context, desired *State) (*Plan, error) {
+	p, err := sy.Plan(ctx, desired)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, sy.Apply(ctx, p)
+}
+
+// current returns the names of the loaded objects of kind k.
+func (sy *Syncer) current(ctx context.Context, k Kind) ([]string, error) {
+	var cmd, key string
+
+	switch k {
+	case KindKey:
+		cmd, key = "get-keys", "keys"
+	case KindShared:
+		cmd, key = "get-shared", "keys"
+	case KindPool:
+		cmd = "get-pools"
+	case KindConn:
+		cmd, key = "get-conns", "conns"
+	}
+
+	out, err := sy.s.Call(ctx, cmd, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", cmd, err)
+	}
+
+	// get-pools reports a section per pool, the others a list of names.
+	if key == "" {
+		var names []string
+
+		for _, name := range out.Keys() {
+			if _, ok := out.Get(name).(*vici.Message); ok {
+				names = append(names, name)
+			}
+		}
+
+		return names, nil
+	}
+
+	switch v := out.Get(key).(type) {
+	case []string:
+		return v, nil
+	case string:
+		return []string{v}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// request returns the command request which carries out c.
+func request(c *Change) (string, *vici.Message, error) {
+	if c.Action == ActionUnload {
+		switch c.Kind {
+		case KindKey:
+			m, err := vici.Build().Set("id", c.Name).Message()
+			return "unload-key", m, err
+		case KindShared:
+			m, err := vici.Build().Set("id", c.Name).Message()
+			return "unload-shared", m, err
+		case KindPool:
+			m, err := vici.Build().Set("name", c.Name).Message()
+			return "unload-pool", m, err
+		default:
+			m, err := vici.Build().Set("name", c.Name).Message()
+			return "unload-conn", m, err
+		}
+	}
+
+	switch c.Kind {
+	case KindKey:
+		return "load-key", c.Payload, nil
+	case KindShared:
+		m := vici.NewMessage()
+		if err := m.Set("id", c.Name); err != nil {
+			return "", nil, err
+		}
+
+		for _, key := range c.Payload.Keys() {
+			if key == "id" {
+				continue
+			}
+
+			if err := m.Set(key, c.Payload.Get(key)); err != nil {
+				return "", nil, err
+			}
+		}
+
+		return "load-shared", m, nil
+	case KindPool:
+		m, err := vici.Build().Set(c.Name, c.Payload).Message()
+		return "load-pool", m, err
+	default:
+		m, err := vici.Build().Set(c.Name, c.Payload).Message()
+		return "load-conn", m, err
+	}
+}
+
+// errmsg returns the errmsg of a failed command's response.
+func errmsg(out *vici.Message) (string, bool) {
+	if out == nil {
+		return "", false
+	}
+
+	msg, ok := out.Get("errmsg").(string)
+
+	return msg, ok && msg != ""
+}
+
+// fingerprint returns a digest of the encoding of m.
+func fingerprint(m *vici.Message) (string, error) {
+	if m == nil {
+		m = vici.NewMessage()
+	}
+
+	data, err := m.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+func sortedKeys(m map[string]*vici.Message) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,408 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package sync_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	gosync "sync"
+	"testing"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/sync"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+// daemon is a fake daemon keeping track of the loaded objects.
+type daemon struct {
+	mu       gosync.Mutex
+	loaded   map[string][]string
+	requests []string
+}
+
+func newDaemon(t *testing.T) (*daemon, *vici.Session) {
+	t.Helper()
+
+	d := &daemon{loaded: make(map[string][]string)}
+
+	srv := vicitest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	get := func(cmd, key, kind string) {
+		srv.Handle(cmd, func(_ *vicitest.Request) (*vici.Message, error) {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+
+			if key == "" {
+				b := vici.Build()
+				for _, name := range d.loaded[kind] {
+					b.Section(name).Set("base", "10.3.0.1").End()
+				}
+
+				return b.Message()
+			}
+
+			return vici.Build().List(key, d.loaded[kind]...).Message()
+		})
+	}
+	get("get-keys", "keys", "key")
+	get("get-shared", "keys", "shared")
+	get("get-pools", "", "pool")
+	get("get-conns", "conns", "conn")
+
+	handle := func(cmd, kind string, name func(*vici.Message) (string, error)) {
+		srv.Handle(cmd, func(req *vicitest.Request) (*vici.Message, error) {
+			n, err := name(req.Message)
+			if err != nil {
+				return nil, err
+			}
+
+			d.mu.Lock()
+			defer d.mu.Unlock()
+
+			d.requests = append(d.requests, fmt.Sprintf("%s %s", cmd, n))
+
+			if strings.HasPrefix(cmd, "unload-") {
+				i := slices.Index(d.loaded[kind], n)
+				if i < 0 {
+					return nil, fmt.Errorf("%s '%s' not found", kind, n)
+				}
+				d.loaded[kind] = slices.Delete(d.loaded[kind], i, i+1)
+			} else if !slices.Contains(d.loaded[kind], n) {
+				d.loaded[kind] = append(d.loaded[kind], n)
+			}
+
+			return vici.Build().Set("success", "yes").Set("id", n).Message()
+		})
+	}
+
+	section := func(m *vici.Message) (string, error) {
+		return m.Keys()[0], nil
+	}
+	field := func(key string) func(*vici.Message) (string, error) {
+		return func(m *vici.Message) (string, error) {
+			return fmt.Sprint(m.Get(key)), nil
+		}
+	}
+
+	handle("load-key", "key", func(m *vici.Message) (string, error) {
+		return sync.KeyID([]byte(m.Get("data").(string)))
+	})
+	handle("unload-key", "key", field("id"))
+	handle("load-shared", "shared", field("id"))
+	handle("unload-shared", "shared", field("id"))
+	handle("load-pool", "pool", section)
+	handle("unload-pool", "pool", field("name"))
+	handle("load-conn", "conn", func(m *vici.Message) (string, error) {
+		name := m.Keys()[0]
+		if v, _ := m.Get(name).(*vici.Message).Get("version").(string); v == "3" {
+			return "", errors.New("invalid value for: version, config discarded")
+		}
+
+		return name, nil
+	})
+	handle("unload-conn", "conn", field("name"))
+
+	return d, vicitest.NewSession(t, srv)
+}
+
+func (d *daemon) load(kind string, names ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.loaded[kind] = append(d.loaded[kind], names...)
+}
+
+func (d *daemon) takeRequests() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	reqs := d.requests
+	d.requests = nil
+
+	return reqs
+}
+
+func ecdsaKey(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func desiredState(t *testing.T, key []byte) *sync.State {
+	t.Helper()
+
+	state := &sync.State{
+		Conns: map[string]*vici.Message{
+			"gw": vicitest.MustBuild(t, vici.Build().List("remote_addrs", "198.51.100.1").Set("version", 2)),
+			"rw": vicitest.MustBuild(t, vici.Build().List("pools", "rw").Set("version", 2)),
+		},
+		Shared: map[string]*vici.Message{
+			"ike-gw": vicitest.MustBuild(t, vici.Build().Set("type", "IKE").Set("data", "secret").List("owners", "gw.example.org")),
+		},
+		Pools: map[string]*vici.Message{
+			"rw": vicitest.MustBuild(t, vici.Build().Set("addrs", "10.3.0.0/24")),
+		},
+	}
+
+	if err := state.AddKey("ecdsa", key); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return state
+}
+
+func TestSyncer(t *testing.T) {
+	d, s := newDaemon(t)
+	ctx := context.Background()
+
+	key := ecdsaKey(t)
+	id, err := sync.KeyID(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Objects loaded by someone else, e.g. swanctl --load-all.
+	d.load("conn", "gw", "old")
+	d.load("pool", "old")
+	d.load("key", "0123456789abcdef0123456789abcdef01234567")
+
+	syncer := sync.NewSyncer(s)
+	desired := desiredState(t, key)
+
+	plan, err := syncer.Plan(ctx, desired)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := "+ load key '" + id + "' (not loaded)\n" +
+		"+ load shared secret 'ike-gw' (not loaded)\n" +
+		"+ load pool 'rw' (not loaded)\n" +
+		"~ load connection 'gw' (not loaded by this syncer)\n" +
+		"+ load connection 'rw' (not loaded)\n" +
+		"- unload connection 'old' (not desired)\n" +
+		"- unload pool 'old' (not desired)\n" +
+		"- unload key '0123456789abcdef0123456789abcdef01234567' (not desired)\n" +
+		"5 to load, 3 to unload, 0 unchanged\n"
+	if got := plan.String(); got != want {
+		t.Fatalf("Unexpected plan:\n%s\nexpected:\n%s", got, want)
+	}
+
+	// Planning does not change anything.
+	if reqs := d.takeRequests(); len(reqs) != 0 {
+		t.Fatalf("Expected no requests while planning, got %v", reqs)
+	}
+
+	if err := syncer.Apply(ctx, plan); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantReqs := []string{
+		"load-key " + id,
+		"load-shared ike-gw",
+		"load-pool rw",
+		"load-conn gw",
+		"load-conn rw",
+		"unload-conn old",
+		"unload-pool old",
+		"unload-key 0123456789abcdef0123456789abcdef01234567",
+	}
+	if reqs := d.takeRequests(); !slices.Equal(reqs, wantReqs) {
+		t.Fatalf("Expected requests %v, got %v", wantReqs, reqs)
+	}
+
+	// A second synchronization finds nothing to do.
+	plan, err = syncer.Sync(ctx, desired)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !plan.Empty() || plan.Unchanged != 5 {
+		t.Fatalf("Expected empty plan with 5 unchanged objects, got:\n%s", plan)
+	}
+
+	// Only the changed connection is reloaded.
+	desired.Conns["rw"] = vicitest.MustBuild(t, vici.Build().List("pools", "rw").Set("version", 1))
+
+	plan, err = syncer.Sync(ctx, desired)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if want := "~ load connection 'rw' (changed)\n1 to load, 0 to unload, 4 unchanged\n"; plan.String() != want {
+		t.Fatalf("Unexpected plan:\n%s\nexpected:\n%s", plan, want)
+	}
+
+	if reqs := d.takeRequests(); !slices.Equal(reqs, []string{"load-conn rw"}) {
+		t.Fatalf("Expected only rw to be reloaded, got %v", reqs)
+	}
+}
+
+func TestSyncerOptions(t *testing.T) {
+	d, s := newDaemon(t)
+	ctx := context.Background()
+
+	d.load("conn", "managed-old", "other")
+	d.load("pool", "old")
+
+	syncer := sync.NewSyncer(s,
+		sync.WithKinds(sync.KindConn),
+		sync.WithManaged(func(_ sync.Kind, name string) bool {
+			return strings.HasPrefix(name, "managed-")
+		}),
+	)
+
+	plan, err := syncer.Sync(ctx, &sync.State{
+		Conns: map[string]*vici.Message{"managed-gw": vici.NewMessage()},
+		Pools: map[string]*vici.Message{"rw": vici.NewMessage()},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := "+ load connection 'managed-gw' (not loaded)\n" +
+		"- unload connection 'managed-old' (not desired)\n" +
+		"1 to load, 1 to unload, 0 unchanged\n"
+	if got := plan.String(); got != want {
+		t.Fatalf("Unexpected plan:\n%s\nexpected:\n%s", got, want)
+	}
+
+	if reqs := d.takeRequests(); !slices.Equal(reqs, []string{"load-conn managed-gw", "unload-conn managed-old"}) {
+		t.Fatalf("Unexpected requests: %v", reqs)
+	}
+}
+
+func TestSyncerApplyFailure(t *testing.T) {
+	d, s := newDaemon(t)
+	ctx := context.Background()
+
+	syncer := sync.NewSyncer(s)
+
+	_, err := syncer.Sync(ctx, &sync.State{
+		Conns: map[string]*vici.Message{
+			"a": vicitest.MustBuild(t, vici.Build().Set("version", 2)),
+			"b": vicitest.MustBuild(t, vici.Build().Set("version", 3)),
+			"c": vicitest.MustBuild(t, vici.Build().Set("version", 2)),
+		},
+	})
+
+	want := "load connection 'b' (not loaded) failed: invalid value for: version, config discarded"
+	if err == nil || err.Error() != want {
+		t.Fatalf("Expected error %q, got %v", want, err)
+	}
+
+	// The changes before the failed one remain applied.
+	if reqs := d.takeRequests(); !slices.Equal(reqs, []string{"load-conn a"}) {
+		t.Fatalf("Unexpected requests: %v", reqs)
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	// Use the SHA-1 subject key identifiers of RFC 5280, which Go generated
+	// before Go 1.25, to compare against.
+	t.Setenv("GODEBUG", "x509sha256skid=0")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		key  crypto.Signer
+		data []byte
+	}{
+		{name: "rsa pkcs1", key: rsaKey, data: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		{name: "ecdsa sec1 pem", key: ecKey, data: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER})},
+		{name: "ed25519 pkcs8", key: edKey, data: edDER},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// The subject key identifier Go generates for CA certificates
+			// is the SHA-1 digest of the public key.
+			tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), IsCA: true, BasicConstraintsValid: true}
+			der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, tt.key.Public(), tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			id, err := sync.KeyID(tt.data)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if want := hex.EncodeToString(cert.SubjectKeyId); id != want {
+				t.Fatalf("Expected key ID %s, got %s", want, id)
+			}
+		})
+	}
+
+	if _, err := sync.KeyID([]byte("not a key")); !errors.Is(err, sync.ErrUnsupportedKey) {
+		t.Fatalf("Expected ErrUnsupportedKey, got %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package tlsterm provides a TLS terminator for the vici protocol, which makes
+// a charon daemon's vici socket available to remote clients over mutually
+// authenticated TLS.
+//
+// The Terminator accepts TLS connections, verifies the client's certificate,
+// and forwards each connection to its own connection to the daemon's vici
+// socket. Clients connect using vici.WithAddr and vici.WithTLSConfig:
+//
+//	t := tlsterm.NewTerminator(serverConfig)
+//	defer t.Close()
+//
+//	l, err := net.Listen("tcp", ":4502")
+//	if err != nil {
+//		return err
+//	}
+//
+//	go t.Serve(l)
+package tlsterm
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// Option is used to specify options to NewTerminator.
+type Option interface {
+	apply(*Terminator)
+}
+
+type funcOption struct {
+	f func(*Terminator)
+}
+
+func (fo *funcOption) apply(t *Terminator) {
+	fo.f(t)
+}
+
+// WithUpstream specifies the network and address of the daemon's vici socket.
+// If this option is not specified, the default path, /var/run/charon.vici, is
+// used.
+func WithUpstream(network, addr string) Option {
+	return &funcOption{func(t *Terminator) {
+		t.network = network
+		t.addr = addr
+	}}
+}
+
+// WithDialContext specifies the dial func to use when dialing the daemon's vici
+// socket.
+func WithDialContext(dialer func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return &funcOption{func(t *Terminator) {
+		t.dialer = dialer
+	}}
+}
+
+// WithHandshakeTimeout specifies how long a client may take to complete the TLS
+// handshake before its connection is closed. If this option is not specified, the
+// timeout is 10 seconds.
+func WithHandshakeTimeout(timeout time.Duration) Option {
+	return &funcOption{func(t *Terminator) {
+		t.handshakeTimeout = timeout
+	}}
+}
+
+// WithLogger specifies the logger used to record accepted and rejected clients.
+// If this option is not specified, nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return &funcOption{func(t *Terminator) {
+		t.logger = logger
+	}}
+}
+
+// Terminator is a TLS terminator for the vici protocol.
+type Terminator struct {
+	config *tls.Config
+
+	network string
+	addr    string
+	dialer  func(ctx context.Context, network, addr string) (net.Conn, error)
+	logger  *slog.Logger
+
+	handshakeTimeout time.Duration
+
+	// Cancelled when the Terminator is closed, to interrupt the handshakes
+	// and upstream dials in progress.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	conns     map[net.Conn]struct{}
+	listeners map[net.Listener]struct{}
+	closed    bool
+
+	wg sync.WaitGroup
+}
+
+// NewTerminator returns a new Terminator using the given TLS configuration.
+// Clients are always required to present a certificate that verifies against
+// config.ClientCAs, regardless of config.ClientAuth. If config.MinVersion is
+// not set, clients are required to use TLS 1.2 or later.
+func NewTerminator(config *tls.Config, opts ...Option) *Terminator {
+	config = config.Clone()
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t := &Terminator{
+		config:           config,
+		network:          "unix",
+		addr:             "/var/run/charon.vici",
+		dialer:           (&net.Dialer{}).DialContext,
+		logger:           slog.New(slog.DiscardHandler),
+		handshakeTimeout: 10 * time.Second,
+		ctx:              ctx,
+		cancel:           cancel,
+		conns:            make(map[net.Conn]struct{}),
+		listeners:        make(map[net.Listener]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt.apply(t)
+	}
+
+	return t
+}
+
+// Serve accepts TLS connections on l, and forwards each of them to the daemon,
+// until l is closed or the Terminator is closed. The listener must not use TLS
+// itself. When the Terminator is closed, Serve returns nil.
+func (t *Terminator) Serve(l net.Listener) error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+
+		return net.ErrClosed
+	}
+	t.listeners[l] = struct{}{}
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.listeners, l)
+		t.mu.Unlock()
+	}()
+
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			t.mu.Lock()
+			closed := t.closed
+			t.mu.Unlock()
+
+			if closed {
+				return nil
+			}
+
+			return err
+		}
+
+		if !t.track(nc) {
+			nc.Close()
+
+			return nil
+		}
+
+		go func() {
+			defer t.untrack(nc)
+
+			t.handle(tls.Server(nc, t.config))
+		}()
+	}
+}
+
+// Close closes all listeners given to Serve and all connections, and waits
+// until the connections are done.
+func (t *Terminator) Close() error {
+	t.cancel()
+
+	t.mu.Lock()
+	t.closed = true
+
+	for l := range t.listeners {
+		l.Close()
+	}
+
+	for nc := range t.conns {
+		nc.Close()
+	}
+	t.mu.Unlock()
+
+	t.wg.Wait()
+
+	return nil
+}
+
+// track adds nc to the open connections, unless the Terminator is closed.
+func (t *Terminator) track(nc net.Conn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return false
+	}
+
+	t.conns[nc] = struct{}{}
+	t.wg.Add(1)
+
+	return true
+}
+
+func (t *Terminator) untrack(nc net.Conn) {
+	t.mu.Lock()
+	delete(t.conns, nc)
+	t.mu.Unlock()
+
+	t.wg.Done()
+}
+
+// handle completes the TLS handshake with a client, and forwards the connection
+// to the daemon until either side closes it.
+func (t *Terminator) handle(tc *tls.Conn) {
+	defer tc.Close()
+
+	ctx := t.ctx
+	remote := slog.String("remote", tc.RemoteAddr().String())
+
+	hctx, cancel := context.WithTimeout(ctx, t.handshakeTimeout)
+	err := tc.HandshakeContext(hctx)
+	cancel()
+
+	if err != nil {
+		t.logger.LogAttrs(ctx, slog.LevelWarn, "rejected client", remote, slog.Any("error", err))
+
+		return
+	}
+
+	// The handshake verified the chain, the leaf identifies the client.
+	subject := slog.String("subject", tc.ConnectionState().PeerCertificates[0].Subject.String())
+
+	upstream, err := t.dialer(ctx, t.network, t.addr)
+	if err != nil {
+		t.logger.LogAttrs(ctx, slog.LevelError, "failed to connect to daemon", remote, subject,
+			slog.String("network", t.network),
+			slog.String("addr", t.addr),
+			slog.Any("error", err),
+		)
+
+		return
+	}
+	defer upstream.Close()
+
+	t.logger.LogAttrs(ctx, slog.LevelInfo, "accepted client", remote, subject)
+
+	// Copy in both directions until one side is done, and then close both
+	// connections to end the other copy.
+	done := make(chan error, 2)
+
+	go func() {
+		_, err := io.Copy(upstream, tc)
+		done <- err
+	}()
+	go func() {
+		_, err := io.Copy(tc, upstream)
+		done <- err
+	}()
+
+	err = <-done
+
+	tc.Close()
+	upstream.Close()
+	<-done
+
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		t.logger.LogAttrs(ctx, slog.LevelWarn, "client connection ended", remote, subject, slog.Any("error", err))
+
+		return
+	}
+
+	t.logger.LogAttrs(ctx, slog.LevelInfo, "client connection ended", remote, subject)
+}
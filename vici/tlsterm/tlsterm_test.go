@@ -0,0 +1,280 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tlsterm_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/tlsterm"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+// testCA is a certificate authority for issuing test certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse CA certificate: %v", err)
+	}
+
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	return pool
+}
+
+// issue returns a certificate for name, which is used as a DNS name or IP
+// address for server certificates.
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	if usage == x509.ExtKeyUsageServerAuth {
+		if ip := net.ParseIP(name); ip != nil {
+			tmpl.IPAddresses = []net.IP{ip}
+		} else {
+			tmpl.DNSNames = []string{name}
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// newTestTerminator starts a Terminator in front of a fake daemon, and returns
+// the address it listens on.
+func newTestTerminator(t *testing.T, ca *testCA, opts ...tlsterm.Option) string {
+	t.Helper()
+
+	srv := vicitest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	srv.Handle("version", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().Set("daemon", "charon").Message()
+	})
+
+	term := tlsterm.NewTerminator(&tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "127.0.0.1", x509.ExtKeyUsageServerAuth)},
+		ClientCAs:    ca.pool(),
+	}, append([]tlsterm.Option{tlsterm.WithDialContext(srv.DialContext)}, opts...)...)
+	t.Cleanup(func() { term.Close() })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	go func() {
+		// nolint
+		term.Serve(l)
+	}()
+
+	return l.Addr().String()
+}
+
+func TestTerminator(t *testing.T) {
+	ca := newTestCA(t, "vici CA")
+	addr := newTestTerminator(t, ca)
+
+	s, err := vici.NewSession(
+		vici.WithAddr("tcp", addr),
+		vici.WithTLSConfig(&tls.Config{
+			RootCAs:      ca.pool(),
+			Certificates: []tls.Certificate{ca.issue(t, "client", x509.ExtKeyUsageClientAuth)},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+	defer s.Close()
+
+	m, err := s.Call(context.Background(), "version", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error calling version: %v", err)
+	}
+
+	if got := m.Get("daemon"); got != "charon" {
+		t.Fatalf("Expected daemon=charon, got %v", got)
+	}
+}
+
+func TestTerminatorRejectsClient(t *testing.T) {
+	ca := newTestCA(t, "vici CA")
+	addr := newTestTerminator(t, ca)
+
+	untrusted := newTestCA(t, "other CA")
+
+	tests := map[string][]tls.Certificate{
+		"no certificate":        nil,
+		"untrusted certificate": {untrusted.issue(t, "client", x509.ExtKeyUsageClientAuth)},
+	}
+
+	for name, certs := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			s, err := vici.NewSessionContext(ctx,
+				vici.WithAddr("tcp", addr),
+				vici.WithTLSConfig(&tls.Config{
+					RootCAs:      ca.pool(),
+					Certificates: certs,
+				}),
+			)
+			if err != nil {
+				// The client may learn about the rejection during the
+				// handshake, or only once it reads from the connection
+				// with TLS 1.3.
+				return
+			}
+			defer s.Close()
+
+			if _, err := s.Call(ctx, "version", nil); err == nil {
+				t.Fatalf("Expected error calling version without a trusted certificate")
+			}
+		})
+	}
+}
+
+func TestTerminatorRejectsOldTLS(t *testing.T) {
+	ca := newTestCA(t, "vici CA")
+	addr := newTestTerminator(t, ca)
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		RootCAs:      ca.pool(),
+		Certificates: []tls.Certificate{ca.issue(t, "client", x509.ExtKeyUsageClientAuth)},
+		MinVersion:   tls.VersionTLS10,
+		MaxVersion:   tls.VersionTLS11,
+	})
+	if err == nil {
+		conn.Close()
+		t.Fatalf("Expected handshake with TLS 1.1 to fail")
+	}
+}
+
+func TestTerminatorHandshakeTimeout(t *testing.T) {
+	ca := newTestCA(t, "vici CA")
+	addr := newTestTerminator(t, ca, tlsterm.WithHandshakeTimeout(50*time.Millisecond))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	// Never start the handshake. The terminator must give up on the
+	// client, and close the connection.
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("Failed to set deadline: %v", err)
+	}
+
+	if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Fatalf("Expected connection to be closed by the terminator, got %v", err)
+	}
+}
+
+func TestTerminatorClose(t *testing.T) {
+	term := tlsterm.NewTerminator(&tls.Config{})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	errs := make(chan error, 1)
+	go func() { errs <- term.Serve(l) }()
+
+	// Give Serve a chance to start accepting, then close it.
+	time.Sleep(10 * time.Millisecond)
+
+	if err := term.Close(); err != nil {
+		t.Fatalf("Unexpected error closing terminator: %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("Unexpected error from Serve: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for Serve to return")
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vicitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// FaultKind is the kind of disturbance caused by a Fault.
+type FaultKind int
+
+const (
+	// FaultDelay delays the packet by Fault.Delay. As packets are transmitted
+	// in order, the packets after it in the same direction are delayed too.
+	FaultDelay FaultKind = iota + 1
+
+	// FaultSplit transmits the packet in pieces, split at Fault.Offsets, with
+	// a pause of Fault.Delay before each piece after the first. Offsets are
+	// counted from the start of the packet's length header, so e.g. an offset
+	// of 2 splits the length header.
+	FaultSplit
+
+	// FaultTruncate transmits only the first Fault.Length bytes of the packet,
+	// including its length header, and then closes the connection.
+	FaultTruncate
+
+	// FaultGarbage transmits Fault.Data before the packet.
+	FaultGarbage
+
+	// FaultDuplicate transmits the packet twice.
+	FaultDuplicate
+
+	// FaultDrop does not transmit the packet at all.
+	FaultDrop
+
+	// FaultDisconnect closes the connection instead of transmitting the packet.
+	FaultDisconnect
+)
+
+// Fault describes how a FaultInjector disturbs one packet of a connection.
+type Fault struct {
+	// Conn is the index of the connection, in the order they are dialed,
+	// starting at 0.
+	Conn int
+
+	// Direction selects the packets sent by the client, vici.CaptureSent, or
+	// the packets received by the client, vici.CaptureReceived.
+	Direction vici.CaptureDirection
+
+	// Packet is the index of the packet among the packets transmitted in
+	// Direction, starting at 0.
+	Packet int
+
+	// Kind is the kind of disturbance.
+	Kind FaultKind
+
+	// Delay is the delay of FaultDelay, and the pause between the pieces of
+	// FaultSplit.
+	Delay time.Duration
+
+	// Offsets are the offsets at which FaultSplit splits the packet.
+	Offsets []int
+
+	// Length is the number of bytes FaultTruncate transmits.
+	Length int
+
+	// Data is transmitted by FaultGarbage.
+	Data []byte
+}
+
+// FaultInjector dials connections to a daemon, e.g. a Server, and disturbs the
+// packets transmitted over them according to a schedule of faults. This makes
+// it possible to test how a vici.Session handles slow, broken and misbehaving
+// daemons. A vici.Session connects through it using vici.WithDialContext:
+//
+//	srv := vicitest.NewServer()
+//	defer srv.Close()
+//
+//	fi := vicitest.NewFaultInjector(srv.DialContext, vicitest.Fault{
+//		Direction: vici.CaptureReceived,
+//		Kind:      vicitest.FaultDuplicate,
+//	})
+//	defer fi.Close()
+//
+//	s, err := vici.NewSession(vici.WithDialContext(fi.DialContext))
+//
+// Packets without faults are transmitted unchanged. Faults apply to the
+// packets as framed on the wire, so the packets of a connection using TLS
+// cannot be disturbed.
+type FaultInjector struct {
+	dial   func(ctx context.Context, network, addr string) (net.Conn, error)
+	faults []Fault
+
+	mu     sync.Mutex
+	dialed int
+	conns  map[*faultConn]struct{}
+	closed bool
+
+	wg sync.WaitGroup
+}
+
+// NewFaultInjector returns a FaultInjector which dials connections using dial,
+// and disturbs them with faults.
+func NewFaultInjector(dial func(ctx context.Context, network, addr string) (net.Conn, error), faults ...Fault) *FaultInjector {
+	return &FaultInjector{
+		dial:   dial,
+		faults: faults,
+		conns:  make(map[*faultConn]struct{}),
+	}
+}
+
+// DialContext dials a connection to the daemon, and returns an in-memory
+// connection to it, over which packets are disturbed. It has the signature
+// expected by vici.WithDialContext.
+func (fi *FaultInjector) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	upstream, err := fi.dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	client, conn := net.Pipe()
+
+	fc := &faultConn{
+		conn:     conn,
+		upstream: upstream,
+		done:     make(chan struct{}),
+	}
+
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+
+	if fi.closed {
+		client.Close()
+		fc.close()
+
+		return nil, net.ErrClosed
+	}
+
+	index := fi.dialed
+	fi.dialed++
+
+	for _, f := range fi.faults {
+		if f.Conn == index {
+			fc.faults = append(fc.faults, f)
+		}
+	}
+	fi.conns[fc] = struct{}{}
+
+	fi.wg.Add(2)
+	go func() {
+		defer fi.wg.Done()
+
+		fc.pump(vici.CaptureSent, fc.upstream, fc.conn)
+	}()
+	go func() {
+		defer fi.wg.Done()
+
+		fc.pump(vici.CaptureReceived, fc.conn, fc.upstream)
+
+		fi.mu.Lock()
+		delete(fi.conns, fc)
+		fi.mu.Unlock()
+	}()
+
+	return client, nil
+}
+
+// Close closes all connections, and waits until they are done.
+func (fi *FaultInjector) Close() error {
+	fi.mu.Lock()
+	fi.closed = true
+
+	for fc := range fi.conns {
+		fc.close()
+	}
+	fi.mu.Unlock()
+
+	fi.wg.Wait()
+
+	return nil
+}
+
+// errDisconnect ends a connection because of a fault.
+var errDisconnect = errors.New("disconnected by fault")
+
+// faultConn is a connection between a client and the daemon, disturbed by
+// faults.
+type faultConn struct {
+	// The server side of the client's connection, and the connection to the
+	// daemon.
+	conn     net.Conn
+	upstream net.Conn
+
+	faults []Fault
+
+	// Closed when the connection is closed, to interrupt delays.
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (fc *faultConn) close() {
+	fc.closeOnce.Do(func() {
+		close(fc.done)
+
+		fc.conn.Close()
+		fc.upstream.Close()
+	})
+}
+
+// pump transmits the packets read from src to dst, applying the faults for dir,
+// until either connection ends. Then, it closes the connection.
+func (fc *faultConn) pump(dir vici.CaptureDirection, dst, src net.Conn) {
+	defer fc.close()
+
+	for i := 0; ; i++ {
+		frame, err := readFrame(src)
+		if err != nil {
+			return
+		}
+
+		if err := fc.transmit(dst, frame, fc.match(dir, i)); err != nil {
+			return
+		}
+	}
+}
+
+// match returns the faults for the i-th packet transmitted in dir.
+func (fc *faultConn) match(dir vici.CaptureDirection, i int) []Fault {
+	var faults []Fault
+
+	for _, f := range fc.faults {
+		if f.Direction == dir && f.Packet == i {
+			faults = append(faults, f)
+		}
+	}
+
+	return faults
+}
+
+// transmit writes frame to dst, disturbed by faults, in order.
+func (fc *faultConn) transmit(dst net.Conn, frame []byte, faults []Fault) error {
+	var (
+		copies  = 1
+		offsets []int
+		pause   time.Duration
+	)
+
+	for _, f := range faults {
+		switch f.Kind {
+		case FaultDelay:
+			if err := fc.sleep(f.Delay); err != nil {
+				return err
+			}
+
+		case FaultSplit:
+			offsets = f.Offsets
+			pause = f.Delay
+
+		case FaultTruncate:
+			if _, err := dst.Write(frame[:min(max(f.Length, 0), len(frame))]); err != nil {
+				return err
+			}
+
+			return errDisconnect
+
+		case FaultGarbage:
+			if _, err := dst.Write(f.Data); err != nil {
+				return err
+			}
+
+		case FaultDuplicate:
+			copies = 2
+
+		case FaultDrop:
+			copies = 0
+
+		case FaultDisconnect:
+			return errDisconnect
+		}
+	}
+
+	for range copies {
+		start := 0
+
+		for _, off := range offsets {
+			if off <= start || off >= len(frame) {
+				continue
+			}
+
+			if _, err := dst.Write(frame[start:off]); err != nil {
+				return err
+			}
+			start = off
+
+			if err := fc.sleep(pause); err != nil {
+				return err
+			}
+		}
+
+		if _, err := dst.Write(frame[start:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sleep waits for d, or until the connection is closed.
+func (fc *faultConn) sleep(d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-fc.done:
+		return net.ErrClosed
+	}
+}
+
+// readFrame reads a packet as framed on the wire, i.e. including its length
+// header, without decoding it.
+func readFrame(r io.Reader) ([]byte, error) {
+	hdr := make([]byte, 4)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+
+	// Let the buffer grow as the data arrives, instead of trusting the
+	// length header.
+	var buf bytes.Buffer
+	buf.Write(hdr)
+
+	if _, err := io.CopyN(&buf, r, int64(binary.BigEndian.Uint32(hdr))); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,303 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vicitest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+// newEchoServer returns a Server with an echo command, which responds with
+// its arguments.
+func newEchoServer(t *testing.T) *vicitest.Server {
+	t.Helper()
+
+	srv := vicitest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	srv.Handle("echo", func(req *vicitest.Request) (*vici.Message, error) {
+		return req.Message, nil
+	})
+
+	return srv
+}
+
+// dialFaults dials a connection to srv through a FaultInjector with faults.
+func dialFaults(t *testing.T, srv *vicitest.Server, faults ...vicitest.Fault) net.Conn {
+	t.Helper()
+
+	fi := vicitest.NewFaultInjector(srv.DialContext, faults...)
+	t.Cleanup(func() { fi.Close() })
+
+	conn, err := fi.DialContext(context.Background(), "", "")
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// echo sends an echo request with the given value of k.
+func echo(t *testing.T, conn net.Conn, k string) {
+	t.Helper()
+
+	m, err := vici.Build().Set("k", k).Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := vici.WritePacket(conn, &vici.Packet{Type: vici.PacketCmdRequest, Name: "echo", Message: m}); err != nil {
+		t.Fatalf("Failed to write request: %v", err)
+	}
+}
+
+// encodedResponse returns the echo response with the given value of k, as
+// framed on the wire.
+func encodedResponse(t *testing.T, k string) []byte {
+	t.Helper()
+
+	m, err := vici.Build().Set("k", k).Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := vici.WritePacket(&buf, &vici.Packet{Type: vici.PacketCmdResponse, Message: m}); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// readAll reads from conn until it is closed, or nothing more is received
+// within a short time.
+func readAll(t *testing.T, conn net.Conn) ([]byte, error) {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := io.ReadAll(conn)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return b, nil
+	}
+	if err == nil {
+		err = io.EOF
+	}
+
+	return b, err
+}
+
+func TestFaultInjector(t *testing.T) {
+	resp := encodedResponse(t, "a")
+
+	tests := []struct {
+		name     string
+		faults   []vicitest.Fault
+		expected []byte
+		closed   bool
+	}{
+		{
+			name:     "none",
+			expected: resp,
+		},
+		{
+			name: "garbage",
+			faults: []vicitest.Fault{
+				{Direction: vici.CaptureReceived, Kind: vicitest.FaultGarbage, Data: []byte("xyz")},
+			},
+			expected: append([]byte("xyz"), resp...),
+		},
+		{
+			name: "duplicate",
+			faults: []vicitest.Fault{
+				{Direction: vici.CaptureReceived, Kind: vicitest.FaultDuplicate},
+			},
+			expected: append(bytes.Clone(resp), resp...),
+		},
+		{
+			name: "drop",
+			faults: []vicitest.Fault{
+				{Direction: vici.CaptureReceived, Kind: vicitest.FaultDrop},
+			},
+			expected: []byte{},
+		},
+		{
+			name: "truncate",
+			faults: []vicitest.Fault{
+				{Direction: vici.CaptureReceived, Kind: vicitest.FaultTruncate, Length: 6},
+			},
+			expected: resp[:6],
+			closed:   true,
+		},
+		{
+			name: "disconnect",
+			faults: []vicitest.Fault{
+				{Direction: vici.CaptureReceived, Kind: vicitest.FaultDisconnect},
+			},
+			expected: []byte{},
+			closed:   true,
+		},
+		{
+			name: "request disconnect",
+			faults: []vicitest.Fault{
+				{Direction: vici.CaptureSent, Kind: vicitest.FaultDisconnect},
+			},
+			expected: []byte{},
+			closed:   true,
+		},
+		{
+			name: "request split",
+			faults: []vicitest.Fault{
+				{Direction: vici.CaptureSent, Kind: vicitest.FaultSplit, Offsets: []int{2, 5}, Delay: time.Millisecond},
+			},
+			expected: resp,
+		},
+		{
+			name: "other packet",
+			faults: []vicitest.Fault{
+				{Direction: vici.CaptureReceived, Packet: 1, Kind: vicitest.FaultDisconnect},
+			},
+			expected: resp,
+		},
+		{
+			name: "other connection",
+			faults: []vicitest.Fault{
+				{Conn: 1, Direction: vici.CaptureReceived, Kind: vicitest.FaultDisconnect},
+			},
+			expected: resp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := dialFaults(t, newEchoServer(t), tt.faults...)
+
+			echo(t, conn, "a")
+
+			b, err := readAll(t, conn)
+			if !bytes.Equal(b, tt.expected) {
+				t.Fatalf("Expected to receive %v, got %v", tt.expected, b)
+			}
+
+			if closed := errors.Is(err, io.EOF); closed != tt.closed {
+				t.Fatalf("Expected closed=%v, got error %v", tt.closed, err)
+			}
+		})
+	}
+}
+
+func TestFaultInjectorSplit(t *testing.T) {
+	conn := dialFaults(t, newEchoServer(t), vicitest.Fault{
+		Direction: vici.CaptureReceived,
+		Kind:      vicitest.FaultSplit,
+		Offsets:   []int{2, 6},
+		Delay:     10 * time.Millisecond,
+	})
+
+	echo(t, conn, "a")
+
+	resp := encodedResponse(t, "a")
+	buf := make([]byte, 64)
+
+	// Each piece is received separately.
+	for _, piece := range [][]byte{resp[:2], resp[2:6], resp[6:]} {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("Unexpected error reading: %v", err)
+		}
+
+		if !bytes.Equal(buf[:n], piece) {
+			t.Fatalf("Expected to receive %v, got %v", piece, buf[:n])
+		}
+	}
+}
+
+func TestFaultInjectorDelay(t *testing.T) {
+	const delay = 50 * time.Millisecond
+
+	conn := dialFaults(t, newEchoServer(t), vicitest.Fault{
+		Direction: vici.CaptureReceived,
+		Kind:      vicitest.FaultDelay,
+		Delay:     delay,
+	})
+
+	start := time.Now()
+	echo(t, conn, "a")
+	echo(t, conn, "b")
+
+	for _, k := range []string{"a", "b"} {
+		p, err := vici.ReadPacket(conn)
+		if err != nil {
+			t.Fatalf("Unexpected error reading: %v", err)
+		}
+
+		if got := p.Message.Get("k"); got != k {
+			t.Fatalf("Expected response k=%s, got %v", k, got)
+		}
+	}
+
+	if d := time.Since(start); d < delay {
+		t.Fatalf("Expected responses to be delayed by %v, got %v", delay, d)
+	}
+}
+
+func TestFaultInjectorClose(t *testing.T) {
+	srv := newEchoServer(t)
+
+	fi := vicitest.NewFaultInjector(srv.DialContext, vicitest.Fault{
+		Direction: vici.CaptureReceived,
+		Kind:      vicitest.FaultDelay,
+		Delay:     time.Hour,
+	})
+
+	conn, err := fi.DialContext(context.Background(), "", "")
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	echo(t, conn, "a")
+
+	// Close interrupts the delay.
+	if err := fi.Close(); err != nil {
+		t.Fatalf("Unexpected error closing: %v", err)
+	}
+
+	if _, err := vici.ReadPacket(conn); !errors.Is(err, io.EOF) {
+		t.Fatalf("Expected connection to be closed, got %v", err)
+	}
+
+	if _, err := fi.DialContext(context.Background(), "", ""); err == nil {
+		t.Fatal("Expected error dialing through a closed FaultInjector")
+	}
+}
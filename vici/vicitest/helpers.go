@@ -0,0 +1,57 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vicitest
+
+import (
+	"testing"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// NewSession returns a new vici.Session connected to srv, with any additional
+// options. If the session cannot be created, the test fails immediately. The
+// session is closed when the test finishes.
+func NewSession(tb testing.TB, srv *Server, opts ...vici.SessionOption) *vici.Session {
+	tb.Helper()
+
+	opts = append([]vici.SessionOption{vici.WithDialContext(srv.DialContext)}, opts...)
+
+	s, err := vici.NewSession(opts...)
+	if err != nil {
+		tb.Fatalf("vicitest: failed to create session: %v", err)
+	}
+	tb.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+// MustBuild returns the message built by b. If b failed to build it, the test
+// fails immediately.
+func MustBuild(tb testing.TB, b *vici.Builder) *vici.Message {
+	tb.Helper()
+
+	m, err := b.Message()
+	if err != nil {
+		tb.Fatalf("vicitest: failed to build message: %v", err)
+	}
+
+	return m
+}
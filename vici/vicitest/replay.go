@@ -0,0 +1,240 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vicitest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// Replay serves a session recorded with vici.WithCapture, e.g. against charon,
+// so that tests can run without a daemon. A vici.Session connects to it using
+// vici.WithDialContext:
+//
+//	r := vicitest.LoadReplay(t, "testdata/list-sas.vicicap")
+//
+//	s, err := vici.NewSession(vici.WithDialContext(r.DialContext))
+//
+// Each connection replays the whole recording, in order. The packets received
+// from the daemon are sent to the client, and each packet sent by the recorded
+// client must be matched by the client, i.e. it must have the same type, name
+// and payload. Otherwise, or if the client sends more packets than recorded, or
+// ends the connection before sending all recorded packets, the test fails.
+//
+// The timing of the recording is not replayed. Recordings of sessions that
+// send requests depending on timing, e.g. vici.WithHealthCheck, are not
+// deterministic, and generally do not replay.
+type Replay struct {
+	tb      testing.TB
+	records []*vici.CaptureRecord
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+
+	wg sync.WaitGroup
+}
+
+// NewReplay returns a Replay of the capture read from r. If the capture cannot
+// be read, the test fails immediately. The Replay is closed when the test
+// finishes.
+func NewReplay(tb testing.TB, r io.Reader) *Replay {
+	tb.Helper()
+
+	cr, err := vici.NewCaptureReader(r)
+	if err != nil {
+		tb.Fatalf("vicitest: failed to read capture: %v", err)
+	}
+
+	var records []*vici.CaptureRecord
+	for {
+		rec, err := cr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			tb.Fatalf("vicitest: failed to read capture: %v", err)
+		}
+
+		records = append(records, rec)
+	}
+
+	rp := &Replay{
+		tb:      tb,
+		records: records,
+		conns:   make(map[net.Conn]struct{}),
+	}
+	tb.Cleanup(rp.Close)
+
+	return rp
+}
+
+// LoadReplay returns a Replay of the capture in the named file, see NewReplay.
+func LoadReplay(tb testing.TB, name string) *Replay {
+	tb.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		tb.Fatalf("vicitest: failed to open capture: %v", err)
+	}
+	defer f.Close()
+
+	return NewReplay(tb, f)
+}
+
+// DialContext returns a new in-memory connection replaying the recording. It has
+// the signature expected by vici.WithDialContext, and ignores network and addr.
+func (rp *Replay) DialContext(ctx context.Context, _, _ string) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	client, server := net.Pipe()
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if rp.closed {
+		client.Close()
+		server.Close()
+
+		return nil, net.ErrClosed
+	}
+	rp.conns[server] = struct{}{}
+
+	rp.wg.Add(1)
+	go func() {
+		defer rp.wg.Done()
+
+		rp.serve(server)
+
+		rp.mu.Lock()
+		delete(rp.conns, server)
+		rp.mu.Unlock()
+	}()
+
+	return client, nil
+}
+
+// Close closes all connections, and waits until they are done. Close is called
+// automatically when the test finishes.
+func (rp *Replay) Close() {
+	rp.mu.Lock()
+	rp.closed = true
+
+	for conn := range rp.conns {
+		conn.Close()
+	}
+	rp.mu.Unlock()
+
+	rp.wg.Wait()
+}
+
+// serve replays the recording on conn.
+func (rp *Replay) serve(conn net.Conn) {
+	defer conn.Close()
+
+	for i, rec := range rp.records {
+		switch rec.Direction {
+		case vici.CaptureReceived:
+			if err := vici.WritePacket(conn, rec.Packet); err != nil {
+				if n := rp.remaining(i); n > 0 {
+					rp.tb.Errorf("vicitest: replay: connection ended with %d recorded packets left to send: %v", n, err)
+				}
+
+				return
+			}
+
+		case vici.CaptureSent:
+			p, err := vici.ReadPacket(conn)
+			if err != nil {
+				rp.tb.Errorf("vicitest: replay: connection ended with %d recorded packets left to send, next is %s: %v",
+					rp.remaining(i), describePacket(rec.Packet), err)
+
+				return
+			}
+
+			if err := matchPacket(rec.Packet, p); err != nil {
+				rp.tb.Errorf("vicitest: replay: packet %d diverges from the recording: %v", i, err)
+
+				return
+			}
+		}
+	}
+
+	if p, err := vici.ReadPacket(conn); err == nil {
+		rp.tb.Errorf("vicitest: replay: unexpected packet after the end of the recording: %s", describePacket(p))
+	}
+}
+
+// remaining returns the number of packets recorded as sent by the client,
+// starting at record i.
+func (rp *Replay) remaining(i int) int {
+	n := 0
+	for _, rec := range rp.records[i:] {
+		if rec.Direction == vici.CaptureSent {
+			n++
+		}
+	}
+
+	return n
+}
+
+// matchPacket returns an error describing the difference if p does not match
+// the expected packet.
+func matchPacket(expected, p *vici.Packet) error {
+	if p.Type != expected.Type || p.Name != expected.Name {
+		return fmt.Errorf("expected %s, got %s", describePacket(expected), describePacket(p))
+	}
+
+	eb, err := expected.Message.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	b, err := p.Message.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(eb, b) {
+		return fmt.Errorf("expected %s with payload:\n%s\ngot payload:\n%s", describePacket(expected), expected.Message, p.Message)
+	}
+
+	return nil
+}
+
+func describePacket(p *vici.Packet) string {
+	if p.Name == "" {
+		return p.Type.String()
+	}
+
+	return p.Type.String() + " " + p.Name
+}
@@ -0,0 +1,313 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vicitest_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+// record runs fn with a session connected to a Server, and returns the capture
+// of the session.
+func record(t *testing.T, fn func(s *vici.Session, srv *vicitest.Server)) []byte {
+	t.Helper()
+
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("version", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().Set("daemon", "charon").Message()
+	})
+	srv.Handle("initiate", func(req *vicitest.Request) (*vici.Message, error) {
+		m, err := vici.Build().Set("msg", "initiating").Message()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := req.Emit("control-log", m); err != nil {
+			return nil, err
+		}
+
+		return vici.Build().Set("success", "yes").Message()
+	})
+
+	var buf bytes.Buffer
+
+	s, err := vici.NewSession(vici.WithDialContext(srv.DialContext), vici.WithCapture(&buf))
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	fn(s, srv)
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Unexpected error closing session: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// session runs the same requests against a daemon or a replay.
+func session(t *testing.T, s *vici.Session, srv *vicitest.Server) {
+	t.Helper()
+
+	ec := make(chan vici.Event, 1)
+	s.NotifyEvents(ec)
+
+	if err := s.Subscribe("ike-updown"); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+
+	if srv != nil {
+		m, err := vici.Build().Set("up", "yes").Message()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := srv.Emit("ike-updown", m); err != nil {
+			t.Fatalf("Unexpected error emitting event: %v", err)
+		}
+	}
+
+	select {
+	case ev := <-ec:
+		if ev.Name != "ike-updown" || ev.Message.Get("up") != "yes" {
+			t.Fatalf("Unexpected event %s: %s", ev.Name, ev.Message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	out, err := s.Call(context.Background(), "version", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if d := out.Get("daemon"); d != "charon" {
+		t.Fatalf("Expected daemon=charon, got %v", d)
+	}
+
+	in, err := vici.Build().Set("child", "net").Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	for m, err := range s.CallStreaming(context.Background(), "initiate", "control-log", in) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if msg := m.Get("msg"); msg != "initiating" {
+			t.Fatalf("Unexpected log message: %v", msg)
+		}
+		n++
+	}
+
+	if n != 1 {
+		t.Fatalf("Expected 1 log message, got %d", n)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	capture := record(t, func(s *vici.Session, srv *vicitest.Server) {
+		session(t, s, srv)
+	})
+
+	path := filepath.Join(t.TempDir(), "session.vicicap")
+	if err := os.WriteFile(path, capture, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := vicitest.LoadReplay(t, path)
+
+	// Every connection replays the whole recording.
+	for range 2 {
+		s, err := vici.NewSession(vici.WithDialContext(r.DialContext))
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		session(t, s, nil)
+
+		if err := s.Close(); err != nil {
+			t.Fatalf("Unexpected error closing session: %v", err)
+		}
+	}
+}
+
+// errorTB records the errors reported by a Replay.
+type errorTB struct {
+	testing.TB
+
+	mu   sync.Mutex
+	errs []string
+}
+
+func (tb *errorTB) Errorf(format string, args ...any) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.errs = append(tb.errs, fmt.Sprintf(format, args...))
+}
+
+func (tb *errorTB) errors() []string {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	return tb.errs
+}
+
+func TestReplayDivergence(t *testing.T) {
+	capture := record(t, func(s *vici.Session, _ *vicitest.Server) {
+		for _, child := range []string{"a", "b"} {
+			in, err := vici.Build().Set("child", child).Message()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := s.Call(context.Background(), "initiate", in); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	})
+
+	tests := []struct {
+		name     string
+		calls    []string
+		children []string
+		expected string
+	}{
+		{
+			name:     "match",
+			calls:    []string{"initiate", "initiate"},
+			children: []string{"a", "b"},
+		},
+		{
+			name:     "payload",
+			calls:    []string{"initiate", "initiate"},
+			children: []string{"a", "c"},
+			expected: "packet 2 diverges from the recording: expected CMD_REQUEST initiate with payload",
+		},
+		{
+			name:     "command",
+			calls:    []string{"version"},
+			children: []string{"a"},
+			expected: "packet 0 diverges from the recording: expected CMD_REQUEST initiate, got CMD_REQUEST version",
+		},
+		{
+			name:     "missing",
+			calls:    []string{"initiate"},
+			children: []string{"a"},
+			expected: "connection ended with 1 recorded packets left to send, next is CMD_REQUEST initiate",
+		},
+		{
+			name:     "extra",
+			calls:    []string{"initiate", "initiate", "version"},
+			children: []string{"a", "b", "c"},
+			expected: "unexpected packet after the end of the recording: CMD_REQUEST version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &errorTB{TB: t}
+			r := vicitest.NewReplay(tb, bytes.NewReader(capture))
+
+			s, err := vici.NewSession(vici.WithDialContext(r.DialContext))
+			if err != nil {
+				t.Fatalf("Failed to create session: %v", err)
+			}
+
+			for i, cmd := range tt.calls {
+				in, err := vici.Build().Set("child", tt.children[i]).Message()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				// nolint
+				s.Call(ctx, cmd, in)
+				cancel()
+			}
+
+			s.Close()
+			r.Close()
+
+			errs := tb.errors()
+
+			if tt.expected == "" {
+				if len(errs) != 0 {
+					t.Fatalf("Unexpected errors: %v", errs)
+				}
+
+				return
+			}
+
+			if len(errs) != 1 || !strings.Contains(errs[0], tt.expected) {
+				t.Fatalf("Expected error containing %q, got %v", tt.expected, errs)
+			}
+		})
+	}
+}
+
+func TestNewReplayInvalid(t *testing.T) {
+	tb := &fatalTB{TB: t}
+
+	func() {
+		defer func() {
+			// nolint
+			recover()
+		}()
+
+		vicitest.NewReplay(tb, strings.NewReader("not a capture"))
+	}()
+
+	if !strings.Contains(tb.msg, "invalid capture") {
+		t.Fatalf("Expected test to fail with invalid capture, got %q", tb.msg)
+	}
+}
+
+// fatalTB records the message of a Fatalf call, and panics to stop the caller
+// like testing.T.Fatalf does.
+type fatalTB struct {
+	testing.TB
+
+	msg string
+}
+
+func (tb *fatalTB) Helper() {}
+
+func (tb *fatalTB) Fatalf(format string, args ...any) {
+	tb.msg = fmt.Sprintf(format, args...)
+
+	panic(tb.msg)
+}
@@ -0,0 +1,343 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package vicitest provides a fake vici daemon, for testing programs that use
+// package vici without running charon.
+//
+// A Server answers command requests with the handlers registered using
+// Server.Handle, confirms all event registrations, and sends events with
+// Server.Emit. A vici.Session connects to it using vici.WithDialContext:
+//
+//	srv := vicitest.NewServer()
+//	defer srv.Close()
+//
+//	srv.Handle("version", func(req *vicitest.Request) (*vici.Message, error) {
+//		return vici.Build().Set("daemon", "charon").Message()
+//	})
+//
+//	s, err := vici.NewSession(vici.WithDialContext(srv.DialContext))
+//
+// Alternatively, a Replay serves a session recorded against charon using
+// vici.WithCapture.
+package vicitest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/strongswan/govici/vici"
+)
+
+// HandlerFunc handles a command request. The returned message is sent as the
+// command response. If an error is returned instead, the response indicates
+// failure, with the error text as its errmsg.
+type HandlerFunc func(req *Request) (*vici.Message, error)
+
+// Request is a command request received by a Server.
+type Request struct {
+	// Command is the name of the requested command.
+	Command string
+
+	// Message holds the command arguments.
+	Message *vici.Message
+
+	// Credentials holds the credentials of the client process, if it is
+	// connected over a UNIX socket and the platform supports it. Otherwise,
+	// Credentials is nil.
+	Credentials *vici.Credentials
+
+	conn *conn
+}
+
+// Emit sends an event to the client that made the request, if the client is
+// registered for the event. This is how streaming commands, e.g. list-sas,
+// send their results.
+func (r *Request) Emit(event string, m *vici.Message) error {
+	return r.conn.emit(event, m)
+}
+
+// Server is a fake vici daemon.
+type Server struct {
+	mu        sync.Mutex
+	handlers  map[string]HandlerFunc
+	conns     map[*conn]struct{}
+	listeners map[net.Listener]struct{}
+	closed    bool
+
+	wg sync.WaitGroup
+}
+
+// NewServer returns a new Server without any command handlers.
+func NewServer() *Server {
+	return &Server{
+		handlers:  make(map[string]HandlerFunc),
+		conns:     make(map[*conn]struct{}),
+		listeners: make(map[net.Listener]struct{}),
+	}
+}
+
+// Handle registers the handler for cmd, replacing any previous handler. Requests
+// for commands without handler are answered with CMD_UNKNOWN.
+func (s *Server) Handle(cmd string, h HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[cmd] = h
+}
+
+// Emit sends an event to all clients registered for the event.
+func (s *Server) Emit(event string, m *vici.Message) error {
+	s.mu.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	var errs []error
+
+	for _, c := range conns {
+		if err := c.emit(event, m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DialContext returns a new in-memory connection to the server. It has the
+// signature expected by vici.WithDialContext, and ignores network and addr.
+func (s *Server) DialContext(ctx context.Context, _, _ string) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	client, server := net.Pipe()
+
+	if err := s.serve(server); err != nil {
+		client.Close()
+
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// Serve accepts connections on l, and serves each of them, until l is closed or
+// the server is closed. When the server is closed, Serve returns nil.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+
+		return net.ErrClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+	}()
+
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+
+			if closed {
+				return nil
+			}
+
+			return err
+		}
+
+		if err := s.serve(nc); err != nil {
+			return nil
+		}
+	}
+}
+
+// Close closes all listeners given to Serve and all client connections, and waits
+// until the connections are done. Handlers must therefore return once their client
+// connection is closed.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+
+	for l := range s.listeners {
+		l.Close()
+	}
+
+	for c := range s.conns {
+		c.nc.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	return nil
+}
+
+// serve starts serving a client connection.
+func (s *Server) serve(nc net.Conn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		nc.Close()
+
+		return net.ErrClosed
+	}
+
+	c := &conn{
+		srv:    s,
+		nc:     nc,
+		events: make(map[string]struct{}),
+	}
+
+	if creds, err := vici.PeerCredentials(nc); err == nil {
+		c.creds = creds
+	}
+	s.conns[c] = struct{}{}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		c.serve()
+
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+
+	return nil
+}
+
+func (s *Server) handler(cmd string) HandlerFunc {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.handlers[cmd]
+}
+
+// conn is a client connection to a Server.
+type conn struct {
+	srv   *Server
+	nc    net.Conn
+	creds *vici.Credentials
+
+	// Serializes writes of responses and events.
+	wmu sync.Mutex
+
+	mu     sync.Mutex
+	events map[string]struct{}
+}
+
+// serve reads and answers requests from the client, until the connection
+// is closed. Requests are handled one at a time, in order.
+func (c *conn) serve() {
+	defer c.nc.Close()
+
+	for {
+		p, err := vici.ReadPacket(c.nc)
+		if err != nil {
+			return
+		}
+
+		var resp *vici.Packet
+
+		switch p.Type {
+		case vici.PacketCmdRequest:
+			resp = c.handle(p)
+
+		case vici.PacketEventRegister:
+			c.mu.Lock()
+			c.events[p.Name] = struct{}{}
+			c.mu.Unlock()
+
+			resp = &vici.Packet{Type: vici.PacketEventConfirm}
+
+		case vici.PacketEventUnregister:
+			c.mu.Lock()
+			delete(c.events, p.Name)
+			c.mu.Unlock()
+
+			resp = &vici.Packet{Type: vici.PacketEventConfirm}
+
+		default:
+			// Not a request, ignore.
+			continue
+		}
+
+		if err := c.write(resp); err != nil {
+			return
+		}
+	}
+}
+
+func (c *conn) handle(p *vici.Packet) *vici.Packet {
+	h := c.srv.handler(p.Name)
+	if h == nil {
+		return &vici.Packet{Type: vici.PacketCmdUnknown}
+	}
+
+	m, err := h(&Request{
+		Command:     p.Name,
+		Message:     p.Message,
+		Credentials: c.creds,
+		conn:        c,
+	})
+	if err != nil {
+		m = vici.NewMessage()
+
+		// nolint
+		_ = m.Set("success", "no")
+		// nolint
+		_ = m.Set("errmsg", err.Error())
+	}
+
+	return &vici.Packet{Type: vici.PacketCmdResponse, Message: m}
+}
+
+func (c *conn) emit(event string, m *vici.Message) error {
+	c.mu.Lock()
+	_, ok := c.events[event]
+	c.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return c.write(&vici.Packet{Type: vici.PacketEvent, Name: event, Message: m})
+}
+
+func (c *conn) write(p *vici.Packet) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	return vici.WritePacket(c.nc, p)
+}
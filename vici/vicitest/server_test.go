@@ -0,0 +1,256 @@
+// Copyright (C) 2026 Nick Rosbrook
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package vicitest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/strongswan/govici/vici"
+	"github.com/strongswan/govici/vici/vicitest"
+)
+
+func TestServerCall(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("version", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().Set("daemon", "charon").Message()
+	})
+	srv.Handle("initiate", func(req *vicitest.Request) (*vici.Message, error) {
+		return nil, fmt.Errorf("CHILD_SA '%v' not found", req.Message.Get("child"))
+	})
+
+	s := vicitest.NewSession(t, srv)
+
+	out, err := s.Call(context.Background(), "version", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if d := out.Get("daemon"); d != "charon" {
+		t.Fatalf("Expected daemon=charon, got %v", d)
+	}
+
+	in, err := vici.Build().Set("child", "net").Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err = s.Call(context.Background(), "initiate", in)
+	if err == nil {
+		t.Fatal("Expected error from failing handler")
+	}
+
+	if msg := out.Get("errmsg"); msg != "CHILD_SA 'net' not found" {
+		t.Fatalf("Unexpected errmsg: %v", msg)
+	}
+
+	if _, err := s.Call(context.Background(), "unknown", nil); err == nil {
+		t.Fatal("Expected error from unknown command")
+	}
+}
+
+func TestServerStream(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	srv.Handle("list-conns", func(req *vicitest.Request) (*vici.Message, error) {
+		for i := range 3 {
+			m, err := vici.Build().Section("conn"+strconv.Itoa(i)).Set("version", "IKEv2").Message()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := req.Emit("list-conn", m); err != nil {
+				return nil, err
+			}
+		}
+
+		return vici.NewMessage(), nil
+	})
+
+	s := vicitest.NewSession(t, srv)
+
+	n := 0
+	for m, err := range s.CallStreaming(context.Background(), "list-conns", "list-conn", nil) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, ok := m.Get("conn" + strconv.Itoa(n)).(*vici.Message); !ok {
+			t.Fatalf("Unexpected event: %s", m)
+		}
+		n++
+	}
+
+	if n != 3 {
+		t.Fatalf("Expected 3 events, got %d", n)
+	}
+}
+
+func TestServerEmit(t *testing.T) {
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	s := vicitest.NewSession(t, srv)
+
+	ec := make(chan vici.Event, 1)
+	s.NotifyEvents(ec)
+
+	// Not registered yet, so this must not be received.
+	if err := srv.Emit("ike-updown", vici.NewMessage()); err != nil {
+		t.Fatalf("Unexpected error emitting event: %v", err)
+	}
+
+	if err := s.Subscribe("ike-updown"); err != nil {
+		t.Fatalf("Unexpected error subscribing: %v", err)
+	}
+
+	m, err := vici.Build().Set("up", "yes").Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := srv.Emit("ike-updown", m); err != nil {
+		t.Fatalf("Unexpected error emitting event: %v", err)
+	}
+
+	select {
+	case ev := <-ec:
+		if ev.Name != "ike-updown" || ev.Message.Get("up") != "yes" {
+			t.Fatalf("Unexpected event %s: %s", ev.Name, ev.Message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	select {
+	case ev := <-ec:
+		t.Fatalf("Received unexpected event %s", ev.Name)
+	default:
+	}
+}
+
+func TestServerServe(t *testing.T) {
+	srv := vicitest.NewServer()
+
+	srv.Handle("version", func(_ *vicitest.Request) (*vici.Message, error) {
+		return vici.Build().Set("daemon", "charon").Message()
+	})
+
+	path := filepath.Join(t.TempDir(), "charon.vici")
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(l)
+	}()
+
+	s, err := vici.NewSession(vici.WithSocketPath(path))
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Call(context.Background(), "version", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := srv.Close(); err != nil {
+		t.Fatalf("Unexpected error closing server: %v", err)
+	}
+
+	if err := <-errs; err != nil {
+		t.Fatalf("Unexpected error from Serve: %v", err)
+	}
+
+	// Closing the server ends client connections.
+	<-s.Done()
+
+	if err := s.Err(); errors.Is(err, vici.ErrSessionClosed) {
+		t.Fatalf("Expected connection to end because of the server, got %v", err)
+	}
+
+	if _, err := srv.DialContext(context.Background(), "", ""); err == nil {
+		t.Fatal("Expected error dialing closed server")
+	}
+}
+
+func TestServerCredentials(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Peer credentials are only supported on linux")
+	}
+
+	srv := vicitest.NewServer()
+	defer srv.Close()
+
+	creds := make(chan *vici.Credentials, 1)
+	srv.Handle("version", func(req *vicitest.Request) (*vici.Message, error) {
+		creds <- req.Credentials
+
+		return vici.NewMessage(), nil
+	})
+
+	path := filepath.Join(t.TempDir(), "charon.vici")
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	go func() {
+		// nolint
+		srv.Serve(l)
+	}()
+
+	// The server runs as the same user as the test.
+	s, err := vici.NewSession(vici.WithSocketPath(path), vici.WithPeerUID(uint32(os.Getuid())))
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Call(context.Background(), "version", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	c := <-creds
+	if c == nil {
+		t.Fatal("Expected client credentials in request")
+	}
+
+	if c.PID != int32(os.Getpid()) || c.UID != uint32(os.Getuid()) || c.GID != uint32(os.Getgid()) {
+		t.Fatalf("Unexpected client credentials: %+v", *c)
+	}
+}